@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Apdex scores latency against a target time T as a single number from 0 to 1:
+// responses within T satisfy, within 4T are tolerated, and slower or failed
+// ones frustrate. The score is (satisfied + tolerating/2) / total.
+type Apdex struct {
+	T          time.Duration
+	Score      float64
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+}
+
+// add counts one response
+func (a *Apdex) add(responseTime time.Duration, success bool) {
+	switch {
+	case !success || responseTime > 4*a.T:
+		a.Frustrated++
+	case responseTime > a.T:
+		a.Tolerating++
+	default:
+		a.Satisfied++
+	}
+}
+
+// finish computes the score from the counts
+func (a *Apdex) finish() {
+	if total := a.Satisfied + a.Tolerating + a.Frustrated; total > 0 {
+		a.Score = (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(total)
+	}
+}
+
+func (a *Apdex) String() string {
+	return fmt.Sprintf("%.2f (T=%v: %d satisfied, %d tolerating, %d frustrated)",
+		a.Score, a.T, a.Satisfied, a.Tolerating, a.Frustrated)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestApdex(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.com"))
+	tester.config.ApdexT = 100 * time.Millisecond
+	tester.results = []Result{
+		{StatusCode: 200, ResponseTime: 50 * time.Millisecond},
+		{StatusCode: 200, ResponseTime: 100 * time.Millisecond},
+		{StatusCode: 200, ResponseTime: 300 * time.Millisecond},
+		{StatusCode: 200, ResponseTime: time.Second},
+		{StatusCode: 500, ResponseTime: 10 * time.Millisecond},
+		{Error: errors.New("boom"), ResponseTime: 10 * time.Millisecond},
+	}
+
+	apdex := tester.calculateStats(time.Second).Apdex
+	if apdex == nil {
+		t.Fatal("Apdex not computed")
+	}
+	if apdex.Satisfied != 2 || apdex.Tolerating != 1 || apdex.Frustrated != 3 {
+		t.Errorf("satisfied/tolerating/frustrated = %d/%d/%d, want 2/1/3", apdex.Satisfied, apdex.Tolerating, apdex.Frustrated)
+	}
+	if want := 2.5 / 6; math.Abs(apdex.Score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", apdex.Score, want)
+	}
+
+	tester.config.ApdexT = 0
+	if stats := tester.calculateStats(time.Second); stats.Apdex != nil {
+		t.Error("Apdex computed without a target time")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenFile serves a bearer token read from a file, re-reading it once it is
+// older than the refresh interval so the run survives token rotation
+type tokenFile struct {
+	path    string
+	refresh time.Duration
+
+	mu       sync.Mutex
+	token    string
+	loadedAt time.Time
+}
+
+// readToken reads a bearer token from path, ignoring surrounding whitespace
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
+func newTokenFile(path string, refresh time.Duration) (*tokenFile, error) {
+	token, err := readToken(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenFile{path: path, refresh: refresh, token: token, loadedAt: time.Now()}, nil
+}
+
+// current returns the token, re-reading the file when the refresh interval has passed
+func (t *tokenFile) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.refresh > 0 && time.Since(t.loadedAt) >= t.refresh {
+		t.loadLocked()
+	}
+	return t.token
+}
+
+// reload re-reads the file now and reports whether the token changed
+func (t *tokenFile) reload() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous := t.token
+	t.loadLocked()
+	return t.token != previous
+}
+
+// loadLocked re-reads the file. A failed read keeps the previous token, since
+// the file may be mid-rotation.
+func (t *tokenFile) loadLocked() {
+	t.loadedAt = time.Now()
+	token, err := readToken(t.path)
+	if err != nil {
+		logger.Warn("reading bearer token failed, keeping the previous one", "file", t.path, "error", err)
+		return
+	}
+	t.token = token
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBearerFileRetriesAfterRotation(t *testing.T) {
+	var hits int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := testConfig(srv.URL)
+	config.BearerFile = path
+	tester := newTestLoadTester(t, config)
+
+	// The token hasn't changed, so a 401 isn't retried
+	if result := tester.makeRequest(testRand()); result.StatusCode != http.StatusUnauthorized || hits != 1 {
+		t.Fatalf("got status %d after %d requests, want a single 401", result.StatusCode, hits)
+	}
+
+	if err := os.WriteFile(path, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result := tester.makeRequest(testRand()); result.StatusCode != http.StatusOK || hits != 3 {
+		t.Fatalf("got status %d after %d requests, want 200 after one retry", result.StatusCode, hits)
+	}
+}
+
+func TestReadTokenRejectsEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(path, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readToken(path); err == nil {
+		t.Error("expected an error for an empty token file")
+	}
+}
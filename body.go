@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// byteSizeUnits maps the suffixes accepted by parseByteSize to their multiplier
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes such as "512", "64KB" or "1.5MB" into bytes (binary units)
+func parseByteSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// randomBody returns size bytes of pseudo-random data seeded from rng. The data
+// only needs to be incompressible, not unpredictable, so a fast ChaCha8 stream is used.
+func randomBody(size int64, rng *rand.Rand) []byte {
+	buf := make([]byte, size)
+	var seed [32]byte
+	for i := 0; i < len(seed); i += 8 {
+		v := rng.Uint64()
+		for j := 0; j < 8; j++ {
+			seed[i+j] = byte(v >> (8 * j))
+		}
+	}
+	rand.NewChaCha8(seed).Read(buf)
+	return buf
+}
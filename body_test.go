@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":   512,
+		"512B":  512,
+		"64KB":  64 * 1024,
+		"1mb":   1024 * 1024,
+		"1.5MB": 1536 * 1024,
+		"2GB":   2 * 1024 * 1024 * 1024,
+	}
+	for in, want := range tests {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "MB", "-1KB", "ten"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) should fail", in)
+		}
+	}
+}
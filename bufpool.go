@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// readBufferSize is the size of the pooled buffers response bodies are read
+// through; large enough that a typical body takes a handful of reads
+const readBufferSize = 32 << 10
+
+// readBuffers holds the buffers response bodies are read through. Bodies are
+// only counted and optionally digested, never kept, so one buffer per
+// in-flight read is all a run needs however large the responses are.
+var readBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, readBufferSize)
+		return &buf
+	},
+}
+
+// readBody reads body to EOF through a pooled buffer and returns its size,
+// feeding it to digest unless that is nil. body is closed as soon as ctx is
+// done so a slow or stalled body doesn't hold up stopping the run.
+func readBody(ctx context.Context, body io.ReadCloser, digest hash.Hash) (int64, error) {
+	stop := context.AfterFunc(ctx, func() { body.Close() })
+	defer stop()
+
+	bufp := readBuffers.Get().(*[]byte)
+	defer readBuffers.Put(bufp)
+	buf := *bufp
+
+	var size int64
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			size += int64(n)
+			if digest != nil {
+				digest.Write(buf[:n])
+			}
+		}
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return size, fmt.Errorf("%w: %w", errCanceledDuringRead, ctx.Err())
+			}
+			return size, err
+		}
+	}
+}
+
+// requestBody is a reusable reader over the configured --body. The transport
+// may go on writing a body after Do returns, when the response comes early,
+// so a body only goes back to the pool once the WroteRequest trace hook has
+// reported the request written.
+type requestBody struct {
+	strings.Reader
+	text    string
+	written atomic.Bool
+	// getBody and wroteRequest are bound once, so using a pooled body
+	// allocates nothing
+	getBody      func() (io.ReadCloser, error)
+	wroteRequest func(httptrace.WroteRequestInfo)
+}
+
+// Close does nothing: the reader holds no resources and is reused
+func (b *requestBody) Close() error { return nil }
+
+func newRequestBody() any {
+	b := &requestBody{}
+	// A redirect or a retry on a fresh connection reads the body again
+	b.getBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(b.text)), nil }
+	b.wroteRequest = func(httptrace.WroteRequestInfo) { b.written.Store(true) }
+	return b
+}
+
+// acquireBody returns a reader over text from the pool
+func (lt *LoadTester) acquireBody(text string) *requestBody {
+	b := lt.requestBodies.Get().(*requestBody)
+	b.Reset(text)
+	b.text = text
+	b.written.Store(false)
+	return b
+}
+
+// releaseBody puts b back in the pool unless the transport may still be
+// reading it, in which case it is left to the garbage collector
+func (lt *LoadTester) releaseBody(b *requestBody) {
+	if b != nil && b.written.Load() {
+		lt.requestBodies.Put(b)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadBodyCountsAndDigests(t *testing.T) {
+	body := strings.Repeat("x", 3*readBufferSize+17)
+
+	n, err := readBody(context.Background(), io.NopCloser(strings.NewReader(body)), nil)
+	if err != nil || n != int64(len(body)) {
+		t.Fatalf("readBody = %d, %v; want %d, nil", n, err, len(body))
+	}
+
+	// Streaming the body through the digest must match digesting it whole
+	streamed, whole := newBodyDigests(), newBodyDigests()
+	d := streamed.digest()
+	if _, err := readBody(context.Background(), io.NopCloser(strings.NewReader(body)), d); err != nil {
+		t.Fatal(err)
+	}
+	streamed.record(d, n)
+	w := whole.digest()
+	w.Write([]byte(body))
+	whole.record(w, int64(len(body)))
+	if got, want := streamed.summary().Bodies[0], whole.summary().Bodies[0]; got != want {
+		t.Errorf("streamed digest %+v, want %+v", got, want)
+	}
+}
+
+func TestSetHeadersTemplate(t *testing.T) {
+	config := testConfig("http://example.com")
+	config.Headers = map[string]string{"x-api-key": "secret", "User-Agent": "custom"}
+	tester := newTestLoadTester(t, config)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", config.URL, nil)
+		tester.setHeaders(req)
+		if got := req.Header.Get("X-Api-Key"); got != "secret" {
+			t.Errorf("X-Api-Key = %q, want secret", got)
+		}
+		if got := req.Header.Get("User-Agent"); got != "custom" {
+			t.Errorf("User-Agent = %q, want custom", got)
+		}
+		// Per-request headers must not leak into the shared template
+		req.Header.Set("X-Request-Id", "abc")
+	}
+	if tester.headerTemplate.Get("X-Request-Id") != "" {
+		t.Error("per-request header leaked into the template")
+	}
+}
+
+func TestRequestBodyReuse(t *testing.T) {
+	var stall atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("early") != "" {
+			// Answer before reading the body, so the transport may still be
+			// writing it when the response arrives
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		// A reused body must arrive whole, with its length rather than chunked
+		if b, _ := io.ReadAll(r.Body); string(b) != `{"n":1}` || r.ContentLength != int64(len(b)) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Header().Set("Content-Length", "4")
+		w.Write([]byte("ok"))
+		w.(http.Flusher).Flush()
+		if stall.Load() {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Method = "POST"
+	config.Body = `{"n":1}`
+	config.Timeout = 50 * time.Millisecond
+	tester := newTestLoadTester(t, config)
+	rng := testRand()
+
+	// A body that stalls past the timeout counts as a timeout
+	stall.Store(true)
+	if result := tester.sendRequest("POST", rng); errorCategory(result) != "timeout" {
+		t.Fatalf("stalled body: error %v, want a timeout", result.Error)
+	}
+	stall.Store(false)
+	// A body cut off by the timeout isn't reused half read
+	for i := 0; i < 20; i++ {
+		if result := tester.sendRequest("POST", rng); result.Error != nil || result.StatusCode != http.StatusOK || result.ContentSize != 4 {
+			t.Fatalf("request %d after a timeout: status %d, size %d, error %v", i, result.StatusCode, result.ContentSize, result.Error)
+		}
+	}
+
+	big := strings.Repeat("x", 1<<20)
+	config.URL = srv.URL + "?early=1"
+	config.Body = big
+	config.Timeout = 5 * time.Second
+	tester = newTestLoadTester(t, config)
+	for i := 0; i < 20; i++ {
+		if result := tester.sendRequest("POST", rng); result.Error != nil || result.StatusCode != http.StatusAccepted {
+			t.Fatalf("early response %d: status %d, error %v", i, result.StatusCode, result.Error)
+		}
+	}
+}
+
+// BenchmarkSendRequest measures one request against a local server for a few
+// response sizes, the figures to watch being allocs/op and B/op. The server
+// runs in the same process, so its allocations are part of the count.
+func BenchmarkSendRequest(b *testing.B) {
+	for _, size := range []int{1 << 10, 16 << 10, 256 << 10} {
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			benchmarkSendRequest(b, size)
+		})
+	}
+}
+
+func benchmarkSendRequest(b *testing.B, size int) {
+	payload := bytes.Repeat([]byte("x"), size)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Method = "POST"
+	config.Body = `{"name":"brutal"}`
+	config.Headers = map[string]string{
+		"Content-Type":  "application/json",
+		"Accept":        "application/json",
+		"X-Api-Key":     "secret",
+		"Cache-Control": "no-cache",
+	}
+	tester := newTestLoadTester(b, config)
+	rng := testRand()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if result := tester.sendRequest("POST", rng); result.Error != nil {
+			b.Fatal(result.Error)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// resolvedBuildInfo returns the version, commit and build date. Values set with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..." win;
+// otherwise they come from the module and VCS information embedded by go build.
+func resolvedBuildInfo() (ver, rev, date string) {
+	ver, rev, date = version, commit, buildDate
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ver, rev, date
+	}
+	if ver == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		ver = info.Main.Version
+	}
+	if commit != "" {
+		// Don't pair a stamped commit with the VCS time of another checkout
+		return ver, rev, date
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			rev = setting.Value
+		case "vcs.time":
+			if date == "" {
+				date = setting.Value
+			}
+		}
+	}
+	return ver, rev, date
+}
+
+// versionString renders the version with commit and build date when known
+func versionString() string {
+	ver, rev, date := resolvedBuildInfo()
+	s := ver
+	if rev != "" {
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		s += " (" + rev
+		if date != "" {
+			s += ", " + date
+		}
+		s += ")"
+	}
+	return s
+}
+
+// RunMetadata describes what produced a results file and where it ran
+type RunMetadata struct {
+	ToolVersion string    `json:"tool_version"`
+	Commit      string    `json:"commit,omitempty"`
+	BuildDate   string    `json:"build_date,omitempty"`
+	GoVersion   string    `json:"go_version"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	Hostname    string    `json:"hostname"`
+	CPUs        int       `json:"cpus"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// metadata describes the current run
+func (lt *LoadTester) metadata() RunMetadata {
+	ver, rev, date := resolvedBuildInfo()
+	hostname, _ := os.Hostname()
+	return RunMetadata{
+		ToolVersion: ver,
+		Commit:      rev,
+		BuildDate:   date,
+		GoVersion:   runtime.Version(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Hostname:    hostname,
+		CPUs:        runtime.NumCPU(),
+		StartedAt:   lt.startTime,
+		FinishedAt:  lt.endTime,
+	}
+}
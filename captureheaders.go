@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxCapturedValues bounds the distinct values remembered per captured header;
+// responses with further values are only counted
+const maxCapturedValues = 10
+
+// CapturedValue is one value of a --capture-headers header and when it was seen
+type CapturedValue struct {
+	Value     string    `json:"value"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// CapturedHeader summarises the values a header took across the run. Mixed is
+// set when responses disagreed, e.g. a proxy splitting traffic between two
+// deployments; Omitted counts responses with values beyond maxCapturedValues.
+type CapturedHeader struct {
+	Header  string          `json:"header"`
+	Values  []CapturedValue `json:"values"`
+	Omitted int             `json:"omitted,omitempty"`
+	Mixed   bool            `json:"mixed"`
+}
+
+// headerCapture tallies the values of a fixed set of response headers as
+// responses arrive, so memory stays bounded however long the run is
+type headerCapture struct {
+	mu      sync.Mutex
+	names   []string
+	values  []map[string]*CapturedValue // per name
+	omitted []int
+}
+
+func newHeaderCapture(names []string) *headerCapture {
+	c := &headerCapture{
+		names:   names,
+		values:  make([]map[string]*CapturedValue, len(names)),
+		omitted: make([]int, len(names)),
+	}
+	for i := range names {
+		c.values[i] = make(map[string]*CapturedValue)
+	}
+	return c
+}
+
+// record counts the captured headers of one response; absent headers count
+// as the value missingHeaderValue
+func (c *headerCapture) record(header http.Header, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, name := range c.names {
+		value := header.Get(name)
+		if value == "" {
+			value = missingHeaderValue
+		}
+		v := c.values[i][value]
+		if v == nil {
+			if len(c.values[i]) >= maxCapturedValues {
+				c.omitted[i]++
+				continue
+			}
+			v = &CapturedValue{Value: value, FirstSeen: at}
+			c.values[i][value] = v
+		}
+		v.Count++
+		v.LastSeen = at
+	}
+}
+
+// summary returns the tallies, most frequent value first
+func (c *headerCapture) summary() []CapturedHeader {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	headers := make([]CapturedHeader, len(c.names))
+	for i, name := range c.names {
+		values := make([]CapturedValue, 0, len(c.values[i]))
+		for _, v := range c.values[i] {
+			values = append(values, *v)
+		}
+		sort.Slice(values, func(a, b int) bool {
+			if values[a].Count != values[b].Count {
+				return values[a].Count > values[b].Count
+			}
+			return values[a].Value < values[b].Value
+		})
+		headers[i] = CapturedHeader{
+			Header:  name,
+			Values:  values,
+			Omitted: c.omitted[i],
+			Mixed:   len(values) > 1 || c.omitted[i] > 0,
+		}
+	}
+	return headers
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHeaderCapture(t *testing.T) {
+	c := newHeaderCapture([]string{"Server", "Via"})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c.record(http.Header{"Server": {"blue"}}, start.Add(time.Duration(i)*time.Second))
+	}
+	c.record(http.Header{"Server": {"green"}}, start.Add(10*time.Second))
+
+	summary := c.summary()
+	server, via := summary[0], summary[1]
+	if !server.Mixed || len(server.Values) != 2 {
+		t.Fatalf("Server = %+v, want two values flagged as mixed", server)
+	}
+	if v := server.Values[0]; v.Value != "blue" || v.Count != 5 || !v.FirstSeen.Equal(start) || !v.LastSeen.Equal(start.Add(4*time.Second)) {
+		t.Errorf("most frequent value = %+v", v)
+	}
+	if via.Mixed || len(via.Values) != 1 || via.Values[0].Value != missingHeaderValue || via.Values[0].Count != 6 {
+		t.Errorf("Via = %+v, want a single (none) value", via)
+	}
+}
+
+func TestHeaderCaptureIsBounded(t *testing.T) {
+	c := newHeaderCapture([]string{"X-Request-Id"})
+	for i := 0; i < maxCapturedValues+25; i++ {
+		c.record(http.Header{"X-Request-Id": {fmt.Sprint(i)}}, time.Now())
+	}
+	h := c.summary()[0]
+	if len(h.Values) != maxCapturedValues || h.Omitted != 25 || !h.Mixed {
+		t.Errorf("kept %d values, omitted %d, mixed %v; want %d, 25, true", len(h.Values), h.Omitted, h.Mixed, maxCapturedValues)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errChaosAborted marks requests canceled on purpose by --chaos-abort
+var errChaosAborted = errors.New("aborted by --chaos-abort")
+
+// parsePercent parses a share such as "2%" or "0.5%" into a fraction
+func parsePercent(s string) (float64, error) {
+	value, ok := strings.CutSuffix(strings.TrimSpace(s), "%")
+	if !ok {
+		return 0, fmt.Errorf("invalid percentage %q, want e.g. 2%%", s)
+	}
+	pct, err := strconv.ParseFloat(value, 64)
+	if err != nil || pct < 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid percentage %q, want 0%% to 100%%", s)
+	}
+	return pct / 100, nil
+}
+
+// parseChaosDelay parses a --chaos-delay such as "5%:200ms"
+func parseChaosDelay(s string) (rate float64, delay time.Duration, err error) {
+	pct, d, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid --chaos-delay %q, want e.g. 5%%:200ms", s)
+	}
+	if rate, err = parsePercent(pct); err != nil {
+		return 0, 0, err
+	}
+	if delay, err = time.ParseDuration(d); err != nil || delay <= 0 {
+		return 0, 0, fmt.Errorf("invalid --chaos-delay %q, the delay must be a positive duration", s)
+	}
+	return rate, delay, nil
+}
+
+// formatPercent renders a fraction the way parsePercent reads it
+func formatPercent(fraction float64) string {
+	return strconv.FormatFloat(fraction*100, 'g', -1, 64) + "%"
+}
+
+// chaosPlan is what --chaos-abort and --chaos-delay do to one request
+type chaosPlan struct {
+	delay time.Duration
+	abort bool
+	// abortAt places the abort after the request headers are written, as a
+	// fraction of the median response time so far
+	abortAt float64
+}
+
+// planChaos draws the chaos for a request from its random stream, so a seeded
+// run hits the same requests again. The draws don't depend on the outcome.
+func (lt *LoadTester) planChaos(rng *rand.Rand) chaosPlan {
+	var plan chaosPlan
+	if lt.config.ChaosDelayRate > 0 && rng.Float64() < lt.config.ChaosDelayRate {
+		plan.delay = lt.config.ChaosDelay
+	}
+	if lt.config.ChaosAbort > 0 {
+		plan.abort = rng.Float64() < lt.config.ChaosAbort
+		plan.abortAt = rng.Float64()
+	}
+	return plan
+}
+
+// chaosAborter cancels a request some time after its headers are written,
+// like a client that hangs up mid-request. A response arriving first is
+// abandoned before its body, so every planned abort happens.
+type chaosAborter struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	after  time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+// newChaosAbort derives the request context for a plan with an abort
+func (lt *LoadTester) newChaosAbort(parent context.Context, plan chaosPlan) *chaosAborter {
+	ctx, cancel := context.WithCancelCause(parent)
+	return &chaosAborter{
+		ctx:    ctx,
+		cancel: cancel,
+		after:  time.Duration(plan.abortAt * float64(lt.live.median())),
+	}
+}
+
+// wroteHeaders starts the countdown, called from the request's client trace.
+// Like error, it does nothing on a nil chaosAborter, a request left alone.
+func (a *chaosAborter) wroteHeaders() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped || a.timer != nil {
+		return
+	}
+	a.timer = time.AfterFunc(a.after, a.fire)
+}
+
+// fire aborts the request now
+func (a *chaosAborter) fire() {
+	a.cancel(errChaosAborted)
+}
+
+// stop releases the context once the request is over
+func (a *chaosAborter) stop() {
+	a.mu.Lock()
+	a.stopped = true
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.mu.Unlock()
+	a.cancel(nil)
+}
+
+// error replaces err with errChaosAborted when the abort is what caused it
+func (a *chaosAborter) error(err error) error {
+	if a != nil && err != nil && errors.Is(context.Cause(a.ctx), errChaosAborted) {
+		return errChaosAborted
+	}
+	return err
+}
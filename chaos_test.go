@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseChaosDelay(t *testing.T) {
+	rate, delay, err := parseChaosDelay("5%:200ms")
+	if err != nil || rate != 0.05 || delay != 200*time.Millisecond {
+		t.Errorf("parseChaosDelay = %v, %v, %v; want 0.05, 200ms", rate, delay, err)
+	}
+	for _, bad := range []string{"5%", "5:200ms", "120%:1s", "5%:0s", "5%:soon"} {
+		if _, _, err := parseChaosDelay(bad); err == nil {
+			t.Errorf("parseChaosDelay(%q) accepted", bad)
+		}
+	}
+	if got, err := parsePercent(formatPercent(0.025)); err != nil || got != 0.025 {
+		t.Errorf("percent round trip = %v, %v; want 0.025", got, err)
+	}
+}
+
+func TestChaosAbortIsSeededAndNotAFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Seed = 42
+	config.ChaosAbort = 0.3
+	var aborted []int
+	for i := 0; i < 2; i++ {
+		stats := newTestLoadTester(t, config).Run(nil)
+		if stats.FailedReqs != 0 {
+			t.Errorf("failed = %d, want aborts kept out of the failures", stats.FailedReqs)
+		}
+		if stats.TotalRequests+stats.ChaosAborted != config.Requests {
+			t.Errorf("%d results and %d aborts, want %d requests in all",
+				stats.TotalRequests, stats.ChaosAborted, config.Requests)
+		}
+		aborted = append(aborted, stats.ChaosAborted)
+	}
+	if aborted[0] == 0 || aborted[0] != aborted[1] {
+		t.Errorf("aborted %v with the same seed, want the same nonzero count", aborted)
+	}
+}
+
+func TestChaosDelayIsNotLatency(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 10
+	config.ChaosDelayRate = 1
+	config.ChaosDelay = 50 * time.Millisecond
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.ChaosDelayed != 10 {
+		t.Errorf("delayed = %d, want all 10", stats.ChaosDelayed)
+	}
+	if stats.MaxResponseTime >= config.ChaosDelay {
+		t.Errorf("max response time %v includes the %v delay", stats.MaxResponseTime, config.ChaosDelay)
+	}
+}
+
+func TestChaosAbortKeepsRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.ChaosAbort = 1
+	config.RequestIDHeaders = []string{"X-Request-ID"}
+	tester := newTestLoadTester(t, config)
+	rng := testRand()
+
+	// An abort planned at a fraction of an hour-long median comes after the
+	// response, and one at a zero median before it; both keep the request's ID
+	for _, median := range []time.Duration{time.Hour, 0} {
+		// Start clears the median; no ETA updates are needed
+		tester.live.Start(0)
+		tester.live.Stop()
+		if median > 0 {
+			tester.live.Record(Result{ResponseTime: median, Timestamp: time.Now()}, true)
+		}
+		result := tester.sendRequest("GET", rng)
+		if result.Error != errChaosAborted {
+			t.Fatalf("median %v: error %v, want the chaos abort", median, result.Error)
+		}
+		if result.RequestID == "" || result.Host == "" {
+			t.Errorf("median %v: aborted result lost its request ID or host: %+v", median, result)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// ANSI colors used for live health indication
+const (
+	colorReset  = "\033[0m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
+)
+
+// useColor and useUnicode are cleared for terminals and logs that can't
+// render ANSI colors or non-ASCII glyphs; see configureOutputStyle
+var (
+	useColor   = true
+	useUnicode = true
+)
+
+// configureOutputStyle disables colors when --plain is given, NO_COLOR is set
+// (https://no-color.org) or the terminal is dumb, and non-ASCII output with --plain
+func configureOutputStyle(plain bool) {
+	if plain || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		useColor = false
+	}
+	if plain {
+		useUnicode = false
+	}
+}
+
+// latencyThresholds classify latencies as healthy, degraded or critical
+type latencyThresholds struct {
+	warn time.Duration // 0 disables the yellow band
+	crit time.Duration // 0 disables the red band
+}
+
+func (t latencyThresholds) enabled() bool {
+	return t.warn > 0 || t.crit > 0
+}
+
+// color returns the ANSI color for d: red at or above crit, yellow at or above warn, green otherwise
+func (t latencyThresholds) color(d time.Duration) string {
+	switch {
+	case t.crit > 0 && d >= t.crit:
+		return colorRed
+	case t.warn > 0 && d >= t.warn:
+		return colorYellow
+	default:
+		return colorGreen
+	}
+}
+
+// colorize wraps the formatted value of d in its threshold color, or returns it
+// uncolored when no thresholds are configured
+func (t latencyThresholds) colorize(d time.Duration, formatted string) string {
+	if !t.enabled() || !useColor {
+		return formatted
+	}
+	return t.color(d) + formatted + colorReset
+}
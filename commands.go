@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newRootCmd builds the command tree. Running the root command with flags
+// directly still starts a load test, as before the run subcommand existed.
+func newRootCmd() *cobra.Command {
+	var rootCmd = &cobra.Command{
+		Use:   "brutal [URL]",
+		Short: "Brutal - A powerful HTTP load testing tool",
+		Long: `Brutal is a blazingly fast HTTP load testing tool with comprehensive analytics.
+It provides detailed statistics, percentile analysis, and supports various HTTP methods.`,
+		Example: `  brutal run https://api.example.com
+  brutal run https://api.example.com -n 1000 -c 50 -o results.json
+  brutal report results.json
+  brutal compare before.json after.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && cmd.Flags().NFlag() == 0 {
+				return cmd.Help()
+			}
+			fmt.Fprintln(os.Stderr, "Warning: running a test without the run subcommand is deprecated; use \"brutal run\"")
+			return runLoadTest(cmd, args)
+		},
+		Args:          cobra.MaximumNArgs(1),
+		Version:       versionString(),
+		SilenceErrors: true,
+	}
+	rootCmd.SetVersionTemplate("Brutal Load Tester {{.Version}}\n")
+	addRunFlags(rootCmd.Flags())
+	// Still accepted for backward compatibility, but documented under run
+	rootCmd.Flags().VisitAll(func(f *pflag.Flag) { f.Hidden = true })
+
+	// Add persistent flags
+	rootCmd.PersistentFlags().BoolVarP(&noBanner, "no-banner", "", false, "Disable ASCII art banner")
+	rootCmd.PersistentFlags().BoolVarP(&plain, "plain", "", false, "Plain ASCII output without colors or Unicode glyphs (colors are also off when NO_COLOR is set)")
+	cobra.OnInitialize(func() { configureOutputStyle(plain) })
+
+	// Add version command
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the version number of Brutal",
+		Run: func(cmd *cobra.Command, args []string) {
+			if !noBanner {
+				printBanner()
+			}
+			fmt.Printf("Brutal Load Tester %s\n", versionString())
+			fmt.Printf("Built with %s for %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+			fmt.Printf("Powered by Cobra CLI Framework\n")
+			fmt.Println()
+		},
+	}
+	rootCmd.AddCommand(versionCmd)
+
+	// Add completion command
+	var completionCmd = &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate completion script",
+		Long: `To load completions:
+
+Bash:
+  $ source <(brutal completion bash)
+
+  # To load completions for each session, execute once:
+  # Linux:
+  $ brutal completion bash > /etc/bash_completion.d/brutal
+  # macOS:
+  $ brutal completion bash > /usr/local/etc/bash_completion.d/brutal
+
+Zsh:
+  # If shell completion is not already enabled in your environment,
+  # you will need to enable it.  You can execute the following once:
+  $ echo "autoload -U compinit; compinit" >> ~/.zshrc
+
+  # To load completions for each session, execute once:
+  $ brutal completion zsh > "${fpath[1]}/_brutal"
+
+  # You will need to start a new shell for this setup to take effect.
+
+fish:
+  $ brutal completion fish | source
+
+  # To load completions for each session, execute once:
+  $ brutal completion fish > ~/.config/fish/completions/brutal.fish
+
+PowerShell:
+  PS> brutal completion powershell | Out-String | Invoke-Expression
+
+  # To load completions for every new session, run:
+  PS> brutal completion powershell > brutal.ps1
+  # and source this file from your PowerShell profile.
+`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Run: func(cmd *cobra.Command, args []string) {
+			switch args[0] {
+			case "bash":
+				cmd.Root().GenBashCompletion(os.Stdout)
+			case "zsh":
+				cmd.Root().GenZshCompletion(os.Stdout)
+			case "fish":
+				cmd.Root().GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+		},
+	}
+	rootCmd.AddCommand(completionCmd)
+
+	rootCmd.AddCommand(newRunCmd(), newReportCmd(), newCompareCmd(), newServeCmd(), newAgentCmd())
+	return rootCmd
+}
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [URL]",
+		Short: "Run a load test",
+		Example: `  brutal run https://api.example.com
+  brutal run https://api.example.com -n 1000 -c 50
+  brutal run https://api.example.com -X POST -d '{"test": "data"}'
+  brutal run https://api.example.com -p http://proxy.example.com:8080`,
+		RunE: runLoadTest,
+		Args: cobra.MaximumNArgs(1),
+	}
+	addRunFlags(cmd.Flags())
+	return cmd
+}
+
+// addRunFlags registers the load test flags, shared by run and the legacy root
+// command. Like the flags' defaults, the settings of a --config applied
+// earlier are reset.
+func addRunFlags(flags *pflag.FlagSet) {
+	configSettings = fileSettings{}
+	flags.SetNormalizeFunc(runFlagAliases)
+	flags.StringVarP(&targetURL, "url", "u", "", "Target URL to test")
+	flags.StringVarP(&presetName, "preset", "", "", "Start from the defaults of a preset: "+presetNames()+
+		" (explicit flags win; stress steps its rate through every phase rather than stopping at the first failure)")
+	flags.StringVarP(&configFile, "config", "", "", "Take the settings of a --print-config dump or an --output results file (explicit flags win)")
+	flags.BoolVarP(&printConfig, "print-config", "", false, "Print the effective configuration as JSON and exit without running")
+	flags.StringVarP(&method, "method", "X", "GET", "HTTP method")
+	flags.StringVarP(&methodMix, "methods", "", "", "Weighted method mix, e.g. GET:70,POST:20,PUT:10, or percentages adding up to 100 like GET:90%,POST:10% (overrides --method; also --method-mix)")
+	flags.StringVarP(&headers, "headers", "H", "", "Headers in JSON format")
+	flags.StringVarP(&contentType, "content-type", "", "", "Content-Type header, e.g. application/xml (overrides the default for a body; a Content-Type in --headers wins)")
+	flags.StringArrayVarP(&requestIDHeaders, "request-id-header", "", nil, "Send a fresh UUID per request in this header, e.g. X-Request-ID or Idempotency-Key (repeatable, all get the same id)")
+	flags.StringVarP(&bearerFile, "bearer-file", "", "", "File with a bearer token for the Authorization header, re-read periodically and after a 401")
+	flags.DurationVarP(&bearerRefresh, "bearer-refresh", "", 30*time.Second, "How often --bearer-file is re-read (0 to only re-read after a 401)")
+	flags.StringVarP(&body, "body", "d", "", "Request body")
+	flags.StringArrayVarP(&formPairs, "form", "", nil, "Form field key=value for an application/x-www-form-urlencoded body, escaped for you (repeatable)")
+	flags.StringArrayVarP(&formURLEncoded, "form-urlencoded", "", nil, "Form fields as key=val&key2=val2, escaped for you (combines with --form)")
+	flags.IntVarP(&concurrent, "concurrent", "c", 10, "Number of concurrent requests")
+	flags.StringVarP(&phasesFile, "phases", "", "", "File of \"name duration targetRPS [thinkTime]\" rows run one after another, with results broken down per phase")
+	flags.Float64VarP(&maxRPSPerWorker, "max-rps-per-worker", "", 0, "Cap each of the --concurrent workers at this many requests per second, to simulate distinct clients with bounded rates")
+	flags.StringVarP(&profileFile, "profile", "", "", "File of \"timeOffset targetRPS\" rows; the request rate is interpolated between them and the run ends at the last row")
+	flags.IntVarP(&requests, "requests", "n", 100, "Total number of requests, 0 for no limit until --max-duration or the --profile ends")
+	flags.IntVarP(&repeat, "repeat", "", 1, "Run the whole test this many times and report how much the runs vary")
+	flags.DurationVarP(&targetP95, "target-p95", "", 0, "Search for the highest concurrency up to -c whose p95 stays under this, one probing run per level")
+	flags.Uint64VarP(&seed, "seed", "", 0, "Seed for every random choice so runs can be reproduced (default: random, printed in the header and saved in results)")
+	flags.BoolVarP(&honorRetryAfter, "honor-retry-after", "", false, "Pause new requests for the Retry-After a 429 or 503 asks for, to find the rate the server sustains")
+	flags.DurationVarP(&maxDuration, "max-duration", "", 0, "Stop the run after this long even if requests remain (safety cap)")
+	flags.IntVarP(&retries, "retries", "", 0, "Re-send a request that failed with a connection error, 5xx or 429 up to this many times")
+	flags.DurationVarP(&retryBackoff, "retry-backoff", "", 100*time.Millisecond, "Backoff before the first retry, doubled for each further one")
+	flags.DurationVarP(&retryMaxBackoff, "retry-max-backoff", "", 2*time.Second, "Cap on the backoff between retries")
+	flags.Float64VarP(&retryJitter, "retry-jitter", "", 1, "Fraction of each backoff drawn at random, from 0 (none) to 1 (full jitter)")
+	flags.StringVarP(&chaosAbort, "chaos-abort", "", "", "Cancel this share of requests (e.g. 2%) at a random point after their headers are sent, reported apart from failures")
+	flags.StringVarP(&chaosDelay, "chaos-delay", "", "", "Hold back this share of requests before sending, e.g. 5%:200ms")
+	flags.DurationVarP(&gracePeriod, "grace-period", "", 0, "For this long from the start, retry refused and reset connections until they succeed, reporting only the last attempt")
+	flags.DurationVarP(&drainTimeout, "drain-timeout", "", 0, "When the run ends, wait this long for requests in flight before canceling them (0 = cancel at --max-duration)")
+	flags.DurationVarP(&cooldown, "cooldown", "", 0, "Stop sending this long before the end of a --max-duration or --profile run and report requests completing then separately")
+	flags.DurationVarP(&timeout, "timeout", "t", 30*time.Second, "Request timeout, 0 for none")
+	flags.BoolVarP(&insecure, "insecure", "k", false, "Skip TLS certificate verification")
+	flags.StringArrayVarP(&pinSHA256, "pin-sha256", "", nil, "Base64 SHA-256 public key pin the server certificate chain must match (repeatable)")
+	flags.StringVarP(&output, "output", "o", "", "Output file for JSON results")
+	flags.StringVarP(&proxy, "proxy", "p", "", "Proxy URL (e.g., http://proxy.example.com:8080)")
+	flags.StringVarP(&hostHeader, "host", "", "", "Override the Host header (virtual host) independently of the URL")
+	flags.StringVarP(&randomBodySize, "random-body-size", "", "", "Send a random body of this size (e.g. 64KB, 1MB) instead of --body")
+	flags.BoolVarP(&randomBodyEach, "random-body-each", "", false, "Generate a new random body for every request instead of reusing one")
+	flags.BoolVarP(&checkConsistency, "check-consistency", "", false, "Hash successful response bodies and report how many different bodies identical requests got")
+	flags.BoolVarP(&countHeaders, "count-headers", "", false, "Count estimated response header bytes and the bytes sent, to report traffic in both directions")
+	flags.BoolVarP(&sse, "sse", "", false, "Hold --concurrent server-sent event streams open until --max-duration and report events/sec and the time between events")
+	flags.StringSliceVarP(&captureHeaders, "capture-headers", "", nil, "Count the distinct values of these response headers, e.g. Server,Via, and flag any that varied")
+	flags.StringArrayVarP(&trackHeaders, "track-header", "", nil, "Tally the values of this response header with latency percentiles per value, e.g. X-Cache (repeatable)")
+	flags.StringVarP(&ifNoneMatch, "if-none-match", "", "", "Send conditional requests: \"auto\" captures ETag/Last-Modified with an initial request, any other value is sent as the ETag")
+	flags.StringVarP(&bodyTemplateFile, "body-template", "", "", "File with a Go text/template rendered into a new body for every request")
+	flags.StringVarP(&rawRequest, "raw-request", "", "", "File with a raw HTTP request to replay (overrides method, headers and body)")
+	flags.IntVarP(&maxConnsPerHost, "max-conns-per-host", "", 0, "Limit connections per host, including those in use (0 = no limit)")
+	flags.IntVarP(&maxIdleConns, "max-idle-conns", "", 0, "Idle connections kept across all hosts (0 = twice --concurrent)")
+	flags.StringVarP(&dnsServer, "dns-server", "", "", "Resolve hosts with this name server (IP[:port], default port 53) instead of the system resolver")
+	flags.BoolVarP(&prewarmConns, "prewarm-conns", "", false, "Resolve DNS and open --concurrent connections before the measured run")
+	flags.BoolVarP(&interactive, "interactive", "", false, "Control the run from the keyboard: +/- (or ]/[) adjust concurrency, space pauses/resumes, d toggles request details, e lists errors")
+	flags.StringVarP(&controlFile, "control", "", "", "JSON file like {\"concurrency\": 50, \"rps\": 200} applied whenever it changes during the run")
+	flags.BoolVarP(&once, "once", "", false, "Send a single request, print the result and exit non-zero unless it succeeds")
+	flags.BoolVarP(&strict, "strict", "", false, "Exit non-zero if any request fails, after reporting the run")
+	flags.BoolVarP(&connectOnly, "connect-only", "", false, "Only open and close connections (with TLS handshake for https) to measure connect cost")
+	flags.BoolVarP(&noSummary, "no-final-summary", "", false, "Don't print the results summary when the test finishes")
+	flags.DurationVarP(&apdexT, "apdex-t", "", 0, "Report the Apdex score for this target response time (satisfied <= T, tolerating <= 4T)")
+	flags.DurationVarP(&warnLatency, "warn-latency", "", 0, "Show live latencies at or above this in yellow")
+	flags.DurationVarP(&critLatency, "crit-latency", "", 0, "Show live latencies at or above this in red")
+	flags.StringVarP(&progressMode, "progress", "", progressAuto, "Progress display: bar, interval (one line every --progress-interval, for CI logs), none, or auto")
+	flags.BoolVarP(&noProgress, "no-progress", "", false, "Don't show live progress during the run; the final summary is still printed (same as --progress none)")
+	flags.DurationVarP(&progressEvery, "progress-interval", "", 10*time.Second, "How often a progress line is printed with --progress interval")
+	flags.BoolVarP(&quiet, "quiet", "q", false, "Print only a single-line result (or just the JSON with --format json)")
+	flags.BoolVarP(&summaryOnly, "summary-only", "", false, "Report only the headline (requests, success rate, RPS, time) instead of the full summary")
+	flags.StringVarP(&outputFormat, "format", "", formatText, "Final summary format: text or json (written to stdout)")
+	flags.StringVarP(&webhookURL, "webhook", "", "", "POST the final summary to this URL when the run finishes (e.g. a Slack incoming webhook)")
+	flags.StringVarP(&webhookFormat, "webhook-format", "", webhookAuto, "Webhook payload: json (the --format json summary plus a text line), slack, or auto (slack for hooks.slack.com)")
+	flags.BoolVarP(&otelEnabled, "otel", "", false, "Send a W3C traceparent with every request and export a client span per request to an OTLP/HTTP collector")
+	flags.StringVarP(&otelEndpoint, "otel-endpoint", "", "", "OTLP/HTTP collector for --otel (default $OTEL_EXPORTER_OTLP_ENDPOINT or http://localhost:4318); implies --otel")
+	flags.StringVarP(&logFile, "log-file", "", "", "Append a log of the run (config, warnings, progress every --progress-interval) to this file")
+	flags.StringVarP(&logLevel, "log-level", "", "info", "Log file level: debug (adds every failed request and retry), info, warn or error")
+	flags.StringVarP(&logFormat, "log-format", "", logFormatJSON, "Log file format: json or text (key=value pairs)")
+	flags.CountVarP(&verbosity, "verbose", "v", "Print every request as it completes (-vv adds response headers); limited to small -n")
+	flags.BoolVarP(&selfMetrics, "self-metrics", "", false, "Show the tool's own goroutines, file descriptors, memory and allocations per request")
+}
+
+// runFlagAliases maps the other names of a run flag to the flag
+func runFlagAliases(flags *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "method-mix" {
+		name = "methods"
+	}
+	return pflag.NormalizedName(name)
+}
+
+func newServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "serve",
+		Short:  "Serve results over HTTP (not implemented yet)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("serve is not implemented yet")
+		},
+	}
+}
+
+func newAgentCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "agent",
+		Short:  "Run as a distributed load agent (not implemented yet)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("agent is not implemented yet")
+		},
+	}
+}
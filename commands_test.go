@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunFlagsParse(t *testing.T) {
+	cmd := newRunCmd()
+	if err := cmd.ParseFlags([]string{"-n", "5", "-c", "2", "--methods", "GET:3,POST:1", "--max-duration", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := buildConfig([]string{"http://example.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Requests != 5 || config.Concurrent != 2 || config.MaxDuration != time.Minute || len(config.MethodMix) != 2 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+}
+
+func TestLegacyRootFlagsStillParse(t *testing.T) {
+	root := newRootCmd()
+	if err := root.ParseFlags([]string{"-u", "http://example.invalid", "-n", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	if targetURL != "http://example.invalid" || requests != 7 {
+		t.Errorf("got url %q and %d requests", targetURL, requests)
+	}
+}
+
+func writeResults(t *testing.T, url string, stats *Stats) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := newTestLoadTester(t, testConfig(url)).SaveResultsToJSON(path, stats); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testStats(rps float64, p95 time.Duration) *Stats {
+	return &Stats{
+		TotalRequests:  100,
+		SuccessfulReqs: 100,
+		RequestsPerSec: rps,
+		Percentiles:    map[int]time.Duration{50: p95 / 2, 95: p95, 99: p95 * 2},
+	}
+}
+
+func TestReportCommandJSON(t *testing.T) {
+	path := writeResults(t, "http://example.invalid", testStats(250, 20*time.Millisecond))
+
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"report", path, "--format", "json"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var saved savedResults
+	if err := json.Unmarshal(out.Bytes(), &saved); err != nil {
+		t.Fatalf("report output isn't JSON: %v", err)
+	}
+	if saved.Stats.RequestsPerSec != 250 || saved.Stats.Percentiles[95] != 20*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", saved.Stats)
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	baseline, err := loadResultsFile(writeResults(t, "http://example.invalid", testStats(200, 20*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidate, err := loadResultsFile(writeResults(t, "http://example.invalid", testStats(150, 30*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidate.Metadata.ToolVersion = "v9.9.9"
+
+	var out bytes.Buffer
+	if err := compareResults(&out, baseline, candidate, 0); err != nil {
+		t.Fatalf("unexpected error without a threshold: %v", err)
+	}
+	for _, want := range []string{"different versions", "-25.0%", "+50.0%"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("compare output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	err = compareResults(&bytes.Buffer{}, baseline, candidate, 30)
+	if err == nil || !strings.Contains(err.Error(), "p95") || strings.Contains(err.Error(), "RPS") {
+		t.Errorf("with a 30%% threshold got %v, want only the p95 regression", err)
+	}
+}
+
+func TestStubCommandsFail(t *testing.T) {
+	for _, name := range []string{"serve", "agent"} {
+		root := newRootCmd()
+		root.SetArgs([]string{name})
+		if err := root.Execute(); err == nil {
+			t.Errorf("%s succeeded, want a not implemented error", name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// conditionalAuto makes --if-none-match capture the validators from the target
+const conditionalAuto = "auto"
+
+// cacheValidators are the ETag and Last-Modified values sent back as
+// If-None-Match and If-Modified-Since
+type cacheValidators struct {
+	etag         string
+	lastModified string
+	// size is the body size of the full response they belong to, used to
+	// estimate the bytes a 304 saved
+	size int64
+}
+
+// setConditionalHeaders makes req conditional on the current validators
+func (lt *LoadTester) setConditionalHeaders(req *http.Request) {
+	v := lt.validators.Load()
+	if v == nil {
+		return
+	}
+	if v.etag != "" {
+		req.Header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" {
+		req.Header.Set("If-Modified-Since", v.lastModified)
+	}
+}
+
+// captureValidators sends an unconditional request and keeps the ETag and
+// Last-Modified of the response for the conditional requests of the run
+func (lt *LoadTester) captureValidators() (*cacheValidators, error) {
+	req, err := http.NewRequestWithContext(lt.ctx, lt.config.Method, lt.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	lt.setHeaders(req)
+
+	resp, err := lt.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	size, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("initial request returned status %d, want 200", resp.StatusCode)
+	}
+	v := &cacheValidators{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		size:         size,
+	}
+	if v.etag == "" && v.lastModified == "" {
+		return nil, fmt.Errorf("the response has no ETag or Last-Modified header to revalidate with")
+	}
+	lt.validators.Store(v)
+	return v, nil
+}
+
+// updateValidators replaces the validators when a full response carries new
+// ones, i.e. the resource changed during the run
+func (lt *LoadTester) updateValidators(resp *http.Response, size int64) {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	current := lt.validators.Load()
+	if current != nil && current.etag == etag && current.lastModified == lastModified {
+		return
+	}
+	lt.validators.Store(&cacheValidators{etag: etag, lastModified: lastModified, size: size})
+}
+
+// ConditionalStats splits the responses to conditional requests into 304s
+// and full 200 responses
+type ConditionalStats struct {
+	NotModified    int
+	Full           int
+	AvgNotModified time.Duration
+	AvgFull        time.Duration
+	// BytesSaved estimates the body bytes the 304s didn't transfer, from the
+	// average full response size
+	BytesSaved int64
+}
+
+// buildConditionalStats must be called with lt.mu held
+func (lt *LoadTester) buildConditionalStats() *ConditionalStats {
+	s := &ConditionalStats{}
+	var notModifiedTime, fullTime time.Duration
+	var fullBytes int64
+	for _, result := range lt.results {
+		switch {
+		case result.Error != nil:
+		case result.StatusCode == http.StatusNotModified:
+			s.NotModified++
+			notModifiedTime += result.ResponseTime
+		case result.StatusCode == http.StatusOK:
+			s.Full++
+			fullTime += result.ResponseTime
+			fullBytes += result.ContentSize
+		}
+	}
+
+	if s.NotModified > 0 {
+		s.AvgNotModified = notModifiedTime / time.Duration(s.NotModified)
+	}
+	fullSize := int64(0)
+	if s.Full > 0 {
+		s.AvgFull = fullTime / time.Duration(s.Full)
+		fullSize = fullBytes / int64(s.Full)
+	} else if v := lt.validators.Load(); v != nil {
+		fullSize = v.size
+	}
+	s.BytesSaved = int64(s.NotModified) * fullSize
+	return s
+}
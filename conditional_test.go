@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	body := strings.Repeat("x", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v` + string(rune('0'+version.Load())) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.IfNoneMatch = conditionalAuto
+	tester := newTestLoadTester(t, config)
+	v, err := tester.captureValidators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.etag != `"v1"` || v.size != 1000 {
+		t.Fatalf("captured %+v", v)
+	}
+
+	stats := tester.Run(nil)
+	c := stats.Conditional
+	if c == nil || c.NotModified != config.Requests || c.Full != 0 {
+		t.Fatalf("conditional stats = %+v, want %d not modified", c, config.Requests)
+	}
+	if c.BytesSaved != int64(config.Requests)*1000 {
+		t.Errorf("bytes saved = %d, want %d", c.BytesSaved, config.Requests*1000)
+	}
+	if stats.SuccessfulReqs != config.Requests {
+		t.Errorf("successful = %d, want 304s to count as successes", stats.SuccessfulReqs)
+	}
+
+	// A changed resource is fetched in full once, then revalidated with its new ETag
+	version.Store(2)
+	tester.limiter.setLimit(1)
+	tester.results = nil
+	if c := tester.Run(nil).Conditional; c.Full != 1 || c.NotModified != config.Requests-1 {
+		t.Errorf("after a change: %d full, %d not modified, want 1 and %d", c.Full, c.NotModified, config.Requests-1)
+	}
+}
+
+func TestCaptureValidatorsNeedsValidators(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.IfNoneMatch = conditionalAuto
+	if _, err := newTestLoadTester(t, config).captureValidators(); err == nil {
+		t.Error("expected an error for a response without ETag or Last-Modified")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// jsonDuration is a time.Duration written to JSON as a string like "30s".
+// Numbers are still read, as nanoseconds, so results files saved before
+// durations were written this way load unchanged.
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var ns int64
+		if err := json.Unmarshal(data, &ns); err != nil {
+			return fmt.Errorf("duration must be a string like \"30s\" or nanoseconds, got %s", data)
+		}
+		*d = jsonDuration(ns)
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(parsed)
+	return nil
+}
+
+// plainConfig is Config without its JSON methods, for them to embed
+type plainConfig Config
+
+// configJSON is the JSON form of a Config: the same fields, except that the
+// durations shadow the embedded ones to read and write them as strings
+type configJSON struct {
+	plainConfig
+	Duration        jsonDuration `json:"duration"`
+	Timeout         jsonDuration `json:"timeout"`
+	MaxDuration     jsonDuration `json:"max_duration,omitempty"`
+	BearerRefresh   jsonDuration `json:"bearer_refresh,omitempty"`
+	ApdexT          jsonDuration `json:"apdex_t,omitempty"`
+	DrainTimeout    jsonDuration `json:"drain_timeout,omitempty"`
+	Cooldown        jsonDuration `json:"cooldown,omitempty"`
+	RetryBackoff    jsonDuration `json:"retry_backoff,omitempty"`
+	RetryMaxBackoff jsonDuration `json:"retry_max_backoff,omitempty"`
+	GracePeriod     jsonDuration `json:"grace_period,omitempty"`
+	ChaosDelay      jsonDuration `json:"chaos_delay,omitempty"`
+}
+
+func newConfigJSON(c Config) configJSON {
+	return configJSON{
+		plainConfig:     plainConfig(c),
+		Duration:        jsonDuration(c.Duration),
+		Timeout:         jsonDuration(c.Timeout),
+		MaxDuration:     jsonDuration(c.MaxDuration),
+		BearerRefresh:   jsonDuration(c.BearerRefresh),
+		ApdexT:          jsonDuration(c.ApdexT),
+		DrainTimeout:    jsonDuration(c.DrainTimeout),
+		Cooldown:        jsonDuration(c.Cooldown),
+		RetryBackoff:    jsonDuration(c.RetryBackoff),
+		RetryMaxBackoff: jsonDuration(c.RetryMaxBackoff),
+		GracePeriod:     jsonDuration(c.GracePeriod),
+		ChaosDelay:      jsonDuration(c.ChaosDelay),
+	}
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newConfigJSON(c))
+}
+
+// UnmarshalJSON reads durations written as strings or as nanoseconds; fields
+// missing from data keep their current values
+func (c *Config) UnmarshalJSON(data []byte) error {
+	cj := newConfigJSON(*c)
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+	*c = Config(cj.plainConfig)
+	c.Duration = time.Duration(cj.Duration)
+	c.Timeout = time.Duration(cj.Timeout)
+	c.MaxDuration = time.Duration(cj.MaxDuration)
+	c.BearerRefresh = time.Duration(cj.BearerRefresh)
+	c.ApdexT = time.Duration(cj.ApdexT)
+	c.DrainTimeout = time.Duration(cj.DrainTimeout)
+	c.Cooldown = time.Duration(cj.Cooldown)
+	c.RetryBackoff = time.Duration(cj.RetryBackoff)
+	c.RetryMaxBackoff = time.Duration(cj.RetryMaxBackoff)
+	c.GracePeriod = time.Duration(cj.GracePeriod)
+	c.ChaosDelay = time.Duration(cj.ChaosDelay)
+	return nil
+}
+
+// timeoutUnset is the Timeout of a loaded config without one, told apart
+// from a timeout of 0, no limit, as no valid config has a negative timeout
+const timeoutUnset = -1
+
+// loadConfigFile reads a Config from a JSON file: a --print-config dump, or a
+// results file saved with --output, whose "config" member is used. A missing
+// timeout is left as timeoutUnset.
+func loadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var saved struct {
+		Config json.RawMessage `json:"config"`
+	}
+	if err := json.Unmarshal(data, &saved); err == nil && len(saved.Config) > 0 && string(saved.Config) != "null" {
+		data = saved.Config
+	}
+	config := Config{Timeout: timeoutUnset}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("%s is not a config or results file: %v", path, err)
+	}
+	return config, nil
+}
+
+// fileSettings are the settings of a --config whose flags take a file name:
+// the rate profile or phases, and the body template. buildConfig falls back
+// on them when none of the flags that would replace them is given.
+type fileSettings struct {
+	profile      []RatePoint
+	phases       []Phase
+	bodyTemplate string
+}
+
+// configSettings holds the fileSettings of the --config being applied
+var configSettings fileSettings
+
+// configFlagValues renders the settings of c that have a flag as flag values;
+// lists are joined with commas. Duration has no flag of its own and becomes
+// --max-duration, the shorter of the two applying when both are set. Without
+// a request count it drives the run, which then has no request limit.
+func configFlagValues(c Config) map[string]string {
+	values := make(map[string]string)
+	set := func(flag, value string, ok bool) {
+		if ok {
+			values[flag] = value
+		}
+	}
+	set("url", c.URL, c.URL != "")
+	set("method", c.Method, c.Method != "")
+	if len(c.Headers) > 0 {
+		data, _ := json.Marshal(c.Headers)
+		values["headers"] = string(data)
+	}
+	set("body", c.Body, c.Body != "")
+	set("concurrent", strconv.Itoa(c.Concurrent), c.Concurrent > 0)
+	set("requests", strconv.Itoa(c.Requests), c.Requests > 0)
+	set("requests", "0", c.Requests == 0 && c.Duration > 0)
+	set("timeout", c.Timeout.String(), c.Timeout != timeoutUnset)
+	limit := c.MaxDuration
+	if c.Duration > 0 && (limit == 0 || c.Duration < limit) {
+		limit = c.Duration
+	}
+	set("max-duration", limit.String(), limit > 0)
+	set("insecure", "true", c.InsecureTLS)
+	set("pin-sha256", strings.Join(c.PinSHA256, ","), len(c.PinSHA256) > 0)
+	set("proxy", c.ProxyURL, c.ProxyURL != "")
+	set("host", c.Host, c.Host != "")
+	set("connect-only", "true", c.ConnectOnly)
+	set("prewarm-conns", "true", c.PrewarmConns)
+	set("random-body-size", strconv.FormatInt(c.RandomBodySize, 10), c.RandomBodySize > 0)
+	set("random-body-each", "true", c.RandomBodyEach)
+	if len(c.MethodMix) > 0 {
+		parts := make([]string, len(c.MethodMix))
+		for i, m := range c.MethodMix {
+			parts[i] = m.Method + ":" + strconv.Itoa(m.Weight)
+		}
+		values["methods"] = strings.Join(parts, ",")
+	}
+	if c.BearerFile != "" {
+		values["bearer-file"] = c.BearerFile
+		values["bearer-refresh"] = c.BearerRefresh.String()
+	}
+	set("seed", strconv.FormatUint(c.Seed, 10), c.Seed != 0)
+	set("apdex-t", c.ApdexT.String(), c.ApdexT > 0)
+	set("honor-retry-after", "true", c.HonorRetryAfter)
+	set("drain-timeout", c.DrainTimeout.String(), c.DrainTimeout > 0)
+	set("cooldown", c.Cooldown.String(), c.Cooldown > 0)
+	set("dns-server", c.DNSServer, c.DNSServer != "")
+	set("max-conns-per-host", strconv.Itoa(c.MaxConnsPerHost), c.MaxConnsPerHost > 0)
+	set("max-idle-conns", strconv.Itoa(c.MaxIdleConns), c.MaxIdleConns > 0)
+	set("max-rps-per-worker", strconv.FormatFloat(c.MaxRPSPerWorker, 'g', -1, 64), c.MaxRPSPerWorker > 0)
+	set("check-consistency", "true", c.CheckConsistency)
+	set("count-headers", "true", c.CountHeaders)
+	set("sse", "true", c.SSE)
+	set("request-id-header", strings.Join(c.RequestIDHeaders, ","), len(c.RequestIDHeaders) > 0)
+	set("capture-headers", strings.Join(c.CaptureHeaders, ","), len(c.CaptureHeaders) > 0)
+	set("track-header", strings.Join(c.TrackHeaders, ","), len(c.TrackHeaders) > 0)
+	set("if-none-match", c.IfNoneMatch, c.IfNoneMatch != "")
+	set("grace-period", c.GracePeriod.String(), c.GracePeriod > 0)
+	set("chaos-abort", formatPercent(c.ChaosAbort), c.ChaosAbort > 0)
+	set("chaos-delay", formatPercent(c.ChaosDelayRate)+":"+c.ChaosDelay.String(), c.ChaosDelayRate > 0)
+	if c.Retries > 0 {
+		values["retries"] = strconv.Itoa(c.Retries)
+		values["retry-backoff"] = c.RetryBackoff.String()
+		values["retry-max-backoff"] = c.RetryMaxBackoff.String()
+		values["retry-jitter"] = strconv.FormatFloat(c.RetryJitter, 'g', -1, 64)
+	}
+	return values
+}
+
+// applyConfigFile sets the flags not given explicitly from the config in
+// path, and configSettings from the settings without a flag value. The URL is
+// left alone when one was passed as an argument.
+func applyConfigFile(flags *pflag.FlagSet, path string, hasURLArg bool) error {
+	config, err := loadConfigFile(path)
+	if err != nil {
+		return fmt.Errorf("error loading --config: %v", err)
+	}
+	if len(config.Phases) == 0 && len(config.Profile) == 1 {
+		return fmt.Errorf("--config %s: a rate profile needs at least two points", path)
+	}
+	configSettings = fileSettings{
+		profile:      config.Profile,
+		phases:       config.Phases,
+		bodyTemplate: config.BodyTemplate,
+	}
+	values := configFlagValues(config)
+	if hasURLArg {
+		delete(values, "url")
+	}
+	if err := setUnchangedFlags(flags, values); err != nil {
+		return fmt.Errorf("--config %s: %v", path, err)
+	}
+	return nil
+}
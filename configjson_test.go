@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigJSONDurations(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(`{"url":"http://x","duration":"30s","timeout":5000000000}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Duration != 30*time.Second || config.Timeout != 5*time.Second {
+		t.Errorf("duration %v, timeout %v; want 30s and 5s", config.Duration, config.Timeout)
+	}
+
+	data, err := json.Marshal(Config{Timeout: 1500 * time.Millisecond, Cooldown: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"timeout":"1.5s"`) || !strings.Contains(string(data), `"cooldown":"1m0s"`) {
+		t.Errorf("durations not written as strings: %s", data)
+	}
+	if strings.Count(string(data), `"timeout"`) != 1 {
+		t.Errorf("timeout written twice: %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeout":"soon"}`), &config); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
+
+func TestConfigRoundTripsThroughResults(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Headers["X-Test"] = "yes"
+	config.MaxDuration = time.Minute
+	config.RetryBackoff = 100 * time.Millisecond
+	config.MethodMix = []WeightedMethod{{Method: "GET", Weight: 3}, {Method: "POST", Weight: 1}}
+	config.Seed = 42
+
+	tester := newTestLoadTester(t, config)
+	stats := tester.Run(nil)
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := tester.SaveResultsToJSON(path, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("loaded config\n%+v\nwant\n%+v", loaded, config)
+	}
+	if _, err := loadResultsFile(path); err != nil {
+		t.Errorf("report can't read the results back: %v", err)
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"url":"http://example.com","concurrent":4,"requests":500,"duration":"30s","timeout":"2s"}`), 0644)
+
+	cmd := newRunCmd()
+	if err := cmd.ParseFlags([]string{"-n", "50"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(cmd.Flags(), path, false); err != nil {
+		t.Fatal(err)
+	}
+	if targetURL != "http://example.com" || concurrent != 4 || timeout != 2*time.Second {
+		t.Errorf("config not applied: url %q, concurrent %d, timeout %v", targetURL, concurrent, timeout)
+	}
+	if requests != 50 {
+		t.Errorf("requests = %d, want the explicit 50", requests)
+	}
+	// The file's duration limits the run
+	if maxDuration != 30*time.Second {
+		t.Errorf("max-duration = %v, want the config's 30s duration", maxDuration)
+	}
+}
+
+func TestConfigDurationDrivesRun(t *testing.T) {
+	defer newRunCmd()
+	srv := newTestServer(t, 0)
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"url":"`+srv.URL+`","duration":"300ms"}`), 0644)
+
+	cmd := newRunCmd()
+	if err := applyConfigFile(cmd.Flags(), path, false); err != nil {
+		t.Fatal(err)
+	}
+	config, err := buildConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := newTestLoadTester(t, config).Run(nil)
+	// Without a request count the duration, not the default -n 100, ends the run
+	if stats.TotalRequests <= 100 || stats.TotalTime < 300*time.Millisecond {
+		t.Errorf("%d requests in %v, want more than 100 over the 300ms duration", stats.TotalRequests, stats.TotalTime)
+	}
+	if stats.PlannedRequests != 0 || stats.StoppedBy != "" || stats.SkippedRequests != 0 {
+		t.Errorf("planned %d, stopped by %q, %d skipped; want a run without a limit ending on time",
+			stats.PlannedRequests, stats.StoppedBy, stats.SkippedRequests)
+	}
+	if line := completionLine(stats, false); line != fmt.Sprintf("Completed: %d", stats.TotalRequests) {
+		t.Errorf("completion line %q", line)
+	}
+
+	cmd = newRunCmd()
+	if err := cmd.ParseFlags([]string{srv.URL, "-n", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildConfig(cmd.Flags().Args()); err == nil {
+		t.Error("-n 0 without a time limit was accepted")
+	}
+}
+
+// TestConfigFileReproducesFlags builds a config from flags, saves it and
+// builds it again from the saved file alone: every setting must survive
+func TestConfigFileReproducesFlags(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pin := "uUcPDtOLOcHAYw5oWNBh7ToEXgYMDrZu5LY8XqQKeL0="
+	common := []string{"https://example.com", "--seed", "7", "-c", "3", "-n", "20", "-t", "2s",
+		"--pin-sha256", pin, "--pin-sha256", "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		"--methods", "GET:3,POST:1", "--bearer-file", write("token", "secret"), "--bearer-refresh", "1m",
+		"--request-id-header", "X-Request-ID", "--request-id-header", "Idempotency-Key",
+		"--capture-headers", "Server,Via", "--track-header", "X-Cache", "--if-none-match", `"v1"`,
+		"--prewarm-conns", "--retries", "2", "--max-duration", "1m", "--chaos-abort", "1%"}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{"template and phases", []string{"--body-template", write("body.tmpl", `{"n":{{seq}}}`),
+			"--phases", write("phases", "warm 10s 5\npeak 20s 50 1s-2s\n")}},
+		{"random body and profile", []string{"--random-body-size", "1KB", "--random-body-each",
+			"--profile", write("profile", "0 10\n30s 100\n")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := newRunCmd()
+			if err := cmd.ParseFlags(append(common, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+			want, err := buildConfig(cmd.Flags().Args())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := write("config.json", string(data))
+
+			cmd = newRunCmd()
+			if err := applyConfigFile(cmd.Flags(), path, false); err != nil {
+				t.Fatal(err)
+			}
+			got, err := buildConfig(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("config from file\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+	newRunCmd()
+}
+
+func TestApplyConfigFileTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		file string
+		want time.Duration
+	}{
+		{`{"url":"http://example.com","timeout":"0s"}`, 0},
+		{`{"config":{"url":"http://example.com","timeout":0}}`, 0},
+		{`{"url":"http://example.com"}`, 30 * time.Second},
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte(tc.file), 0644)
+		cmd := newRunCmd()
+		if err := applyConfigFile(cmd.Flags(), path, false); err != nil {
+			t.Fatal(err)
+		}
+		if timeout != tc.want {
+			t.Errorf("%s: timeout = %v, want %v", tc.file, timeout, tc.want)
+		}
+	}
+}
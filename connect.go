@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// targetAddr returns host:port for the configured URL, filling in the scheme's default port
+func targetAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		if u.Scheme == "https" {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// makeConnection opens a TCP connection to the target, completes the TLS handshake
+// for https URLs, and closes it again without sending a request
+func (lt *LoadTester) makeConnection() Result {
+	start := time.Now()
+
+	u, err := url.Parse(lt.config.URL)
+	if err != nil {
+		return Result{Error: err, ResponseTime: time.Since(start), Timestamp: time.Now()}
+	}
+	if u.Hostname() == "" {
+		return Result{Error: fmt.Errorf("URL has no host: %s", lt.config.URL), ResponseTime: time.Since(start), Timestamp: time.Now()}
+	}
+
+	dialer := &net.Dialer{Timeout: lt.config.Timeout, Resolver: lt.dnsResolver}
+	conn, err := dialer.DialContext(lt.ctx, "tcp", targetAddr(u))
+	if err != nil {
+		return Result{Error: err, ResponseTime: time.Since(start), Timestamp: time.Now()}
+	}
+	defer conn.Close()
+
+	var handshakeTime time.Duration
+	var state tls.ConnectionState
+	if u.Scheme == "https" {
+		handshakeStart := time.Now()
+		tlsConn := tls.Client(conn, lt.connectTLS)
+		if lt.config.Timeout > 0 {
+			tlsConn.SetDeadline(time.Now().Add(lt.config.Timeout))
+		}
+		err := tlsConn.HandshakeContext(lt.ctx)
+		handshakeTime = time.Since(handshakeStart)
+		if err != nil {
+			return Result{Error: err, ResponseTime: time.Since(start), TLSHandshake: handshakeTime, Timestamp: time.Now()}
+		}
+		state = tlsConn.ConnectionState()
+	}
+
+	return Result{
+		ResponseTime:   time.Since(start),
+		TLSHandshake:   handshakeTime,
+		Timestamp:      time.Now(),
+		TLSVersion:     state.Version,
+		TLSCipherSuite: state.CipherSuite,
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// maxBodyVariants bounds the distinct response bodies tracked by
+// --check-consistency; responses with further variants are only counted
+const maxBodyVariants = 50
+
+// BodyVariant is one distinct response body, identified by its digest
+type BodyVariant struct {
+	Digest string `json:"digest"`
+	Count  int    `json:"count"`
+	Size   int64  `json:"size"`
+}
+
+// BodyConsistency reports the distinct bodies of successful responses; more
+// than one variant for identical requests usually means stale replicas
+type BodyConsistency struct {
+	Variants int           `json:"variants"`
+	Bodies   []BodyVariant `json:"bodies"`
+	// Omitted counts responses whose variant was beyond maxBodyVariants
+	Omitted int `json:"omitted,omitempty"`
+}
+
+// bodyDigests tallies response bodies by their 64-bit FNV-1a digest, which is
+// cheap and plenty to tell apart the handful of variants a target serves
+type bodyDigests struct {
+	mu       sync.Mutex
+	variants map[uint64]*BodyVariant
+	omitted  int
+}
+
+func newBodyDigests() *bodyDigests {
+	return &bodyDigests{variants: make(map[uint64]*BodyVariant)}
+}
+
+// digest returns a hash to stream one response body through before it is
+// passed to record
+func (d *bodyDigests) digest() hash.Hash64 {
+	// FNV-64a from the standard library rather than xxhash, to avoid a new dependency
+	return fnv.New64a()
+}
+
+func (d *bodyDigests) record(digest hash.Hash64, size int64) {
+	sum := digest.Sum64()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v := d.variants[sum]
+	if v == nil {
+		if len(d.variants) >= maxBodyVariants {
+			d.omitted++
+			return
+		}
+		v = &BodyVariant{Digest: fmt.Sprintf("%016x", sum), Size: size}
+		d.variants[sum] = v
+	}
+	v.Count++
+}
+
+// summary returns the variants, most frequent first
+func (d *bodyDigests) summary() *BodyConsistency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := &BodyConsistency{Variants: len(d.variants), Omitted: d.omitted}
+	for _, v := range d.variants {
+		c.Bodies = append(c.Bodies, *v)
+	}
+	sort.Slice(c.Bodies, func(a, b int) bool {
+		if c.Bodies[a].Count != c.Bodies[b].Count {
+			return c.Bodies[a].Count > c.Bodies[b].Count
+		}
+		return c.Bodies[a].Digest < c.Bodies[b].Digest
+	})
+	return c
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := hits.Add(1); {
+		case n%10 == 0:
+			w.Write([]byte("stale replica"))
+		case n%10 == 1:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("error page"))
+		default:
+			w.Write([]byte("fresh"))
+		}
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.CheckConsistency = true
+	bc := newTestLoadTester(t, config).Run(nil).BodyConsistency
+	if bc == nil || bc.Variants != 2 {
+		t.Fatalf("consistency = %+v, want 2 variants", bc)
+	}
+	if bc.Bodies[0].Count != 40 || bc.Bodies[0].Size != 5 || bc.Bodies[1].Count != 5 || bc.Bodies[1].Size != 13 {
+		t.Errorf("bodies = %+v, want 40 fresh and 5 stale, error pages left out", bc.Bodies)
+	}
+}
+
+func TestBodyDigestsAreBounded(t *testing.T) {
+	d := newBodyDigests()
+	for i := 0; i < maxBodyVariants+5; i++ {
+		digest := d.digest()
+		digest.Write([]byte{byte(i)})
+		d.record(digest, 1)
+	}
+	if bc := d.summary(); bc.Variants != maxBodyVariants || bc.Omitted != 5 {
+		t.Errorf("variants %d, omitted %d; want %d and 5", bc.Variants, bc.Omitted, maxBodyVariants)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// controlPollInterval is how often --control checks its file for changes
+const controlPollInterval = 500 * time.Millisecond
+
+// controlSettings are the fields of a --control file. A field left out keeps
+// its current value.
+type controlSettings struct {
+	Concurrency *int `json:"concurrency"`
+	// RPS caps the rate requests are sent at, on top of any --profile; 0 removes the cap
+	RPS *float64 `json:"rps"`
+}
+
+// readControlFile parses a --control file, rejecting unknown fields so a typo
+// doesn't go unnoticed in the middle of a run
+func readControlFile(path string) (controlSettings, error) {
+	var settings controlSettings
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		return settings, err
+	}
+	if settings.Concurrency != nil && *settings.Concurrency < 1 {
+		return settings, fmt.Errorf("concurrency must be at least 1, got %d", *settings.Concurrency)
+	}
+	if rps := settings.RPS; rps != nil && (*rps < 0 || math.IsInf(*rps, 0) || math.IsNaN(*rps)) {
+		return settings, fmt.Errorf("invalid rps %v", *rps)
+	}
+	return settings, nil
+}
+
+// applyControl applies the settings of a --control file to the running test
+func (lt *LoadTester) applyControl(settings controlSettings) {
+	if settings.Concurrency != nil {
+		lt.SetConcurrency(*settings.Concurrency)
+	}
+	if settings.RPS != nil {
+		lt.rateCap.set(*settings.RPS)
+	}
+}
+
+// startControlFile watches path and applies its settings whenever it changes,
+// starting with its current contents. The file may be created after the run
+// starts; an invalid one is reported and ignored until it changes again.
+// The returned function stops watching.
+func startControlFile(tester *LoadTester, path string) func() {
+	var modTime time.Time
+	var size int64
+	check := func() {
+		info, err := os.Stat(path)
+		if err != nil || (info.ModTime().Equal(modTime) && info.Size() == size) {
+			return
+		}
+		modTime, size = info.ModTime(), info.Size()
+
+		settings, err := readControlFile(path)
+		if err != nil {
+			logger.Warn("ignoring control file", "path", path, "error", err)
+			return
+		}
+		tester.applyControl(settings)
+		logger.Info("control file applied",
+			"path", path,
+			"concurrency", tester.Concurrency(),
+			"rps_cap", tester.rateCap.rate())
+	}
+	check()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(controlPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// rateCap spaces request starts to at most a rate set while the test runs
+// by --control. Its zero value doesn't limit anything.
+type rateCap struct {
+	mu       sync.Mutex
+	interval time.Duration // 0 when there is no cap
+	next     time.Time     // earliest start of the next request
+}
+
+// set caps the rate at rps requests per second, or removes the cap for 0
+func (c *rateCap) set(rps float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rps <= 0 {
+		c.interval = 0
+		return
+	}
+	c.interval = time.Duration(float64(time.Second) / rps)
+}
+
+// rate returns the cap in requests per second, 0 when there is none
+func (c *rateCap) rate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.interval == 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(c.interval)
+}
+
+// wait blocks until the next request may start under the cap. It returns
+// false if cancel is closed first.
+func (c *rateCap) wait(cancel <-chan struct{}) bool {
+	c.mu.Lock()
+	if c.interval == 0 {
+		c.mu.Unlock()
+		return true
+	}
+	// Like the profile, the time spent below the cap isn't banked as a burst
+	start := time.Now()
+	if c.next.After(start) {
+		start = c.next
+	}
+	c.next = start.Add(c.interval)
+	c.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// reset forgets the reserved start before the tester runs again; the cap stays
+func (c *rateCap) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next = time.Time{}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadControlFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		wantErr bool
+	}{
+		{`{"concurrency": 20, "rps": 150.5}`, false},
+		{`{"rps": 0}`, false},
+		{`{"concurrency": 0}`, true},
+		{`{"rps": -1}`, true},
+		{`{"concurency": 20}`, true},
+		{`{"concurrency": 20`, true},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, "control.json")
+		os.WriteFile(path, []byte(tt.content), 0644)
+		_, err := readControlFile(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%d: %s: err = %v, wantErr %v", i, tt.content, err, tt.wantErr)
+		}
+	}
+
+	settings, _ := readControlFile(filepath.Join(dir, "control.json"))
+	if settings.Concurrency != nil || settings.RPS != nil {
+		t.Errorf("a failed read returned settings %+v", settings)
+	}
+}
+
+func TestRateCapSpacesRequests(t *testing.T) {
+	var c rateCap
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		c.wait(nil)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("an unset cap held requests back for %v", elapsed)
+	}
+
+	c.set(100) // 10ms apart
+	if c.rate() != 100 {
+		t.Errorf("rate = %v, want 100", c.rate())
+	}
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		c.wait(nil)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > 80*time.Millisecond {
+		t.Errorf("5 requests took %v, want about 40ms", elapsed)
+	}
+
+	c.set(0)
+	if c.rate() != 0 || !c.wait(nil) {
+		t.Error("set(0) didn't remove the cap")
+	}
+}
+
+func TestControlFileAppliesChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.json")
+	os.WriteFile(path, []byte(`{"concurrency": 8}`), 0644)
+
+	tester := newTestLoadTester(t, testConfig("http://example.com"))
+	stop := startControlFile(tester, path)
+	defer stop()
+	if tester.Concurrency() != 8 {
+		t.Fatalf("concurrency = %d, want 8 from the initial file", tester.Concurrency())
+	}
+
+	// An invalid file is ignored, then the next valid one applies
+	os.WriteFile(path, []byte(`{"concurrency": -3}`), 0644)
+	time.Sleep(2 * controlPollInterval)
+	os.WriteFile(path, []byte(`{"rps": 250}`), 0644)
+	deadline := time.Now().Add(5 * controlPollInterval)
+	for tester.TargetRPS() != 250 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if tester.TargetRPS() != 250 || tester.Concurrency() != 8 {
+		t.Errorf("rps cap %v, concurrency %d; want 250 and the unchanged 8", tester.TargetRPS(), tester.Concurrency())
+	}
+}
+
+func TestRunFollowsControlRateCap(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 20
+	tester := newTestLoadTester(t, config)
+	tester.rateCap.set(200)
+
+	start := time.Now()
+	stats := tester.Run(nil)
+	// 20 requests 5ms apart take about 95ms
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("run took %v, faster than the cap allows", elapsed)
+	}
+	if stats.TotalRequests != 20 {
+		t.Errorf("completed %d requests, want 20", stats.TotalRequests)
+	}
+}
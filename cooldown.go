@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+)
+
+// CooldownStats summarises the requests that completed during the --cooldown
+// window at the end of the run, which are left out of the main statistics
+type CooldownStats struct {
+	Duration        time.Duration         `json:"duration"`
+	Requests        int                   `json:"requests"`
+	Failed          int                   `json:"failed"`
+	MaxResponseTime time.Duration         `json:"max_response_time"`
+	Percentiles     map[int]time.Duration `json:"percentiles,omitempty"`
+	// Stopped is true when dispatching stopped for the cooldown before all
+	// planned requests were sent
+	Stopped bool `json:"stopped"`
+}
+
+// buildCooldownStats summarises the results set aside during the cooldown
+func buildCooldownStats(results []Result, cooldown time.Duration, success func(Result) bool) *CooldownStats {
+	cs := &CooldownStats{Duration: cooldown, Requests: len(results)}
+	times := make([]time.Duration, 0, len(results))
+	for _, result := range results {
+		if !success(result) {
+			cs.Failed++
+		}
+		cs.MaxResponseTime = max(cs.MaxResponseTime, result.ResponseTime)
+		times = append(times, result.ResponseTime)
+	}
+	cs.Percentiles = percentilesOf(times)
+	return cs
+}
+
+// cooldownStart returns when dispatching stops for the cooldown of a run
+// started at start, or the zero time without a cooldown
+func (lt *LoadTester) cooldownStart(start time.Time) time.Time {
+	if lt.config.Cooldown <= 0 {
+		return time.Time{}
+	}
+	return start.Add(lt.runLimit() - lt.config.Cooldown)
+}
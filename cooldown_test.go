@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCooldownExcludesTail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 100000
+	config.Concurrent = 2
+	config.MaxDuration = 400 * time.Millisecond
+	config.Cooldown = 200 * time.Millisecond
+	stats := newTestLoadTester(t, config).Run(nil)
+
+	if stats.Cooldown == nil || !stats.Cooldown.Stopped {
+		t.Fatalf("cooldown = %+v, want dispatching stopped", stats.Cooldown)
+	}
+	if stats.MaxDurationReached {
+		t.Error("in-flight requests were cut off by --max-duration despite the cooldown")
+	}
+	// Requests started just before the cooldown finish inside it
+	if stats.Cooldown.Requests == 0 || stats.Cooldown.Requests > config.Concurrent {
+		t.Errorf("cooldown requests = %d, want 1..%d", stats.Cooldown.Requests, config.Concurrent)
+	}
+	if stats.TotalTime > 210*time.Millisecond {
+		t.Errorf("TotalTime = %v, want the span before the cooldown", stats.TotalTime)
+	}
+	if stats.stopReason() != "--cooldown" {
+		t.Errorf("stopReason = %q", stats.stopReason())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// parseDNSServer normalizes a --dns-server address, defaulting to port 53
+func parseDNSServer(s string) (string, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		// No port given; a bare IPv6 address still fails SplitHostPort
+		host, port = s, "53"
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("%q is not an IP address", host)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// newDNSResolver returns a resolver that sends every query to server instead
+// of the system's configured name servers
+func newDNSResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// resolver returns the resolver used to look up the target, the system one
+// unless --dns-server is set
+func (lt *LoadTester) resolver() *net.Resolver {
+	if lt.dnsResolver != nil {
+		return lt.dnsResolver
+	}
+	return net.DefaultResolver
+}
+
+// dialer returns a dialer for connections to the target that resolves with
+// the configured resolver
+func (lt *LoadTester) dialer() *net.Dialer {
+	return &net.Dialer{Timeout: lt.config.Timeout, KeepAlive: 30 * time.Second, Resolver: lt.dnsResolver}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseDNSServer(t *testing.T) {
+	cases := map[string]string{
+		"8.8.8.8":          "8.8.8.8:53",
+		"8.8.8.8:5353":     "8.8.8.8:5353",
+		"::1":              "[::1]:53",
+		"[2001:db8::1]:53": "[2001:db8::1]:53",
+	}
+	for in, want := range cases {
+		if got, err := parseDNSServer(in); err != nil || got != want {
+			t.Errorf("parseDNSServer(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"dns.google", "8.8.8.8:0", "8.8.8.8:x", ""} {
+		if _, err := parseDNSServer(bad); err == nil {
+			t.Errorf("parseDNSServer(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestDNSResolverUsesServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	queried := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 512)
+		if _, _, err := pc.ReadFrom(buf); err == nil {
+			queried <- struct{}{}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	// The server never answers, so the lookup fails; only the query matters
+	newDNSResolver(pc.LocalAddr().String()).LookupHost(ctx, "brutal.invalid")
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Fatal("the custom name server received no query")
+	}
+}
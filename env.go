@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envReference matches ${VAR} and ${VAR:-default}. A bare $VAR is left alone so
+// request bodies containing dollar signs aren't mangled.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// secretNameParts mark variables whose values are redacted from printed config
+var secretNameParts = []string{"TOKEN", "SECRET", "PASSWORD", "KEY"}
+
+// envExpander expands environment variable references, remembering the
+// variables that were missing and the values that came from secret variables
+type envExpander struct {
+	missing map[string]bool
+	secrets map[string]bool
+}
+
+func newEnvExpander() *envExpander {
+	return &envExpander{missing: make(map[string]bool), secrets: make(map[string]bool)}
+}
+
+func (e *envExpander) expand(s string) string {
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envReference.FindStringSubmatch(ref)
+		name, hasDefault := m[1], strings.Contains(ref, ":-")
+		value, ok := os.LookupEnv(name)
+		if !ok || (value == "" && hasDefault) {
+			if !hasDefault {
+				e.missing[name] = true
+				return ref
+			}
+			return m[2]
+		}
+		if isSecretName(name) && value != "" {
+			e.secrets[value] = true
+		}
+		return value
+	})
+}
+
+// err reports the referenced variables that are unset and have no default
+func (e *envExpander) err() error {
+	if len(e.missing) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(e.missing))
+	for name := range e.missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("environment variables not set: %s (use ${VAR:-default} for optional ones)", strings.Join(names, ", "))
+}
+
+// secretValues returns the values that came from secret variables
+func (e *envExpander) secretValues() []string {
+	values := make([]string, 0, len(e.secrets))
+	for value := range e.secrets {
+		values = append(values, value)
+	}
+	return values
+}
+
+func isSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets replaces every secret value in s
+func redactSecrets(s string, secrets []string) string {
+	// Longest first so a secret containing another is replaced whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+	return s
+}
+
+// redactedConfigJSON renders config as JSON with its secrets redacted, also
+// where JSON escaping changed how a secret is written
+func redactedConfigJSON(config Config) (json.RawMessage, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]string, 0, 2*len(config.secrets))
+	for _, secret := range config.secrets {
+		escaped, _ := json.Marshal(secret)
+		secrets = append(secrets, secret, string(escaped[1:len(escaped)-1]))
+	}
+	return json.RawMessage(redactSecrets(string(data), secrets)), nil
+}
+
+// expandConfigEnv expands environment variable references in the string
+// fields of config that come from flags, a raw request or a body template
+func expandConfigEnv(config *Config) error {
+	e := newEnvExpander()
+	config.URL = e.expand(config.URL)
+	config.Host = e.expand(config.Host)
+	config.ProxyURL = e.expand(config.ProxyURL)
+	config.Body = e.expand(config.Body)
+	config.BodyTemplate = e.expand(config.BodyTemplate)
+	for key, value := range config.Headers {
+		config.Headers[key] = e.expand(value)
+	}
+	if err := e.err(); err != nil {
+		return err
+	}
+	config.secrets = e.secretValues()
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandConfigEnv(t *testing.T) {
+	t.Setenv("BRUTAL_TEST_HOST", "api.local")
+	t.Setenv("BRUTAL_TEST_TOKEN", "s3cr3t")
+	t.Setenv("BRUTAL_TEST_EMPTY", "")
+
+	config := Config{
+		URL:     "http://${BRUTAL_TEST_HOST}:${BRUTAL_TEST_PORT:-8080}/",
+		Headers: map[string]string{"Authorization": "Bearer ${BRUTAL_TEST_TOKEN}"},
+		Body:    `{"price": "$5", "tag": "${BRUTAL_TEST_EMPTY:-none}"}`,
+	}
+	if err := expandConfigEnv(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.URL != "http://api.local:8080/" {
+		t.Errorf("URL = %q", config.URL)
+	}
+	if got := config.Headers["Authorization"]; got != "Bearer s3cr3t" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if config.Body != `{"price": "$5", "tag": "none"}` {
+		t.Errorf("Body = %q", config.Body)
+	}
+	if len(config.secrets) != 1 || config.secrets[0] != "s3cr3t" {
+		t.Errorf("secrets = %v, want [s3cr3t]", config.secrets)
+	}
+	if got := redactSecrets(config.Headers["Authorization"], config.secrets); got != "Bearer [REDACTED]" {
+		t.Errorf("redacted = %q", got)
+	}
+}
+
+func TestExpandConfigEnvMissing(t *testing.T) {
+	config := Config{
+		URL:     "http://${BRUTAL_TEST_UNSET_B}/",
+		Headers: map[string]string{"X-Key": "${BRUTAL_TEST_UNSET_A}", "X-Opt": "${BRUTAL_TEST_UNSET_C:-}"},
+	}
+	err := expandConfigEnv(&config)
+	if err == nil {
+		t.Fatal("expected an error for unset variables")
+	}
+	if !strings.Contains(err.Error(), "BRUTAL_TEST_UNSET_A, BRUTAL_TEST_UNSET_B") || strings.Contains(err.Error(), "UNSET_C") {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestResultsRedactSecrets(t *testing.T) {
+	t.Setenv("BRUTAL_TEST_TOKEN", "s3cr3t<&>")
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Headers["Authorization"] = "Bearer ${BRUTAL_TEST_TOKEN}"
+	if err := expandConfigEnv(&config); err != nil {
+		t.Fatal(err)
+	}
+	tester := newTestLoadTester(t, config)
+	stats := tester.Run(nil)
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := tester.SaveResultsToJSON(path, stats); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary bytes.Buffer
+	if err := tester.WriteJSON(&summary, stats); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"results file": string(saved), "JSON summary": summary.String()} {
+		if strings.Contains(data, "s3cr3t") {
+			t.Errorf("%s leaks the secret:\n%s", name, data)
+		}
+		if !strings.Contains(data, `"Authorization": "Bearer [REDACTED]"`) {
+			t.Errorf("%s has no redacted Authorization header:\n%s", name, data)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// maxDistinctErrors bounds how many distinct error messages are tracked; further
+// messages are only counted so a run with unique errors can't grow memory unbounded
+const maxDistinctErrors = 300
+
+// ErrorGroup is one distinct error message and how often it occurred
+type ErrorGroup struct {
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// errorGroups collects error messages grouped by their text
+type errorGroups struct {
+	groups map[string]*ErrorGroup
+	// omitted counts occurrences of messages beyond maxDistinctErrors
+	omitted int
+}
+
+func newErrorGroups() *errorGroups {
+	return &errorGroups{groups: make(map[string]*ErrorGroup)}
+}
+
+func (g *errorGroups) add(message string, at time.Time) {
+	if group, ok := g.groups[message]; ok {
+		group.Count++
+		if at.After(group.LastSeen) {
+			group.LastSeen = at
+		}
+		return
+	}
+
+	if len(g.groups) >= maxDistinctErrors {
+		g.omitted++
+		return
+	}
+	g.groups[message] = &ErrorGroup{Message: message, Count: 1, FirstSeen: at, LastSeen: at}
+}
+
+// sorted returns the groups ordered by descending count, then by message
+func (g *errorGroups) sorted() []ErrorGroup {
+	list := make([]ErrorGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		list = append(list, *group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Message < list[j].Message
+	})
+	return list
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorGroupsCapAndOrder(t *testing.T) {
+	g := newErrorGroups()
+	start := time.Now()
+	for i := 0; i < maxDistinctErrors+50; i++ {
+		g.add(fmt.Sprintf("error %d", i), start)
+	}
+	for i := 0; i < 3; i++ {
+		g.add("error 7", start.Add(time.Duration(i+1)*time.Second))
+	}
+
+	list := g.sorted()
+	if len(list) != maxDistinctErrors {
+		t.Fatalf("tracked %d messages, want %d", len(list), maxDistinctErrors)
+	}
+	if g.omitted != 50 {
+		t.Errorf("omitted = %d, want 50", g.omitted)
+	}
+	if list[0].Message != "error 7" || list[0].Count != 4 {
+		t.Errorf("most frequent = %+v, want error 7 x4", list[0])
+	}
+	if !list[0].LastSeen.Equal(start.Add(3*time.Second)) || !list[0].FirstSeen.Equal(start) {
+		t.Errorf("first/last seen = %v/%v", list[0].FirstSeen, list[0].LastSeen)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// formContentType is the Content-Type of bodies built from --form fields
+const formContentType = "application/x-www-form-urlencoded"
+
+// encodeForm builds an application/x-www-form-urlencoded body from key=value
+// fields, escaping keys and values and keeping the order they were given in.
+// Each of fields may hold several pairs separated by "&".
+func encodeForm(fields []string) (string, error) {
+	var pairs []string
+	for _, field := range fields {
+		for _, pair := range strings.Split(field, "&") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || key == "" {
+				return "", fmt.Errorf("invalid form field %q, want key=value", pair)
+			}
+			pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&"), nil
+}
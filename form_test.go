@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestEncodeForm(t *testing.T) {
+	got, err := encodeForm([]string{"q=a+b&path=/x y", "name=Zoë", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "q=a%2Bb&path=%2Fx+y&name=Zo%C3%AB&empty="; got != want {
+		t.Errorf("encodeForm = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"novalue", "=value", "a=1&"} {
+		if _, err := encodeForm([]string{bad}); err == nil {
+			t.Errorf("encodeForm(%q) succeeded", bad)
+		}
+	}
+}
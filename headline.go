@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Headline is the gist of a run, all that --summary-only reports
+type Headline struct {
+	Requests   int `json:"requests"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+	// SuccessRate is the percentage of requests that succeeded, 0 without requests
+	SuccessRate float64      `json:"success_rate"`
+	RPS         float64      `json:"rps"`
+	Duration    jsonDuration `json:"duration"`
+	StoppedBy   string       `json:"stopped_by,omitempty"`
+}
+
+func newHeadline(stats *Stats) Headline {
+	s := Headline{
+		Requests:   stats.TotalRequests,
+		Successful: stats.SuccessfulReqs,
+		Failed:     stats.FailedReqs,
+		RPS:        stats.RequestsPerSec,
+		Duration:   jsonDuration(stats.TotalTime.Round(time.Millisecond)),
+		StoppedBy:  stats.stopReason(),
+	}
+	if s.Requests > 0 {
+		s.SuccessRate = float64(s.Successful) / float64(s.Requests) * 100
+	}
+	return s
+}
+
+// String renders the headline as one line for the console
+func (s Headline) String() string {
+	return fmt.Sprintf("Requests: %d | Successful: %d (%.1f%%) | Failed: %d | Requests/sec: %.2f | Time: %v",
+		s.Requests, s.Successful, s.SuccessRate, s.Failed, s.RPS, time.Duration(s.Duration))
+}
+
+// keyValues renders the headline as one line of key=value pairs, like summaryLine
+func (s Headline) keyValues() string {
+	return fmt.Sprintf("requests=%d successful=%d failed=%d success_rate=%.1f%% rps=%.2f duration=%v",
+		s.Requests, s.Successful, s.Failed, s.SuccessRate, s.RPS, time.Duration(s.Duration))
+}
+
+// writeHeadlineJSON writes the headline with the run metadata, or with --quiet
+// the headline alone on a single line
+func (lt *LoadTester) writeHeadlineJSON(w io.Writer, stats *Stats, terse bool) error {
+	encoder := json.NewEncoder(w)
+	if terse {
+		return encoder.Encode(newHeadline(stats))
+	}
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"metadata": lt.metadata(),
+		"summary":  newHeadline(stats),
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeadline(t *testing.T) {
+	stats := &Stats{TotalRequests: 200, SuccessfulReqs: 196, FailedReqs: 4,
+		TotalTime: 2*time.Second + 345678*time.Microsecond, RequestsPerSec: 85.27, PlannedRequests: 1000, MaxDurationReached: true}
+	h := newHeadline(stats)
+
+	if want := "Requests: 200 | Successful: 196 (98.0%) | Failed: 4 | Requests/sec: 85.27 | Time: 2.346s"; h.String() != want {
+		t.Errorf("String() = %q, want %q", h.String(), want)
+	}
+	if want := "requests=200 successful=196 failed=4 success_rate=98.0% rps=85.27 duration=2.346s"; h.keyValues() != want {
+		t.Errorf("keyValues() = %q, want %q", h.keyValues(), want)
+	}
+	if h.StoppedBy != "--max-duration" {
+		t.Errorf("stopped by %q", h.StoppedBy)
+	}
+	if newHeadline(&Stats{}).SuccessRate != 0 {
+		t.Error("success rate without requests, want 0")
+	}
+}
+
+func TestWriteHeadlineJSON(t *testing.T) {
+	srv := newTestServer(t, 0)
+	tester := newTestLoadTester(t, testConfig(srv.URL))
+	stats := tester.Run(nil)
+
+	var terse bytes.Buffer
+	if err := tester.writeHeadlineJSON(&terse, stats, true); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(terse.String(), "\n") != 1 {
+		t.Errorf("terse JSON spans several lines:\n%s", terse.String())
+	}
+	var h Headline
+	if err := json.Unmarshal(terse.Bytes(), &h); err != nil || h.Requests != 50 || h.SuccessRate != 100 {
+		t.Errorf("decoded %+v, %v; want 50 requests at 100%%", h, err)
+	}
+
+	var full bytes.Buffer
+	tester.writeHeadlineJSON(&full, stats, false)
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(full.Bytes(), &doc); err != nil || doc["metadata"] == nil || doc["summary"] == nil || doc["stats"] != nil {
+		t.Errorf("want metadata and summary only, got %s", full.String())
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// histogramMin is the lower bound of the first bucket; anything faster lands in bucket 0
+	histogramMin = time.Microsecond
+	// histogramGrowth is the ratio between consecutive bucket bounds, giving ~2.5% precision
+	histogramGrowth = 1.05
+	// histogramBuckets covers 1µs to well beyond an hour
+	histogramBuckets = 460
+)
+
+var histogramLogGrowth = math.Log(histogramGrowth)
+
+// latencyHistogram is a fixed-size log-bucketed histogram. Recording and
+// reading a quantile are O(buckets) at most, independent of the sample count,
+// so it can be queried on every progress tick without sorting.
+type latencyHistogram struct {
+	counts [histogramBuckets]uint64
+	total  uint64
+}
+
+func histogramBucket(d time.Duration) int {
+	if d <= histogramMin {
+		return 0
+	}
+	i := int(math.Log(float64(d)/float64(histogramMin))/histogramLogGrowth) + 1
+	if i >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return i
+}
+
+// histogramBound returns the upper bound of bucket i
+func histogramBound(i int) time.Duration {
+	return time.Duration(float64(histogramMin) * math.Pow(histogramGrowth, float64(i)))
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[histogramBucket(d)]++
+	h.total++
+}
+
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+}
+
+// subtract removes the samples of other, which must have been merged in before
+func (h *latencyHistogram) subtract(other *latencyHistogram) {
+	for i, c := range other.counts {
+		h.counts[i] -= c
+	}
+	h.total -= other.total
+}
+
+func (h *latencyHistogram) reset() {
+	*h = latencyHistogram{}
+}
+
+// quantile returns the upper bound of the bucket holding the p-th percentile,
+// using the same nearest-rank definition as percentile
+func (h *latencyHistogram) quantile(p int) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(float64(p) / 100 * float64(h.total)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return histogramBound(i)
+		}
+	}
+	return histogramBound(histogramBuckets - 1)
+}
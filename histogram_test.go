@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHistogramQuantileMatchesExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var h latencyHistogram
+	samples := make([]time.Duration, 10000)
+	for i := range samples {
+		samples[i] = time.Duration(rng.ExpFloat64() * float64(20*time.Millisecond))
+		h.record(samples[i])
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	for _, p := range reportedPercentiles {
+		exact := percentile(samples, p)
+		got := h.quantile(p)
+		if got < exact || float64(got) > float64(exact)*histogramGrowth {
+			t.Errorf("p%d = %v, exact %v: outside one bucket", p, got, exact)
+		}
+	}
+}
+
+func TestHistogramEmptyAndMerge(t *testing.T) {
+	var a, b latencyHistogram
+	if a.quantile(50) != 0 {
+		t.Error("empty histogram should report 0")
+	}
+
+	a.record(time.Millisecond)
+	b.record(time.Second)
+	a.merge(&b)
+	if a.total != 2 {
+		t.Errorf("total = %d, want 2", a.total)
+	}
+	if got := a.quantile(99); got < time.Second {
+		t.Errorf("p99 = %v, want >= 1s", got)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// HostStats summarises the requests answered by one host
+type HostStats struct {
+	Requests int           `json:"requests"`
+	Failed   int           `json:"failed"`
+	P95      time.Duration `json:"p95"`
+	Bytes    int64         `json:"bytes"`
+}
+
+// errorHost returns the host of the request a transport error happened on,
+// which after a redirect isn't the target's, or "" when the error doesn't say
+func errorHost(err error) string {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return ""
+	}
+	u, perr := url.Parse(urlErr.URL)
+	if perr != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// buildHostStats breaks the results down by the host that answered them.
+// It returns nil when every request was answered by target, the host of the
+// configured URL, as there is nothing to break down then.
+func buildHostStats(results []Result, target string, success func(Result) bool) map[string]HostStats {
+	hosts := make(map[string]HostStats)
+	times := make(map[string][]time.Duration)
+	for _, result := range results {
+		host := result.Host
+		if host == "" {
+			host = target
+		}
+		h := hosts[host]
+		h.Requests++
+		if !success(result) {
+			h.Failed++
+		}
+		h.Bytes += result.ContentSize
+		hosts[host] = h
+		times[host] = append(times[host], result.ResponseTime)
+	}
+	if _, ok := hosts[target]; len(hosts) == 0 || (len(hosts) == 1 && ok) {
+		return nil
+	}
+
+	for host, durations := range times {
+		h := hosts[host]
+		h.P95 = percentilesOf(durations)[95]
+		hosts[host] = h
+	}
+	return hosts
+}
+
+// targetHost returns the host of the configured URL, "" if it doesn't parse
+func targetHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildHostStats(t *testing.T) {
+	success := func(r Result) bool { return r.StatusCode == 200 }
+	results := []Result{
+		{Host: "origin", StatusCode: 200, ContentSize: 10, ResponseTime: time.Millisecond},
+		{Host: "origin", StatusCode: 500, ContentSize: 5, ResponseTime: 3 * time.Millisecond},
+		// A request without a known host is counted against the target
+		{StatusCode: 200, ResponseTime: 2 * time.Millisecond},
+	}
+	hosts := buildHostStats(results, "target", success)
+	if o := hosts["origin"]; o.Requests != 2 || o.Failed != 1 || o.Bytes != 15 || o.P95 != 3*time.Millisecond {
+		t.Errorf("origin = %+v", o)
+	}
+	if hosts["target"].Requests != 1 {
+		t.Errorf("target = %+v, want 1 request", hosts["target"])
+	}
+
+	if buildHostStats(results[2:], "target", success) != nil {
+		t.Error("want nil when every request went to the target")
+	}
+}
+
+func TestRunBreaksDownRedirectedHosts(t *testing.T) {
+	origin := newTestServer(t, 0)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, origin.URL, http.StatusMovedPermanently)
+	}))
+	t.Cleanup(redirector.Close)
+
+	stats := newTestLoadTester(t, testConfig(redirector.URL)).Run(nil)
+	originHost := strings.TrimPrefix(origin.URL, "http://")
+	if len(stats.Hosts) != 1 || stats.Hosts[originHost].Requests != 50 {
+		t.Errorf("hosts = %+v, want all 50 requests on %s", stats.Hosts, originHost)
+	}
+	if stats.TargetHost != strings.TrimPrefix(redirector.URL, "http://") {
+		t.Errorf("target host = %q", stats.TargetHost)
+	}
+
+	if stats := newTestLoadTester(t, testConfig(origin.URL)).Run(nil); stats.Hosts != nil {
+		t.Errorf("hosts = %+v without redirects, want nil", stats.Hosts)
+	}
+}
+
+func TestErrorHost(t *testing.T) {
+	_, err := http.Get("http://127.0.0.1:1/path")
+	if host := errorHost(err); host != "127.0.0.1:1" {
+		t.Errorf("errorHost = %q, want 127.0.0.1:1", host)
+	}
+	if host := errorHost(nil); host != "" {
+		t.Errorf("errorHost(nil) = %q", host)
+	}
+}
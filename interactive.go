@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// concurrencyStep is the relative change applied by each +/- key press
+const concurrencyStep = 0.10
+
+// startKeyboardControl puts the terminal into raw mode and adjusts the running
+// test's concurrency from key presses: + or ] raises it by 10%, - or [ lowers it,
+// space pauses or resumes issuing new requests, d shows or hides each
+// request's outcome as it completes, and e lists the distinct errors so far.
+// Raw mode swallows Ctrl+C, so it calls interrupt itself, ending the run as
+// SIGINT would; a second Ctrl+C restores the terminal and exits.
+// The returned function restores the terminal; it is a no-op when stdin isn't a terminal.
+func startKeyboardControl(tester *LoadTester, interrupt func()) (func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}, nil
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	restore := func() { term.Restore(fd, state) }
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				return
+			}
+			switch buf[0] {
+			case '+', '=', ']':
+				tester.scaleConcurrency(1 + concurrencyStep)
+			case '-', '_', '[':
+				tester.scaleConcurrency(1 - concurrencyStep)
+			case ' ':
+				tester.TogglePause()
+			case 'd', 'D':
+				tester.ToggleDetail()
+			case 'e', 'E':
+				tester.ShowErrors()
+			case 3: // Ctrl+C
+				if !tester.interrupted() {
+					interrupt()
+					continue
+				}
+				restore()
+				fmt.Println()
+				os.Exit(130)
+			}
+		}
+	}()
+
+	return restore, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// watchInterrupts makes the first SIGINT end tester's runs early, like
+// --max-duration, so the summary, --output and the rest of the reporting
+// still happen. A second one kills the process as usual. The returned
+// interrupt does the same for a Ctrl+C read from a raw terminal, and stop
+// stops listening.
+func watchInterrupts(tester *LoadTester) (interrupt func(), stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tester.interrupt = ctx
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		// From now on an interrupt has its default effect
+		signal.Stop(signals)
+	}()
+	return cancel, cancel
+}
+
+// interrupted reports whether an interrupt ended the runs
+func (lt *LoadTester) interrupted() bool {
+	return lt.interrupt != nil && lt.interrupt.Err() != nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInterruptEndsRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name string
+		send func(interrupt func())
+	}{
+		{"Ctrl+C", func(interrupt func()) { interrupt() }},
+		{"SIGINT", func(func()) {
+			p, _ := os.FindProcess(os.Getpid())
+			p.Signal(os.Interrupt)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := testConfig(srv.URL)
+			config.Requests = 1000
+			config.MaxDuration = time.Minute
+			tester := newTestLoadTester(t, config)
+			interrupt, stop := watchInterrupts(tester)
+			defer stop()
+			time.AfterFunc(200*time.Millisecond, func() { tc.send(interrupt) })
+
+			start := time.Now()
+			stats := tester.Run(nil)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("run took %v despite the interrupt", elapsed)
+			}
+			if !stats.Interrupted || stats.MaxDurationReached || stats.StoppedBy != "an interrupt" {
+				t.Errorf("interrupted %v, max duration reached %v, stopped by %q",
+					stats.Interrupted, stats.MaxDurationReached, stats.StoppedBy)
+			}
+			if stats.TotalRequests == 0 || stats.FailedReqs != 0 {
+				t.Errorf("%d requests with %d failed, want those completed before the interrupt and none failed",
+					stats.TotalRequests, stats.FailedReqs)
+			}
+		})
+	}
+}
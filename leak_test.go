@@ -0,0 +1,57 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkNoLeaks fails t when more goroutines are running than before, giving
+// connections a moment to wind down on both ends
+func checkNoLeaks(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("%d goroutines left running, %d before:\n%s", runtime.NumGoroutine(), before, buf[:n])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunLeavesNoGoroutines(t *testing.T) {
+	srv := newTestServer(t, 3)
+
+	tests := map[string]func(*Config){
+		"requests": func(c *Config) {},
+		"profile": func(c *Config) {
+			c.Profile = []RatePoint{{At: 0, RPS: 200}, {At: 200 * time.Millisecond, RPS: 200}}
+		},
+		"drain and cooldown": func(c *Config) {
+			c.Requests = 1 << 20
+			c.MaxDuration = 300 * time.Millisecond
+			c.Cooldown = 100 * time.Millisecond
+			c.DrainTimeout = time.Second
+		},
+		"retries": func(c *Config) {
+			c.Retries = 1
+			c.RetryBackoff = time.Millisecond
+			c.RetryMaxBackoff = time.Millisecond
+		},
+	}
+	for name, configure := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+			config := testConfig(srv.URL)
+			configure(&config)
+			tester := newTestLoadTester(t, config)
+			tester.Run(nil)
+			tester.Reset()
+			tester.Run(nil)
+			tester.Close()
+			checkNoLeaks(t, before)
+		})
+	}
+}
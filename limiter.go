@@ -0,0 +1,170 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// concurrencyLimiter bounds the number of requests in flight. Unlike a buffered
+// channel semaphore its limit can be changed while the test is running.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	inFlight int
+	// freeSlots are released slot ids to hand out again; nextSlot is the
+	// first id never handed out
+	freeSlots []int
+	nextSlot  int
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	l := &concurrencyLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a slot is free and returns its id. Ids are reused once
+// released, so they stay below the peak concurrency and identify a worker.
+func (l *concurrencyLimiter) acquire() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	if n := len(l.freeSlots); n > 0 {
+		slot := l.freeSlots[n-1]
+		l.freeSlots = l.freeSlots[:n-1]
+		return slot
+	}
+	l.nextSlot++
+	return l.nextSlot
+}
+
+func (l *concurrencyLimiter) release(slot int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.freeSlots = append(l.freeSlots, slot)
+	l.cond.Broadcast()
+}
+
+// setLimit changes the limit. Lowering it doesn't interrupt requests already
+// in flight; new ones wait until enough of them have finished.
+func (l *concurrencyLimiter) setLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+func (l *concurrencyLimiter) getLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// ConcurrencyChange records a live adjustment of the concurrency limit
+type ConcurrencyChange struct {
+	At      time.Time     `json:"at"`
+	Elapsed time.Duration `json:"elapsed"`
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+}
+
+// SetConcurrency changes the number of concurrent requests of the running test
+// and records the change in the results
+func (lt *LoadTester) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	from := lt.limiter.getLimit()
+	if from == n {
+		return
+	}
+	lt.limiter.setLimit(n)
+	now := time.Now()
+	var elapsed time.Duration // a change made before the run starts is at 0
+	if !lt.startTime.IsZero() {
+		elapsed = now.Sub(lt.startTime)
+	}
+	lt.concurrencyChanges = append(lt.concurrencyChanges, ConcurrencyChange{
+		At:      now,
+		Elapsed: elapsed,
+		From:    from,
+		To:      n,
+	})
+}
+
+// Concurrency returns the current concurrency limit
+func (lt *LoadTester) Concurrency() int {
+	return lt.limiter.getLimit()
+}
+
+// scaleConcurrency adjusts the concurrency limit by a factor, moving by at least one
+func (lt *LoadTester) scaleConcurrency(factor float64) {
+	current := lt.Concurrency()
+	next := int(float64(current) * factor)
+	if next == current {
+		if factor > 1 {
+			next++
+		} else {
+			next--
+		}
+	}
+	lt.SetConcurrency(next)
+}
+
+// inFlightGauge measures how many requests are actually in flight, which can
+// stay below the limit when the loop can't start requests fast enough
+type inFlightGauge struct {
+	current atomic.Int64
+	peak    atomic.Int64
+	busy    atomic.Int64 // total nanoseconds spent in flight, summed over requests
+}
+
+// enter marks a request as started and returns its start time for exit
+func (g *inFlightGauge) enter() time.Time {
+	n := g.current.Add(1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return time.Now()
+}
+
+func (g *inFlightGauge) exit(start time.Time) {
+	g.busy.Add(int64(time.Since(start)))
+	g.current.Add(-1)
+}
+
+// InFlight returns the number of requests in flight right now
+func (lt *LoadTester) InFlight() int {
+	return int(lt.inFlight.current.Load())
+}
+
+// reset clears the peak and busy time between runs
+func (g *inFlightGauge) reset() {
+	g.peak.Store(0)
+	g.busy.Store(0)
+}
+
+// average returns the time-averaged number of requests in flight over elapsed:
+// the summed time in flight divided by the wall time (Little's law)
+func (g *inFlightGauge) average(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(g.busy.Load()) / float64(elapsed)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterResize(t *testing.T) {
+	l := newConcurrencyLimiter(2)
+	l.acquire()
+	l.acquire()
+
+	var acquired int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.acquire()
+			atomic.AddInt32(&acquired, 1)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&acquired); n != 0 {
+		t.Fatalf("%d acquired past a full limiter", n)
+	}
+
+	l.setLimit(4)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&acquired); n != 2 {
+		t.Fatalf("%d acquired after raising the limit to 4, want 2", n)
+	}
+
+	l.release(1)
+	wg.Wait()
+}
+
+func TestConcurrencyLimiterReusesSlots(t *testing.T) {
+	l := newConcurrencyLimiter(3)
+	a, b := l.acquire(), l.acquire()
+	if a != 1 || b != 2 {
+		t.Fatalf("slots = %d, %d, want 1, 2", a, b)
+	}
+	l.release(a)
+	if c := l.acquire(); c != a {
+		t.Errorf("slot after release = %d, want reused %d", c, a)
+	}
+	if d := l.acquire(); d != 3 {
+		t.Errorf("new slot = %d, want 3", d)
+	}
+}
+
+func TestSetConcurrencyRecordsChanges(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.invalid"))
+
+	tester.scaleConcurrency(1 + concurrencyStep) // 5 -> 6 (rounds to at least one step)
+	tester.SetConcurrency(6)                     // no-op
+	tester.scaleConcurrency(1 - concurrencyStep) // 6 -> 5
+	tester.SetConcurrency(0)                     // clamped to 1
+
+	changes := tester.concurrencyChanges
+	if len(changes) != 3 {
+		t.Fatalf("recorded %d changes, want 3: %+v", len(changes), changes)
+	}
+	want := [][2]int{{5, 6}, {6, 5}, {5, 1}}
+	for i, c := range changes {
+		if c.From != want[i][0] || c.To != want[i][1] {
+			t.Errorf("change %d = %d -> %d, want %d -> %d", i, c.From, c.To, want[i][0], want[i][1])
+		}
+	}
+	if tester.Concurrency() != 1 {
+		t.Errorf("concurrency = %d, want 1", tester.Concurrency())
+	}
+}
+
+func TestInFlightGauge(t *testing.T) {
+	var g inFlightGauge
+	a := g.enter()
+	b := g.enter()
+	time.Sleep(20 * time.Millisecond)
+	g.exit(a)
+	g.exit(b)
+	g.exit(g.enter())
+
+	if peak := g.peak.Load(); peak != 2 {
+		t.Errorf("peak = %d, want 2", peak)
+	}
+	if current := g.current.Load(); current != 0 {
+		t.Errorf("current = %d after all exits, want 0", current)
+	}
+	// Two requests in flight for ~20ms of a 20ms window average to ~2
+	if avg := g.average(20 * time.Millisecond); avg < 1.9 || avg > 3 {
+		t.Errorf("average = %.2f, want about 2", avg)
+	}
+}
+
+func TestRunReportsInFlight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.PeakInFlight != config.Concurrent {
+		t.Errorf("peak in flight = %d, want %d", stats.PeakInFlight, config.Concurrent)
+	}
+	if stats.AvgInFlight <= 0 || stats.AvgInFlight > float64(config.Concurrent) {
+		t.Errorf("average in flight = %.2f, want within (0, %d]", stats.AvgInFlight, config.Concurrent)
+	}
+	if stats.PeakUtilization != 1 || stats.Utilization != stats.AvgInFlight/float64(config.Concurrent) {
+		t.Errorf("utilization = %.2f avg, %.2f peak", stats.Utilization, stats.PeakUtilization)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	rateBucketWidth = 100 * time.Millisecond
+	rateWindowSize  = 5 * time.Second
+	rateBucketCount = int(rateWindowSize / rateBucketWidth)
+
+	latencyWindowSize  = 10 // seconds of recent latency kept for windowed percentiles
+	latencyWindowWidth = time.Second
+
+	// etaMinCompleted and etaMinElapsed gate the ETA until the rate is meaningful
+	etaMinCompleted = 10
+	etaMinElapsed   = time.Second
+	// etaSmoothing is the weight of each new estimate in the moving average,
+	// damping the ETA so it doesn't flicker with bursty throughput
+	etaSmoothing = 0.2
+	// etaInterval is how often the smoothed ETA takes in a new estimate, fixed
+	// so the damping doesn't depend on how often the figures are read
+	etaInterval = time.Second
+)
+
+// rateWindow counts completions in a ring of fixed-width time buckets so the
+// recent completion rate can be read without scanning every result
+type rateWindow struct {
+	counts [rateBucketCount]int
+	slots  [rateBucketCount]int64
+}
+
+func bucketSlot(t time.Time) int64 {
+	return t.UnixNano() / int64(rateBucketWidth)
+}
+
+func (w *rateWindow) record(t time.Time) {
+	slot := bucketSlot(t)
+	i := int(slot % int64(rateBucketCount))
+	if w.slots[i] != slot {
+		w.slots[i] = slot
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+}
+
+// sum returns the number of completions recorded in the window ending at now
+func (w *rateWindow) sum(now time.Time) int {
+	current := bucketSlot(now)
+	total := 0
+	for i, slot := range w.slots {
+		if slot > current-int64(rateBucketCount) && slot <= current {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
+
+// latencyWindow keeps one histogram per second for the last latencyWindowSize seconds
+type latencyWindow struct {
+	hists [latencyWindowSize]latencyHistogram
+	slots [latencyWindowSize]int64
+}
+
+func (w *latencyWindow) record(t time.Time, d time.Duration) {
+	slot := t.UnixNano() / int64(latencyWindowWidth)
+	i := int(slot % latencyWindowSize)
+	if w.slots[i] != slot {
+		w.slots[i] = slot
+		w.hists[i].reset()
+	}
+	w.hists[i].record(d)
+}
+
+// merged returns a histogram of the samples recorded in the window ending at now
+func (w *latencyWindow) merged(now time.Time) latencyHistogram {
+	current := now.UnixNano() / int64(latencyWindowWidth)
+	var h latencyHistogram
+	for i, slot := range w.slots {
+		if slot > current-latencyWindowSize && slot <= current {
+			h.merge(&w.hists[i])
+		}
+	}
+	return h
+}
+
+// LiveStats aggregates results as they complete so progress output can report
+// current figures while the test is still running
+type LiveStats struct {
+	mu            sync.Mutex
+	started       time.Time
+	total         int // planned requests, 0 when unknown
+	completed     int
+	failed        int
+	rateLimited   int
+	bytesIn       int64
+	bytesOut      int64
+	errors        *errorGroups
+	window        rateWindow
+	latency       latencyHistogram
+	recentLatency latencyWindow
+	eta           time.Duration // smoothed estimate, 0 until known
+	etaAt         time.Time     // when eta was last updated
+	// stopETA ends the goroutine updating eta, nil when none runs
+	stopETA func()
+}
+
+// LiveSnapshot is a point-in-time copy of LiveStats
+type LiveSnapshot struct {
+	Completed   int
+	Failed      int
+	RateLimited int // 429 and 503 + Retry-After responses
+	Elapsed     time.Duration
+	CurrentRPS  float64 // over the last five seconds
+	OverallRPS  float64
+	// Percentiles over the whole run and over the last ten seconds, keyed like Stats.Percentiles
+	Percentiles       map[int]time.Duration
+	RecentPercentiles map[int]time.Duration
+	// ETA is the estimated time until the planned requests complete, based on
+	// the windowed rate; ETAKnown is false until enough samples exist
+	ETA      time.Duration
+	ETAKnown bool
+	// Projected is the requests a run with a time limit is on course to
+	// complete by then, 0 when the request count ends it first; filled in by
+	// the caller, who knows the limit
+	Projected int
+	// Concurrency is the live concurrency limit and ConfiguredConcurrency the
+	// value from the command line; they differ after interactive adjustments.
+	// LiveStats doesn't own the limiter, so the caller fills these in.
+	Concurrency           int
+	ConfiguredConcurrency int
+	// TargetRPS is the rate a --profile or --control currently asks for, 0 without one;
+	// also filled in by the caller
+	TargetRPS float64
+	// Paused is true while the run is paused from the keyboard; filled in by the caller
+	Paused bool
+	// InFlight is the number of requests in flight right now; filled in by the caller
+	InFlight int
+	// BytesIn and BytesOut are the bytes received and sent so far, only
+	// counted with --count-headers
+	BytesIn  int64
+	BytesOut int64
+	// Backlog is the requests a --profile scheduled that haven't completed,
+	// 0 without one; filled in by the caller
+	Backlog int
+}
+
+// NewLiveStats creates live statistics for a run starting now
+func NewLiveStats() *LiveStats {
+	return &LiveStats{started: time.Now(), errors: newErrorGroups()}
+}
+
+// Start resets the statistics for a run of total requests starting now and
+// keeps the ETA updated until Stop is called
+func (ls *LiveStats) Start(total int) {
+	ls.Stop()
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.started = time.Now()
+	ls.total = total
+	ls.eta = 0
+	ls.etaAt = time.Time{}
+	ls.completed = 0
+	ls.failed = 0
+	ls.rateLimited = 0
+	ls.bytesIn = 0
+	ls.bytesOut = 0
+	ls.errors = newErrorGroups()
+	ls.window = rateWindow{}
+	ls.latency.reset()
+	ls.recentLatency = latencyWindow{}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(etaInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				ls.mu.Lock()
+				ls.updateETA(now)
+				ls.mu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	ls.stopETA = func() {
+		close(stop)
+		<-done
+	}
+}
+
+// Stop ends the ETA updates started by Start
+func (ls *LiveStats) Stop() {
+	ls.mu.Lock()
+	stop := ls.stopETA
+	ls.stopETA = nil
+	ls.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// Record adds a completed result
+func (ls *LiveStats) Record(result Result, success bool) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.completed++
+	if !success {
+		ls.failed++
+	}
+	if result.RateLimited {
+		ls.rateLimited++
+	}
+	if result.RequestBytes > 0 {
+		ls.bytesIn += result.ContentSize + result.HeaderBytes
+		ls.bytesOut += result.RequestBytes
+	}
+	if result.Error != nil {
+		ls.errors.add(result.Error.Error(), result.Timestamp)
+	}
+	ls.window.record(result.Timestamp)
+	ls.latency.record(result.ResponseTime)
+	ls.recentLatency.record(result.Timestamp, result.ResponseTime)
+}
+
+// Snapshot returns the current live figures
+func (ls *LiveStats) Snapshot() LiveSnapshot {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	now := time.Now()
+	snap := LiveSnapshot{
+		Completed:         ls.completed,
+		Failed:            ls.failed,
+		RateLimited:       ls.rateLimited,
+		BytesIn:           ls.bytesIn,
+		BytesOut:          ls.bytesOut,
+		Elapsed:           now.Sub(ls.started),
+		Percentiles:       make(map[int]time.Duration),
+		RecentPercentiles: make(map[int]time.Duration),
+	}
+
+	if snap.Elapsed > 0 {
+		snap.CurrentRPS = ls.currentRPS(now)
+		snap.OverallRPS = float64(ls.completed) / snap.Elapsed.Seconds()
+	}
+
+	// The ETA counts down between updates
+	if !ls.etaAt.IsZero() {
+		snap.ETA = max(ls.eta-now.Sub(ls.etaAt), 0)
+		snap.ETAKnown = true
+	}
+
+	recent := ls.recentLatency.merged(now)
+	for _, p := range reportedPercentiles {
+		snap.Percentiles[p] = ls.latency.quantile(p)
+		snap.RecentPercentiles[p] = recent.quantile(p)
+	}
+
+	return snap
+}
+
+// median returns the median response time so far, 0 before the first result
+func (ls *LiveStats) median() time.Duration {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.latency.quantile(50)
+}
+
+// Errors returns the distinct error messages seen so far, most frequent first,
+// and the number of errors whose messages exceeded the tracking cap
+func (ls *LiveStats) Errors() ([]ErrorGroup, int) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.errors.sorted(), ls.errors.omitted
+}
+
+// currentRPS returns the completion rate over the last five seconds, or over
+// the time elapsed so far when the run is younger than that. It must be called
+// with ls.mu held.
+func (ls *LiveStats) currentRPS(now time.Time) float64 {
+	span := min(now.Sub(ls.started), rateWindowSize)
+	if span <= 0 {
+		return 0
+	}
+	return float64(ls.window.sum(now)) / span.Seconds()
+}
+
+// updateETA folds the current remaining-time estimate into the smoothed ETA.
+// It runs every etaInterval and must be called with ls.mu held.
+func (ls *LiveStats) updateETA(now time.Time) {
+	rps := ls.currentRPS(now)
+	if ls.total <= 0 || ls.completed < etaMinCompleted || now.Sub(ls.started) < etaMinElapsed || rps <= 0 {
+		return
+	}
+
+	remaining := ls.total - ls.completed
+	if remaining <= 0 {
+		ls.eta = 0
+	} else {
+		estimate := time.Duration(float64(remaining) / rps * float64(time.Second))
+		if ls.etaAt.IsZero() {
+			ls.eta = estimate
+		} else {
+			ls.eta = time.Duration(etaSmoothing*float64(estimate) + (1-etaSmoothing)*float64(ls.eta))
+		}
+	}
+	ls.etaAt = now
+}
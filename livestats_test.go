@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLiveStatsETA(t *testing.T) {
+	// Without Start no ticker runs, so the test drives the updates itself
+	ls := NewLiveStats()
+	ls.total = 100
+	tick := func() {
+		ls.mu.Lock()
+		ls.updateETA(time.Now())
+		ls.mu.Unlock()
+	}
+
+	tick()
+	if snap := ls.Snapshot(); snap.ETAKnown {
+		t.Fatal("ETA should be unknown before any results")
+	}
+
+	// Pretend the run started two seconds ago and 50 requests finished in the last second
+	ls.mu.Lock()
+	ls.started = time.Now().Add(-2 * time.Second)
+	ls.mu.Unlock()
+	for i := 0; i < 50; i++ {
+		ls.Record(Result{StatusCode: 200, ResponseTime: time.Millisecond, Timestamp: time.Now()}, true)
+	}
+
+	if snap := ls.Snapshot(); snap.ETAKnown {
+		t.Fatal("ETA should stay unknown until the next update")
+	}
+	tick()
+	snap := ls.Snapshot()
+	if !snap.ETAKnown {
+		t.Fatal("ETA should be known after 50 results over 2s")
+	}
+	// 50 remaining at 25 req/s over the two-second window
+	if snap.ETA < time.Second || snap.ETA > 3*time.Second {
+		t.Errorf("ETA = %v, want about 2s", snap.ETA)
+	}
+	if snap.Completed != 50 || snap.Failed != 0 {
+		t.Errorf("completed/failed = %d/%d", snap.Completed, snap.Failed)
+	}
+}
+
+func TestLiveStatsETASmoothing(t *testing.T) {
+	ls := NewLiveStats()
+	ls.total = 100
+	ls.started = time.Now().Add(-2 * time.Second)
+	for i := 0; i < 50; i++ {
+		ls.Record(Result{StatusCode: 200, ResponseTime: time.Millisecond, Timestamp: time.Now()}, true)
+	}
+	ls.updateETA(time.Now())
+	first := ls.eta
+
+	// Reading the figures, however often, leaves the estimate alone
+	for i := 0; i < 100; i++ {
+		ls.Snapshot()
+	}
+	if ls.eta != first {
+		t.Errorf("ETA changed from %v to %v by Snapshot", first, ls.eta)
+	}
+
+	// Halving the rate doubles the raw estimate; one update moves the
+	// smoothed ETA only part of the way there
+	ls.started = time.Now().Add(-4 * time.Second)
+	ls.updateETA(time.Now())
+	if ls.eta <= first || ls.eta >= 2*first {
+		t.Errorf("ETA after the rate halved = %v, want between %v and %v", ls.eta, first, 2*first)
+	}
+}
+
+// TestLiveStatsMatchSummary records a fixed mix of results and checks the live
+// counts against both the mix and the summary calculateStats builds from them
+func TestLiveStatsMatchSummary(t *testing.T) {
+	lt := newTestLoadTester(t, testConfig("http://localhost"))
+	now := time.Now()
+	lt.startTime = now
+	mix := []struct {
+		result Result
+		n      int
+	}{
+		{Result{StatusCode: 200}, 6},
+		{Result{StatusCode: 500}, 2},
+		{Result{Error: errors.New("connection refused")}, 1},
+		{Result{StatusCode: 429, RateLimited: true, RetryAfter: -1}, 4},
+	}
+	for _, m := range mix {
+		for i := 0; i < m.n; i++ {
+			result := m.result
+			result.ResponseTime = time.Millisecond
+			result.Timestamp = now
+			lt.live.Record(result, lt.isSuccess(result))
+			lt.results = append(lt.results, result)
+		}
+	}
+
+	snap := lt.live.Snapshot()
+	if snap.Completed != 13 || snap.Failed != 7 || snap.RateLimited != 4 {
+		t.Errorf("live completed/failed/rate limited = %d/%d/%d, want 13/7/4",
+			snap.Completed, snap.Failed, snap.RateLimited)
+	}
+
+	stats := lt.calculateStats(time.Second)
+	if stats.RateLimited == nil {
+		t.Fatal("summary has no rate-limited responses")
+	}
+	if snap.Completed != stats.TotalRequests || snap.Failed != stats.FailedReqs || snap.RateLimited != stats.RateLimited.Responses {
+		t.Errorf("live completed/failed/rate limited = %d/%d/%d, summary has %d/%d/%d",
+			snap.Completed, snap.Failed, snap.RateLimited,
+			stats.TotalRequests, stats.FailedReqs, stats.RateLimited.Responses)
+	}
+}
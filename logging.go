@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// logger records what a run did to --log-file. It discards everything until
+// setupLogging installs a file handler, so the screen output is never affected.
+var logger = slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+
+// Log file formats accepted by --log-format
+const (
+	logFormatJSON = "json"
+	logFormatText = "text"
+)
+
+// setupLogging directs logger to a log file at the given level, as JSON or as
+// logfmt-style text, and returns a function that closes the file
+func setupLogging(filename, level, format string) (func() error, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid --log-level %q: must be debug, info, warn or error", level)
+	}
+	if format != logFormatJSON && format != logFormatText {
+		return nil, fmt.Errorf("invalid --log-format %q: must be json or text", format)
+	}
+	if filename == "" {
+		return func() error { return nil }, nil
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %v", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	if format == logFormatText {
+		logger = slog.New(slog.NewTextHandler(f, opts))
+	} else {
+		logger = slog.New(slog.NewJSONHandler(f, opts))
+	}
+	return f.Close, nil
+}
+
+// errorMessage returns err's text, or "" when err is nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// errorCategory classifies a failed result for logs
+func errorCategory(result Result) string {
+	err := result.Error
+	if err == nil {
+		return "http_status"
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.Is(err, errCanceledDuringRead):
+		return "canceled_during_read"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_reset"
+	case errors.Is(err, errPinMismatch):
+		return "tls_pin"
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &recordErr):
+		return "tls"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "other"
+}
+
+// startIntervalLog writes a progress summary to the log every interval until
+// the returned stop function is called
+func startIntervalLog(tester *LoadTester, interval time.Duration) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				snap := tester.Live().Snapshot()
+				logger.Info("progress",
+					"elapsed", snap.Elapsed,
+					"completed", snap.Completed,
+					"failed", snap.Failed,
+					"current_rps", snap.CurrentRPS,
+					"p95", snap.RecentPercentiles[95],
+					"concurrency", tester.Concurrency())
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{Result{StatusCode: 503}, "http_status"},
+		{Result{Error: fmt.Errorf("wrapped: %w", context.DeadlineExceeded)}, "timeout"},
+		{Result{Error: &net.DNSError{Err: "no such host", Name: "nope.invalid"}}, "dns"},
+		{Result{Error: fmt.Errorf("something else")}, "other"},
+	}
+	for _, tt := range tests {
+		if got := errorCategory(tt.result); got != tt.want {
+			t.Errorf("errorCategory(%v) = %q, want %q", tt.result.Error, got, tt.want)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	result := newTestLoadTester(t, testConfig("http://"+addr)).makeRequest(testRand())
+	if got := errorCategory(result); got != "connection_refused" {
+		t.Errorf("errorCategory(%v) = %q, want connection_refused", result.Error, got)
+	}
+}
+
+func TestSetupLoggingRejectsUnknownLevel(t *testing.T) {
+	if _, err := setupLogging("", "loud", logFormatJSON); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := setupLogging("", "info", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestSetupLoggingTextFormat(t *testing.T) {
+	defer func(l *slog.Logger) { logger = l }(logger)
+	path := filepath.Join(t.TempDir(), "run.log")
+	closeLog, err := setupLogging(path, "info", logFormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("run finished", "requests", 3)
+	logger.Debug("request failed")
+	closeLog()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `msg="run finished" requests=3`) || strings.Contains(got, "request failed") {
+		t.Errorf("text log = %q, want the info record only, as key=value pairs", got)
+	}
+}
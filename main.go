@@ -1,18 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"log"
 	"math"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -32,25 +39,158 @@ const banner = `
 
 // Config holds the configuration for load testing
 type Config struct {
-	URL         string            `json:"url"`
-	Method      string            `json:"method"`
-	Headers     map[string]string `json:"headers"`
-	Body        string            `json:"body"`
-	Concurrent  int               `json:"concurrent"`
-	Requests    int               `json:"requests"`
-	Duration    time.Duration     `json:"duration"`
-	Timeout     time.Duration     `json:"timeout"`
-	InsecureTLS bool              `json:"insecure_tls"`
-	ProxyURL    string            `json:"proxy_url"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Concurrent int               `json:"concurrent"`
+	// Requests 0 sends requests until MaxDuration or the Profile ends
+	Requests    int           `json:"requests"`
+	Duration    time.Duration `json:"duration"` // only read from --config, as --max-duration
+	Timeout     time.Duration `json:"timeout"`
+	InsecureTLS bool          `json:"insecure_tls"`
+	// PinSHA256 are base64 SHA-256 public key pins; the server's chain must match one
+	PinSHA256   []string `json:"pin_sha256,omitempty"`
+	ProxyURL    string   `json:"proxy_url"`
+	ConnectOnly bool     `json:"connect_only"`
+	Host        string   `json:"host"`
+	// PrewarmConns opens Concurrent connections before the run; see LoadTester.Prewarm
+	PrewarmConns bool `json:"prewarm_conns"`
+	// RandomBodySize sends a random body of this many bytes instead of Body;
+	// RandomBodyEach regenerates it for every request instead of reusing one buffer
+	RandomBodySize int64 `json:"random_body_size"`
+	RandomBodyEach bool  `json:"random_body_each"`
+	// BodyTemplate is a text/template rendered into a new body for every request
+	BodyTemplate string `json:"body_template,omitempty"`
+	// MaxDuration cancels the run after this long regardless of remaining requests
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+	// MethodMix sends each request with a method picked by weight instead of Method
+	MethodMix []WeightedMethod `json:"method_mix,omitempty"`
+	// Profile paces request starts to a target rate interpolated between its
+	// points; the run ends at the last point
+	Profile []RatePoint `json:"profile,omitempty"`
+	// Phases run one after another with their own rate and think time; they
+	// are compiled into Profile, which paces the run
+	Phases []Phase `json:"phases,omitempty"`
+	// BearerFile holds a bearer token that is re-read every BearerRefresh and after a 401
+	BearerFile    string        `json:"bearer_file,omitempty"`
+	BearerRefresh time.Duration `json:"bearer_refresh,omitempty"`
+	// Seed drives every random choice (method mix, random bodies, template
+	// functions), so runs with the same seed send the same requests
+	Seed uint64 `json:"seed"`
+	// ApdexT is the target response time of the Apdex score; 0 leaves it out
+	ApdexT time.Duration `json:"apdex_t,omitempty"`
+	// MaxConnsPerHost caps the connections per host, 0 for no limit;
+	// MaxIdleConns overrides the idle pool size of twice the concurrency
+	MaxConnsPerHost int `json:"max_conns_per_host,omitempty"`
+	MaxIdleConns    int `json:"max_idle_conns,omitempty"`
+	// RequestIDHeaders each carry a fresh UUID per request, e.g. X-Request-ID or Idempotency-Key
+	RequestIDHeaders []string `json:"request_id_headers,omitempty"`
+	// CheckConsistency hashes successful response bodies to count distinct variants
+	CheckConsistency bool `json:"check_consistency,omitempty"`
+	// CountHeaders adds estimated header bytes to the transfer figures, and
+	// counts the bytes sent
+	CountHeaders bool `json:"count_headers,omitempty"`
+	// SSE opens Concurrent server-sent event streams and counts their events
+	// instead of sending requests
+	SSE bool `json:"sse,omitempty"`
+	// CaptureHeaders are response headers whose distinct values are counted
+	// to spot responses that disagree
+	CaptureHeaders []string `json:"capture_headers,omitempty"`
+	// TrackHeaders are response headers whose values are tallied with latency percentiles
+	TrackHeaders []string `json:"track_headers,omitempty"`
+	// IfNoneMatch sends conditional requests: "auto" captures the validators
+	// with an initial request, any other value is sent as the ETag
+	IfNoneMatch string `json:"if_none_match,omitempty"`
+	// HonorRetryAfter pauses new requests for the backoff a rate-limited response asks for
+	HonorRetryAfter bool `json:"honor_retry_after,omitempty"`
+	// DrainTimeout is how long requests in flight when the run ends may take
+	// to finish before they are canceled; 0 cancels them at --max-duration and
+	// otherwise lets them run to the request timeout
+	DrainTimeout time.Duration `json:"drain_timeout,omitempty"`
+	// Cooldown stops dispatching this long before the end of a time-limited
+	// run; requests completing in that window are reported separately
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+	// DNSServer is the host:port of a name server that replaces the system resolver
+	DNSServer string `json:"dns_server,omitempty"`
+	// Retries re-sends a request that failed with a connection error, a 5xx
+	// or a 429 up to this many times. The backoff starts at RetryBackoff and
+	// doubles up to RetryMaxBackoff; RetryJitter is the fraction of it that is
+	// random, 1 for full jitter.
+	Retries         int           `json:"retries,omitempty"`
+	RetryBackoff    time.Duration `json:"retry_backoff,omitempty"`
+	RetryMaxBackoff time.Duration `json:"retry_max_backoff,omitempty"`
+	RetryJitter     float64       `json:"retry_jitter,omitempty"`
+	// GracePeriod is how long from the start refused and reset connections
+	// are retried until they succeed, so a target still coming up isn't
+	// charged for them
+	GracePeriod time.Duration `json:"grace_period,omitempty"`
+	// ChaosAbort is the fraction of requests canceled on purpose mid-request,
+	// and ChaosDelayRate the fraction held back ChaosDelay before sending
+	ChaosAbort     float64       `json:"chaos_abort,omitempty"`
+	ChaosDelayRate float64       `json:"chaos_delay_rate,omitempty"`
+	ChaosDelay     time.Duration `json:"chaos_delay,omitempty"`
+	// MaxRPSPerWorker caps how often each concurrency slot starts a request,
+	// on top of any global pacing; 0 leaves the slots unpaced
+	MaxRPSPerWorker float64 `json:"max_rps_per_worker,omitempty"`
+
+	// secrets are values expanded from secret-looking environment variables,
+	// redacted when the config is printed
+	secrets []string
 }
 
 // Result holds the result of a single request
 type Result struct {
 	StatusCode   int
-	ResponseTime time.Duration
+	Method       string
+	ResponseTime time.Duration // until the whole response, body included, was read
+	HeaderTime   time.Duration // until the response headers were read, before the body
 	ContentSize  int64
 	Error        error
-	Timestamp    time.Time
+	Timestamp    time.Time // when the result was complete
+	StartTime    time.Time // when the request was dispatched
+	// ScheduledAt is when --profile meant the request to start, as an offset
+	// from the start of the run; StartTime minus that slot is its queue delay
+	ScheduledAt  time.Duration `json:",omitempty"`
+	Seq          int           // 1-based dispatch order within the run
+	WorkerID     int           // concurrency slot that ran the request, from 1
+	TLSHandshake time.Duration // only measured in connect-only mode
+	Trailers     []string      // names of the response trailers received, if any
+	Headers      http.Header   `json:"-"` // response headers, only captured for -vv
+	TTFB         time.Duration // time to the first response byte
+	DNSLookup    time.Duration // 0 when the request reused a connection or the host is an IP
+	TTLB         time.Duration // time to the last body byte, i.e. the whole response read
+	// RateLimited marks a 429, or a 503 with Retry-After; RetryAfter is the
+	// backoff it advertised, -1 without a valid Retry-After header
+	RateLimited bool
+	RetryAfter  time.Duration
+	// TrackedHeaders holds the values of the --track-header headers, in order
+	TrackedHeaders []string
+	// RequestID is the UUID sent in the --request-id-header headers
+	RequestID string `json:",omitempty"`
+	// TLSVersion and TLSCipherSuite are what the connection negotiated, 0 over plain HTTP
+	TLSVersion     uint16 `json:",omitempty"`
+	TLSCipherSuite uint16 `json:",omitempty"`
+	// TraceID and SpanID are the W3C trace context sent with --otel
+	TraceID string `json:",omitempty"`
+	SpanID  string `json:",omitempty"`
+	// Host is the host that answered, after any redirects; "" when unknown
+	Host string `json:",omitempty"`
+	// HeaderBytes estimates the response status line and headers, RequestBytes
+	// the whole request; both are only counted with --count-headers
+	HeaderBytes  int64 `json:",omitempty"`
+	RequestBytes int64 `json:",omitempty"`
+	// Events is how many events an --sse stream delivered
+	Events int `json:",omitempty"`
+	// ServerTiming holds the durations the response reported in Server-Timing
+	ServerTiming []ServerTiming `json:",omitempty"`
+	// Retries is how many times --retries re-sent the request and Backoff the
+	// time waited in between; the other fields describe the last attempt
+	Retries int           `json:",omitempty"`
+	Backoff time.Duration `json:",omitempty"`
+	// GraceRetries is how many refused or reset attempts --grace-period
+	// retried before this one
+	GraceRetries int `json:",omitempty"`
 }
 
 // Stats holds aggregated statistics
@@ -63,10 +203,149 @@ type Stats struct {
 	MaxResponseTime time.Duration
 	AvgResponseTime time.Duration
 	ResponseTimes   []time.Duration
-	StatusCodes     map[int]int
-	TotalBytes      int64
+	StatusCodes     map[int]int // responses by status code; requests without one are in ErrorCount
+	ErrorCount      int         // requests that got no response: connection errors, timeouts
+	TotalBytes      int64       // response bodies; headers are in HeaderBytes with --count-headers
 	RequestsPerSec  float64
 	Percentiles     map[int]time.Duration
+	Timeline        []TimelineBucket // per-second throughput and latency over the run
+	// Degradation compares the first and last tenth of runs of 10 seconds or more
+	Degradation *Degradation `json:",omitempty"`
+	// Outliers lists very slow requests and mostly failing seconds, nil when there are none
+	Outliers    *Outliers    `json:",omitempty"`
+	SelfMetrics *SelfMetrics // peak client resource usage, only set with --self-metrics
+	ConnectOnly bool
+	// HeaderBytes estimates the response status lines and headers received and
+	// SentBytes the whole requests sent, only counted with --count-headers
+	HeaderBytes int64 `json:",omitempty"`
+	SentBytes   int64 `json:",omitempty"`
+
+	// Trailers counts how many responses carried each trailer name;
+	// ResponsesWithTrailers counts responses that had any trailer at all
+	Trailers              map[string]int
+	ResponsesWithTrailers int
+
+	// ConcurrencyChanges lists live adjustments of the concurrency limit during the run
+	ConcurrencyChanges []ConcurrencyChange
+
+	// ErrorGroups lists distinct error messages by frequency, capped at
+	// maxDistinctErrors; ErrorsOmitted counts errors with messages beyond the cap
+	ErrorGroups   []ErrorGroup
+	ErrorsOmitted int
+
+	// HandshakePercentiles holds TLS handshake latencies in connect-only mode against https targets
+	HandshakePercentiles map[int]time.Duration
+
+	// TTFBPercentiles and TTLBPercentiles hold the times to the first and the
+	// last response byte of requests that read a full response
+	TTFBPercentiles map[int]time.Duration
+	TTLBPercentiles map[int]time.Duration
+	// TransferPercentiles hold the time from the first to the last byte of
+	// each response, the part of TTLB spent moving the body rather than
+	// waiting for the server; MedianThroughput is the median body bytes/sec
+	// over that time, for responses with a body
+	TransferPercentiles map[int]time.Duration
+	MedianThroughput    float64
+	// HeaderPercentiles hold the times until the response headers were read,
+	// the latency before any of the body, which Percentiles include
+	HeaderPercentiles map[int]time.Duration
+
+	// DNSPercentiles holds the lookup times of requests that resolved the host
+	DNSPercentiles map[int]time.Duration
+
+	// PlannedRequests is the configured request count, 0 without a limit; it
+	// is larger than TotalRequests when MaxDurationReached or ProfileFinished
+	// cut the run short
+	PlannedRequests    int
+	MaxDurationReached bool
+	ProfileFinished    bool
+	// Interrupted is set when Ctrl+C or SIGINT ended the run early
+	Interrupted bool `json:",omitempty"`
+	// CanceledAtDrain counts requests still in flight when --drain-timeout ran
+	// out; like those cut off by --max-duration they aren't in the results
+	CanceledAtDrain int `json:",omitempty"`
+	// ChaosAborted counts the requests --chaos-abort canceled, also left out
+	// of the results, and ChaosDelayed those --chaos-delay held back
+	ChaosAborted int `json:",omitempty"`
+	ChaosDelayed int `json:",omitempty"`
+	// StoppedBy names the limit that ended the run before every planned
+	// request was sent, as stopReason does, and SkippedRequests counts the
+	// planned requests that never were
+	StoppedBy       string `json:",omitempty"`
+	SkippedRequests int    `json:",omitempty"`
+
+	// Methods breaks the results down per method when a --methods mix is used
+	Methods map[string]MethodStats
+
+	// AvgInFlight and PeakInFlight are the requests actually in flight during
+	// the run, which can fall short of the configured concurrency
+	AvgInFlight  float64
+	PeakInFlight int
+	// Utilization and PeakUtilization are AvgInFlight and PeakInFlight as a
+	// fraction of the configured concurrency
+	Utilization     float64
+	PeakUtilization float64
+
+	// Apdex is the satisfaction score for --apdex-t, nil when not requested
+	Apdex *Apdex
+
+	// Conditional compares 304 and full responses with --if-none-match, nil otherwise
+	Conditional *ConditionalStats
+
+	// HeaderBreakdowns tally the values of each --track-header header
+	HeaderBreakdowns []HeaderBreakdown
+	// CapturedHeaders summarise the values of each --capture-headers header
+	CapturedHeaders []CapturedHeader
+	// TLS counts the negotiated TLS version and cipher suite pairs of https runs
+	TLS []TLSParams
+
+	// BodyConsistency lists the distinct successful response bodies with --check-consistency
+	BodyConsistency *BodyConsistency
+
+	// RateLimited summarises 429 and 503 + Retry-After responses, nil when there were none
+	RateLimited *RateLimitStats
+
+	// Timeouts summarises the requests given up at the timeout, nil when none were
+	Timeouts *TimeoutStats `json:",omitempty"`
+
+	// Retries summarises --retries, nil when no request was retried
+	Retries *RetryStats `json:",omitempty"`
+
+	// Grace summarises the retries of --grace-period, nil when there were none
+	Grace *GraceStats `json:",omitempty"`
+
+	// Workers counts the requests of each concurrency slot with --max-rps-per-worker, nil otherwise
+	Workers *WorkerStats `json:",omitempty"`
+
+	// Queue reports how late requests started against the --profile schedule, nil without one
+	Queue *QueueStats `json:",omitempty"`
+	// Saturation is where the backlog of a --profile run started growing for
+	// good, nil when it didn't
+	Saturation *Saturation `json:",omitempty"`
+
+	// SSE summarises the events of an --sse run, nil otherwise
+	SSE *SSEStats `json:",omitempty"`
+
+	// ServerTiming aggregates the Server-Timing metrics responses reported, nil without any
+	ServerTiming []ServerTimingStats `json:",omitempty"`
+
+	// Hosts breaks the results down by the host that answered, nil when it was always the target
+	Hosts map[string]HostStats `json:",omitempty"`
+	// TargetHost is the host of the configured URL
+	TargetHost string `json:",omitempty"`
+
+	// Phases segments the results by --phases phase, nil without phases
+	Phases []PhaseStats `json:",omitempty"`
+
+	// Cooldown reports the requests left out of the statistics by --cooldown, nil without one
+	Cooldown *CooldownStats `json:",omitempty"`
+
+	// Repeat aggregates all runs of a --repeat series, nil for a single run;
+	// the other fields describe the last run
+	Repeat *RepeatStats `json:",omitempty"`
+	// Tune reports the --target-p95 search, whose best probe the other
+	// fields describe
+	Tune *TuneStats `json:",omitempty"`
 }
 
 // LoadTester represents the load testing tool
@@ -74,42 +353,176 @@ type LoadTester struct {
 	config     Config
 	httpClient *http.Client
 	results    []Result
-	mu         sync.Mutex
+	// cooldownResults completed during the --cooldown window and are kept out of results
+	cooldownResults []Result
+	randomBody      []byte
+	// bodyTemplate renders the body of each request, nil unless configured
+	bodyTemplate *bodyTemplate
+	// bearer supplies the Authorization token, nil unless --bearer-file is set
+	bearer *tokenFile
+	// trace is called with every completed request for -v; captureHeaders
+	// keeps the response headers on results for -vv
+	trace          func(Result)
+	captureHeaders bool
+	limiter        *concurrencyLimiter
+	inFlight       inFlightGauge
+	pacer          *ratePacer   // nil unless a rate profile is configured
+	workerPacer    *workerPacer // nil unless --max-rps-per-worker is set
+	sse            *sseEvents   // nil unless --sse is set
+	retryAfter     retryAfterGate
+	// dnsResolver replaces the system resolver, nil unless --dns-server is set
+	dnsResolver *net.Resolver
+	pause       pauseGate
+	rateCap     rateCap // set while running by --control
+	// recent keeps the last outcomes for the --interactive detail view, nil
+	// otherwise; detail is true while the view is on
+	recent *recentResults
+	detail atomic.Bool
+	// otel exports a span per request with --otel, nil otherwise
+	otel *otelExporter
+	// headerTemplate holds the configured headers, cloned onto every request
+	headerTemplate http.Header
+	// requestBodies pools the readers the configured --body is sent through
+	requestBodies sync.Pool
+	// connectTLS is the TLS config of --connect-only handshakes, built once
+	// with the target's server name; nil unless connecting to an https URL
+	connectTLS *tls.Config
+	// headerCapture tallies --capture-headers, nil unless configured
+	headerCapture *headerCapture
+	// bodyDigests tallies response bodies for --check-consistency, nil unless configured
+	bodyDigests *bodyDigests
+	// validators are sent on conditional requests, nil unless --if-none-match is set
+	validators    atomic.Pointer[cacheValidators]
+	rateLimitSeen atomic.Bool
+	// drainCanceled counts requests canceled when --drain-timeout ran out
+	drainCanceled atomic.Int64
+	prewarmed     *connPool // connections opened by Prewarm, nil unless enabled
+	live          *LiveStats
+	// ctx is cancelled when the run exceeds Config.MaxDuration
+	ctx context.Context
+	// interrupt ends every run early once done, like --max-duration; nil
+	// unless watchInterrupts is listening
+	interrupt context.Context
+	// chaosAborted and chaosDelayed count what --chaos-abort and --chaos-delay did
+	chaosAborted atomic.Int64
+	chaosDelayed atomic.Int64
+	// concurrencyChanges records live adjustments made with SetConcurrency
+	concurrencyChanges []ConcurrencyChange
+	startTime          time.Time
+	endTime            time.Time
+	mu                 sync.Mutex
 }
 
 // Global variables for command flags
 var (
-	targetURL  string
-	method     string
-	headers    string
-	body       string
-	concurrent int
-	requests   int
-	timeout    time.Duration
-	insecure   bool
-	output     string
-	noBanner   bool
-	proxy      string
-	version    string = "dev"
+	targetURL        string
+	method           string
+	headers          string
+	contentType      string
+	body             string
+	concurrent       int
+	requests         int
+	timeout          time.Duration
+	insecure         bool
+	pinSHA256        []string
+	output           string
+	noBanner         bool
+	proxy            string
+	selfMetrics      bool
+	noSummary        bool
+	randomBodySize   string
+	randomBodyEach   bool
+	once             bool
+	strict           bool
+	repeat           int
+	targetP95        time.Duration
+	interactive      bool
+	prewarmConns     bool
+	warnLatency      time.Duration
+	critLatency      time.Duration
+	progressMode     string
+	progressEvery    time.Duration
+	quiet            bool
+	summaryOnly      bool
+	maxDuration      time.Duration
+	plain            bool
+	methodMix        string
+	logFile          string
+	logLevel         string
+	logFormat        string
+	bearerFile       string
+	bearerRefresh    time.Duration
+	verbosity        int
+	noProgress       bool
+	presetName       string
+	configFile       string
+	controlFile      string
+	printConfig      bool
+	profileFile      string
+	seed             uint64
+	apdexT           time.Duration
+	honorRetryAfter  bool
+	dnsServer        string
+	cooldown         time.Duration
+	drainTimeout     time.Duration
+	retries          int
+	maxRPSPerWorker  float64
+	chaosAbort       string
+	chaosDelay       string
+	retryBackoff     time.Duration
+	retryMaxBackoff  time.Duration
+	retryJitter      float64
+	phasesFile       string
+	ifNoneMatch      string
+	maxConnsPerHost  int
+	trackHeaders     []string
+	captureHeaders   []string
+	checkConsistency bool
+	countHeaders     bool
+	sse              bool
+	gracePeriod      time.Duration
+	requestIDHeaders []string
+	formPairs        []string
+	formURLEncoded   []string
+	maxIdleConns     int
+	bodyTemplateFile string
+	outputFormat     string
+	webhookURL       string
+	otelEnabled      bool
+	otelEndpoint     string
+	webhookFormat    string
+	connectOnly      bool
+	hostHeader       string
+	rawRequest       string
+	version          string = "dev"
+	commit           string // set with -ldflags "-X main.commit=..."
+	buildDate        string // set with -ldflags "-X main.buildDate=..."
 )
 
-// NewLoadTester creates a new load tester instance
-func NewLoadTester(config Config) *LoadTester {
+// NewLoadTester creates a new load tester instance. It fails when a setting
+// read from a file, such as the body template or bearer token, is invalid.
+func NewLoadTester(config Config) (*LoadTester, error) {
 	transport := &http.Transport{
 		MaxIdleConns:        config.Concurrent * 2,
 		MaxIdleConnsPerHost: config.Concurrent,
+		MaxConnsPerHost:     config.MaxConnsPerHost,
 		IdleConnTimeout:     30 * time.Second,
 	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
 
-	if config.InsecureTLS {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
 	}
+	transport.TLSClientConfig = tlsConfig
 
 	// Configure proxy if provided
 	if config.ProxyURL != "" {
 		proxyURL, err := url.Parse(config.ProxyURL)
 		if err != nil {
-			log.Printf("Invalid proxy URL: %v", err)
+			logger.Warn("invalid proxy URL, connecting directly", "proxy", config.ProxyURL, "error", err)
 		} else {
 			transport.Proxy = http.ProxyURL(proxyURL)
 		}
@@ -119,87 +532,570 @@ func NewLoadTester(config Config) *LoadTester {
 		Timeout:   config.Timeout,
 		Transport: transport,
 	}
+	// Event streams stay open for the whole run; openStream applies the timeout
+	if config.SSE {
+		client.Timeout = 0
+	}
 
-	return &LoadTester{
+	lt := &LoadTester{
 		config:     config,
 		httpClient: client,
 		results:    make([]Result, 0),
+
+		headerTemplate: newHeaderTemplate(config.Headers),
+		requestBodies:  sync.Pool{New: newRequestBody},
+		limiter:        newConcurrencyLimiter(config.Concurrent),
+		live:           NewLiveStats(),
+		ctx:            context.Background(),
+	}
+
+	if len(config.Profile) > 0 {
+		lt.pacer = newRatePacer(config.Profile)
+	}
+	if config.ConnectOnly {
+		// makeConnection reports a URL that doesn't parse
+		if u, err := url.Parse(config.URL); err == nil && u.Scheme == "https" {
+			lt.connectTLS = tlsConfigFor(tlsConfig, targetAddr(u))
+		}
+	}
+	if config.MaxRPSPerWorker > 0 {
+		lt.workerPacer = newWorkerPacer(config.MaxRPSPerWorker)
+	}
+	if config.SSE {
+		lt.sse = &sseEvents{}
+	}
+
+	if config.DNSServer != "" {
+		lt.dnsResolver = newDNSResolver(config.DNSServer)
+		transport.DialContext = lt.dialer().DialContext
+	}
+
+	// Prewarmed connections are dialed to the target, so they are useless through a proxy
+	if config.PrewarmConns && config.ProxyURL == "" {
+		lt.prewarmed = newConnPool(lt.dialer(), transport.TLSClientConfig)
+		lt.prewarmed.install(transport)
+	}
+
+	if config.RandomBodySize > 0 && !config.RandomBodyEach {
+		lt.randomBody = randomBody(config.RandomBodySize, lt.setupRand())
 	}
+
+	if config.BodyTemplate != "" {
+		tmpl, err := newBodyTemplate(config.BodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing body template: %v", err)
+		}
+		lt.bodyTemplate = tmpl
+	}
+
+	if config.BearerFile != "" {
+		bearer, err := newTokenFile(config.BearerFile, config.BearerRefresh)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bearer token: %v", err)
+		}
+		lt.bearer = bearer
+	}
+
+	if len(config.CaptureHeaders) > 0 {
+		lt.headerCapture = newHeaderCapture(config.CaptureHeaders)
+	}
+	if config.CheckConsistency {
+		lt.bodyDigests = newBodyDigests()
+	}
+
+	// --if-none-match auto captures the validators in runLoadTest instead
+	if config.IfNoneMatch != "" && config.IfNoneMatch != conditionalAuto {
+		lt.validators.Store(&cacheValidators{etag: config.IfNoneMatch})
+	}
+
+	return lt, nil
 }
 
-// makeRequest performs a single HTTP request
-func (lt *LoadTester) makeRequest() Result {
-	start := time.Now()
+// makeRequest performs a single HTTP request with the configured or a mixed
+// method, making every random choice with rng
+func (lt *LoadTester) makeRequest(rng *rand.Rand) Result {
+	method := lt.pickMethod(rng)
+	result := lt.sendRequest(method, rng)
+	// The token may have rotated since it was last read: reload it and retry
+	// once, but only if the file actually holds a different token now
+	if result.StatusCode == http.StatusUnauthorized && lt.bearer != nil && lt.bearer.reload() {
+		result = lt.sendRequest(method, rng)
+	}
+	// A target still coming up may refuse the first connections; within
+	// --grace-period those are retried and only the last attempt counts
+	var graceRetries int
+	for lt.inGracePeriod() && refusedOrReset(result) {
+		wait := retryDelay(graceRetries+1, graceBackoff, graceMaxBackoff, 1, rng)
+		logger.Debug("retrying within grace period",
+			"method", method,
+			"attempt", graceRetries+1,
+			"wait", wait,
+			"category", errorCategory(result))
+		if !lt.waitBackoff(wait) {
+			break
+		}
+		graceRetries++
+		result = lt.sendRequest(method, rng)
+	}
+	var retries int
+	var backoff time.Duration
+	for retries < lt.config.Retries && lt.retryable(result) {
+		wait := retryDelay(retries+1, lt.config.RetryBackoff, lt.config.RetryMaxBackoff, lt.config.RetryJitter, rng)
+		logger.Debug("retrying request",
+			"method", method,
+			"attempt", retries+1,
+			"wait", wait,
+			"status", result.StatusCode,
+			"category", errorCategory(result))
+		if !lt.waitBackoff(wait) {
+			break
+		}
+		retries++
+		backoff += wait
+		result = lt.sendRequest(method, rng)
+	}
+	result.Retries = retries
+	result.Backoff = backoff
+	result.GraceRetries = graceRetries
+	result.Method = method
+	return result
+}
 
+func (lt *LoadTester) sendRequest(method string, rng *rand.Rand) Result {
 	var bodyReader io.Reader
-	if lt.config.Body != "" {
-		bodyReader = strings.NewReader(lt.config.Body)
+	var body *requestBody
+	switch {
+	case len(lt.config.MethodMix) > 0 && !methodSendsBody(method):
+	case lt.bodyTemplate != nil:
+		// Rendered before the clock starts so it doesn't count as latency
+		rendered, err := lt.bodyTemplate.render(rng)
+		if err != nil {
+			return Result{Error: fmt.Errorf("rendering body template: %v", err), Timestamp: time.Now()}
+		}
+		bodyReader = bytes.NewReader(rendered)
+	case lt.config.RandomBodySize > 0 && lt.config.RandomBodyEach:
+		// Generated before the clock starts so it doesn't count as latency
+		bodyReader = bytes.NewReader(randomBody(lt.config.RandomBodySize, rng))
+	case lt.randomBody != nil:
+		bodyReader = bytes.NewReader(lt.randomBody)
+	case lt.config.Body != "":
+		body = lt.acquireBody(lt.config.Body)
+		defer lt.releaseBody(body)
+		bodyReader = body
 	}
 
-	req, err := http.NewRequest(lt.config.Method, lt.config.URL, bodyReader)
-	if err != nil {
-		return Result{Error: err, ResponseTime: time.Since(start), Timestamp: time.Now()}
+	// Chaos delays come before the clock starts, so they don't count as latency
+	chaos := lt.planChaos(rng)
+	if chaos.delay > 0 {
+		lt.chaosDelayed.Add(1)
+		if !lt.waitBackoff(chaos.delay) {
+			return Result{Error: lt.ctx.Err(), Timestamp: time.Now()}
+		}
 	}
+	reqCtx := lt.ctx
+	var abort *chaosAborter
+	if chaos.abort {
+		abort = lt.newChaosAbort(lt.ctx, chaos)
+		defer abort.stop()
+		reqCtx = abort.ctx
+	}
+
+	start := time.Now()
 
-	// Add headers
-	for key, value := range lt.config.Headers {
-		req.Header.Set(key, value)
+	var firstByte, dnsStart time.Time
+	var dnsLookup time.Duration
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsLookup = time.Since(dnsStart) },
+		WroteHeaders:         abort.wroteHeaders,
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	if body != nil {
+		trace.WroteRequest = body.wroteRequest
 	}
+	ctx := httptrace.WithClientTrace(reqCtx, trace)
 
-	// Set default User-Agent if not provided
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", "Go Brutal/1.0")
+	req, err := http.NewRequestWithContext(ctx, method, lt.config.URL, bodyReader)
+	if err != nil {
+		return Result{Error: err, ResponseTime: time.Since(start), Timestamp: time.Now()}
+	}
+	if body != nil {
+		// NewRequest only knows the length and how to re-read the body for
+		// its own reader types
+		req.ContentLength = int64(len(body.text))
+		req.GetBody = body.getBody
+	}
+	lt.setHeaders(req)
+	lt.setConditionalHeaders(req)
+	requestID := lt.setRequestID(req)
+	traceID, spanID := lt.setTraceContext(req)
+	var requestBytes, headerBytes int64
+	if lt.config.CountHeaders {
+		requestBytes = requestSize(req)
 	}
 
 	resp, err := lt.httpClient.Do(req)
-	responseTime := time.Since(start)
+	headerTime := time.Since(start)
 
 	if err != nil {
-		return Result{Error: err, ResponseTime: responseTime, Timestamp: time.Now()}
+		return Result{Error: abort.error(err), ResponseTime: headerTime, Timestamp: time.Now(),
+			RequestID: requestID, TraceID: traceID, SpanID: spanID, Host: errorHost(err),
+			RequestBytes: requestBytes}
 	}
 	defer resp.Body.Close()
+	if abort != nil {
+		// The response beat the abort, so hang up before reading the body
+		abort.fire()
+		return Result{Error: errChaosAborted, ResponseTime: headerTime, Timestamp: time.Now(),
+			RequestID: requestID, TraceID: traceID, SpanID: spanID, Host: resp.Request.URL.Host,
+			RequestBytes: requestBytes}
+	}
+	if lt.config.CountHeaders {
+		headerBytes = responseHeaderSize(resp)
+	}
+
+	// Only successful bodies are compared; error pages differ for other reasons
+	var digest hash.Hash64
+	if lt.bodyDigests != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		digest = lt.bodyDigests.digest()
+	}
 
 	// Read response body to get content size
-	bodyBytes, err := io.ReadAll(resp.Body)
+	size, err := readBody(lt.ctx, resp.Body, digest)
+	lastByte := time.Now()
 	if err != nil {
 		return Result{
 			StatusCode:   resp.StatusCode,
-			ResponseTime: responseTime,
-			Error:        err,
+			ResponseTime: lastByte.Sub(start),
+			HeaderTime:   headerTime,
+			Error:        abort.error(err),
 			Timestamp:    time.Now(),
+			RequestID:    requestID,
+			TraceID:      traceID,
+			SpanID:       spanID,
+			Host:         resp.Request.URL.Host,
+			HeaderBytes:  headerBytes,
+			RequestBytes: requestBytes,
 		}
 	}
 
-	return Result{
+	var headers http.Header
+	if lt.captureHeaders {
+		headers = resp.Header
+	}
+
+	if resp.StatusCode == http.StatusOK && lt.config.IfNoneMatch == conditionalAuto {
+		lt.updateValidators(resp, size)
+	}
+
+	if lt.headerCapture != nil {
+		lt.headerCapture.record(resp.Header, lastByte)
+	}
+	if digest != nil {
+		lt.bodyDigests.record(digest, size)
+	}
+
+	var tracked []string
+	if len(lt.config.TrackHeaders) > 0 {
+		tracked = trackedHeaderValues(resp.Header, lt.config.TrackHeaders)
+	}
+
+	rateLimited, retryAfter, advertised := rateLimitResponse(resp, time.Now())
+	if !advertised {
+		retryAfter = -1
+	}
+
+	// Trailers are only populated once the body has been read to EOF
+	var trailers []string
+	for name := range resp.Trailer {
+		trailers = append(trailers, name)
+	}
+
+	result := Result{
 		StatusCode:   resp.StatusCode,
-		ResponseTime: responseTime,
-		ContentSize:  int64(len(bodyBytes)),
+		ResponseTime: lastByte.Sub(start),
+		HeaderTime:   headerTime,
+		ContentSize:  size,
 		Timestamp:    time.Now(),
+		Trailers:     trailers,
+		Headers:      headers,
+		TTFB:         firstByte.Sub(start),
+		DNSLookup:    dnsLookup,
+		TTLB:         lastByte.Sub(start),
+		RateLimited:  rateLimited,
+		RetryAfter:   retryAfter,
+
+		TrackedHeaders: tracked,
+		RequestID:      requestID,
+		TraceID:        traceID,
+		SpanID:         spanID,
+		Host:           resp.Request.URL.Host,
+		HeaderBytes:    headerBytes,
+		RequestBytes:   requestBytes,
+		ServerTiming:   serverTimings(resp.Header, resp.Trailer),
+	}
+	if resp.TLS != nil {
+		result.TLSVersion = resp.TLS.Version
+		result.TLSCipherSuite = resp.TLS.CipherSuite
+	}
+	return result
+}
+
+// newHeaderTemplate builds the headers every request starts from: the
+// configured ones plus the default User-Agent, canonicalized once up front
+func newHeaderTemplate(headers map[string]string) http.Header {
+	template := make(http.Header, len(headers)+1)
+	for key, value := range headers {
+		template.Set(key, value)
+	}
+	if template.Get("User-Agent") == "" {
+		template.Set("User-Agent", "Go Brutal/1.0")
+	}
+	return template
+}
+
+// setHeaders applies the configured headers, virtual host, bearer token and
+// default User-Agent to req
+func (lt *LoadTester) setHeaders(req *http.Request) {
+	// One clone copies every value in a single allocation, where setting the
+	// headers one by one would canonicalize and allocate each of them
+	req.Header = lt.headerTemplate.Clone()
+
+	// net/http ignores a "Host" entry in req.Header and sends req.Host instead,
+	// so the virtual host has to be set on the request itself
+	if lt.config.Host != "" {
+		req.Host = lt.config.Host
+	}
+
+	if lt.bearer != nil {
+		req.Header.Set("Authorization", "Bearer "+lt.bearer.current())
+	}
+}
+
+// setRequestID sends a fresh UUID in every --request-id-header header and
+// returns it, or returns "" when none are configured. The id comes from the
+// unseeded source: a replay with the same --seed must not reuse idempotency keys.
+func (lt *LoadTester) setRequestID(req *http.Request) string {
+	if len(lt.config.RequestIDHeaders) == 0 {
+		return ""
 	}
+	id := newUUID(globalRand)
+	for _, name := range lt.config.RequestIDHeaders {
+		req.Header.Set(name, id)
+	}
+	return id
+}
+
+// noteRateLimited logs the first rate-limited response of the run and, with
+// --honor-retry-after, pauses new requests for the advertised backoff
+func (lt *LoadTester) noteRateLimited(result Result) {
+	if lt.rateLimitSeen.CompareAndSwap(false, true) {
+		logger.Warn("target is rate limiting",
+			"seq", result.Seq,
+			"status", result.StatusCode,
+			"retry_after", result.RetryAfter)
+	}
+	if lt.config.HonorRetryAfter && result.RetryAfter > 0 {
+		lt.retryAfter.extend(result.Timestamp.Add(result.RetryAfter))
+	}
+}
+
+// errCanceledDuringRead marks responses whose body read was cut off because the
+// run was stopped
+var errCanceledDuringRead = errors.New("canceled during read")
+
+// execute performs one unit of work: a request, a bare connection in
+// connect-only mode or an event stream with --sse
+func (lt *LoadTester) execute(rng *rand.Rand) Result {
+	if lt.config.ConnectOnly {
+		return lt.makeConnection()
+	}
+	if lt.config.SSE {
+		return lt.openStream()
+	}
+	return lt.makeRequest(rng)
+}
+
+// Close releases the connections kept open between requests
+func (lt *LoadTester) Close() {
+	if lt.prewarmed != nil {
+		lt.prewarmed.closeAll()
+	}
+	lt.httpClient.CloseIdleConnections()
 }
 
 // Run executes the load test
 func (lt *LoadTester) Run(progressCallback func(completed, total int)) *Stats {
 	startTime := time.Now()
+	lt.startTime = startTime
+	lt.live.Start(lt.config.Requests)
+	defer lt.live.Stop()
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, lt.config.Concurrent)
+
+	// runCtx ends the run at --max-duration or an interrupt. Requests use
+	// lt.ctx, which is the same unless --drain-timeout gives those in flight
+	// longer to finish.
+	runCtx := context.Background()
+	if lt.interrupt != nil {
+		runCtx = lt.interrupt
+	}
+	if lt.config.MaxDuration > 0 {
+		ctx, cancel := context.WithTimeout(runCtx, lt.config.MaxDuration)
+		defer cancel()
+		runCtx = ctx
+	}
+	lt.ctx = runCtx
+	var cancelRequests context.CancelFunc
+	var drainOnce sync.Once
+	var drainTimer atomic.Pointer[time.Timer]
+	// startDrain cancels the requests still in flight --drain-timeout after delay
+	startDrain := func(delay time.Duration) {
+		drainOnce.Do(func() { drainTimer.Store(time.AfterFunc(max(delay, 0)+lt.config.DrainTimeout, cancelRequests)) })
+	}
+	if lt.config.DrainTimeout > 0 {
+		lt.ctx, cancelRequests = context.WithCancel(context.Background())
+		defer cancelRequests()
+		// A run whose requests all finished in time leaves nothing to cancel
+		defer func() {
+			if t := drainTimer.Load(); t != nil {
+				t.Stop()
+			}
+		}()
+		// The dispatch loop can be stuck waiting for a free slot when
+		// --max-duration passes, so the drain starts from the deadline itself
+		stop := context.AfterFunc(runCtx, func() { startDrain(0) })
+		defer stop()
+	}
 
 	completed := 0
 	progressMu := sync.Mutex{}
 
-	for i := 0; i < lt.config.Requests; i++ {
+	if lt.pacer != nil {
+		lt.pacer.run()
+		defer lt.pacer.close()
+	}
+
+	// Dispatching stops at the start of the cooldown, while requests already
+	// in flight may finish until the end of the run
+	dispatch := runCtx
+	cooldownAt := lt.cooldownStart(startTime)
+	if !cooldownAt.IsZero() {
+		ctx, cancel := context.WithDeadline(runCtx, cooldownAt)
+		defer cancel()
+		dispatch = ctx
+	}
+
+	profileFinished := false
+	dispatched := 0
+	for i := 0; lt.config.Requests == 0 || i < lt.config.Requests; i++ {
+		var scheduled time.Duration
+		if lt.pacer != nil {
+			at, ok := lt.pacer.wait(dispatch.Done())
+			if !ok {
+				profileFinished = dispatch.Err() == nil
+				break
+			}
+			scheduled = at.Sub(startTime)
+		}
+		if !lt.rateCap.wait(dispatch.Done()) {
+			break
+		}
+		if lt.config.HonorRetryAfter && !lt.retryAfter.wait(dispatch.Done()) {
+			break
+		}
+		if !lt.pause.wait(dispatch.Done()) {
+			break
+		}
+		worker := lt.limiter.acquire()
+		if dispatch.Err() != nil {
+			lt.limiter.release(worker)
+			break
+		}
 		wg.Add(1)
-		semaphore <- struct{}{}
+		dispatched++
 
-		go func() {
+		go func(seq int, rng *rand.Rand) {
 			defer wg.Done()
-			defer func() { <-semaphore }()
+			defer lt.limiter.release(worker)
+
+			// The slot is held while it waits its turn, like a user who
+			// can't click faster than the cap
+			if lt.workerPacer != nil && !lt.workerPacer.wait(worker, dispatch.Done()) {
+				return
+			}
 
-			result := lt.makeRequest()
+			started := lt.inFlight.enter()
+			result := lt.execute(rng)
+			lt.inFlight.exit(started)
+			// Think time keeps the slot busy, as a user would before the
+			// next click; once dispatching has stopped there is no next click
+			if think := lt.thinkTime(started.Sub(startTime), rng); think > 0 {
+				select {
+				case <-time.After(think):
+				case <-dispatch.Done():
+				}
+			}
+			result.StartTime = started
+			result.ScheduledAt = scheduled
+			result.Seq = seq
+			result.WorkerID = worker
+			// Requests cut off by --max-duration or at the end of
+			// --drain-timeout never completed, so they aren't counted as failures
+			if result.Error != nil && lt.ctx.Err() != nil {
+				if cancelRequests != nil {
+					lt.drainCanceled.Add(1)
+					logger.Debug("request canceled at drain",
+						"seq", result.Seq,
+						"method", result.Method,
+						"response_time", result.ResponseTime)
+					return
+				}
+				logger.Debug("request cut off by max duration",
+					"seq", result.Seq,
+					"method", result.Method,
+					"category", errorCategory(result))
+				return
+			}
+			// Aborts the client chose aren't the server's failures
+			if errors.Is(result.Error, errChaosAborted) {
+				lt.chaosAborted.Add(1)
+				logger.Debug("request aborted by chaos",
+					"seq", result.Seq,
+					"method", result.Method,
+					"response_time", result.ResponseTime)
+				return
+			}
+			if !lt.isSuccess(result) {
+				logger.Debug("request failed",
+					"seq", result.Seq,
+					"request_id", result.RequestID,
+					"worker", result.WorkerID,
+					"method", result.Method,
+					"status", result.StatusCode,
+					"error", errorMessage(result.Error),
+					"category", errorCategory(result),
+					"response_time", result.ResponseTime)
+			}
+
+			if result.RateLimited {
+				lt.noteRateLimited(result)
+			}
 
 			lt.mu.Lock()
-			lt.results = append(lt.results, result)
+			if !cooldownAt.IsZero() && result.Timestamp.After(cooldownAt) {
+				lt.cooldownResults = append(lt.cooldownResults, result)
+			} else {
+				lt.results = append(lt.results, result)
+			}
 			lt.mu.Unlock()
+			lt.live.Record(result, lt.isSuccess(result))
+			lt.noteRecent(result)
+			if lt.otel != nil {
+				lt.otel.add(lt.newSpan(result, lt.isSuccess(result)))
+			}
+			if lt.trace != nil {
+				lt.trace(result)
+			}
 
 			progressMu.Lock()
 			completed++
@@ -209,13 +1105,186 @@ func (lt *LoadTester) Run(progressCallback func(completed, total int)) *Stats {
 			if progressCallback != nil {
 				progressCallback(currentCompleted, lt.config.Requests)
 			}
-		}()
+		}(i+1, lt.requestRand(i))
 	}
 
+	// Requests still in flight when the run ends get --drain-timeout to finish.
+	// With a cooldown the run ends after it rather than when dispatching stops.
+	if cancelRequests != nil {
+		end := time.Now()
+		if !cooldownAt.IsZero() {
+			end = cooldownAt.Add(lt.config.Cooldown)
+		}
+		startDrain(time.Until(end))
+	}
 	wg.Wait()
-	totalTime := time.Since(startTime)
+	lt.endTime = time.Now()
+	totalTime := lt.endTime.Sub(startTime)
+	// Rates cover the time before the cooldown, the same span as the results
+	if !cooldownAt.IsZero() && lt.endTime.After(cooldownAt) {
+		totalTime = cooldownAt.Sub(startTime)
+	}
+
+	if lt.prewarmed != nil {
+		lt.prewarmed.closeAll()
+	}
+
+	stats := lt.calculateStats(totalTime)
+	stats.PlannedRequests = lt.config.Requests
+	stats.AvgInFlight = lt.inFlight.average(totalTime)
+	stats.PeakInFlight = int(lt.inFlight.peak.Load())
+	if lt.config.Concurrent > 0 {
+		stats.Utilization = stats.AvgInFlight / float64(lt.config.Concurrent)
+		stats.PeakUtilization = float64(stats.PeakInFlight) / float64(lt.config.Concurrent)
+	}
+	stats.Interrupted = lt.interrupted()
+	stats.MaxDurationReached = runCtx.Err() != nil && !stats.Interrupted
+	stats.ProfileFinished = profileFinished
+	stats.CanceledAtDrain = int(lt.drainCanceled.Load())
+	stats.ChaosAborted = int(lt.chaosAborted.Load())
+	stats.ChaosDelayed = int(lt.chaosDelayed.Load())
+	if len(lt.config.Phases) > 0 {
+		stats.Phases = buildPhaseStats(lt.results, lt.config.Phases, startTime, lt.isSuccess)
+	}
+	if lt.config.Cooldown > 0 {
+		stats.Cooldown = buildCooldownStats(lt.cooldownResults, lt.config.Cooldown, lt.isSuccess)
+		stats.Cooldown.Stopped = !stats.MaxDurationReached && !profileFinished &&
+			stats.TotalRequests+stats.Cooldown.Requests < stats.PlannedRequests
+	}
+	if lt.workerPacer != nil {
+		stats.Workers = buildWorkerStats(lt.results)
+	}
+	if lt.pacer != nil {
+		stats.Queue = buildQueueStats(lt.results, startTime)
+		lt.addBacklog(stats.Timeline, startTime)
+		stats.Saturation = findSaturation(stats.Timeline, startTime)
+	}
+	if lt.sse != nil {
+		stats.SSE = lt.buildSSEStats(totalTime)
+	}
+	stats.StoppedBy = stats.stopReason()
+	if stats.PlannedRequests > 0 {
+		stats.SkippedRequests = stats.PlannedRequests - dispatched
+	}
+	if stats.MaxDurationReached {
+		logger.Warn("max duration reached, run stopped",
+			"max_duration", lt.config.MaxDuration,
+			"completed", stats.TotalRequests,
+			"planned", stats.PlannedRequests)
+	}
+	return stats
+}
+
+// formatStatusCodes lists the responses by status code in ascending order,
+// then the requests that got no response, each with its share of all requests
+func formatStatusCodes(stats *Stats) []string {
+	codes := make([]int, 0, len(stats.StatusCodes))
+	for code := range stats.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	share := func(n int) float64 {
+		return float64(n) / float64(stats.TotalRequests) * 100
+	}
+	lines := make([]string, 0, len(codes)+1)
+	for _, code := range codes {
+		count := stats.StatusCodes[code]
+		lines = append(lines, fmt.Sprintf("%d: %d (%.1f%%)", code, count, share(count)))
+	}
+	if stats.ErrorCount > 0 {
+		lines = append(lines, fmt.Sprintf("No response: %d (%.1f%%)", stats.ErrorCount, share(stats.ErrorCount)))
+	}
+	return lines
+}
+
+// reportedPercentiles are the percentiles included in every latency summary
+var reportedPercentiles = []int{50, 95, 99}
+
+// percentilesOf sorts durations and returns their reportedPercentiles, or nil when empty
+func percentilesOf(durations []time.Duration) map[int]time.Duration {
+	if len(durations) == 0 {
+		return nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentiles := make(map[int]time.Duration)
+	for _, p := range reportedPercentiles {
+		percentiles[p] = percentile(durations, p)
+	}
+	return percentiles
+}
+
+// percentile returns the p-th percentile of an ascending slice using the nearest-rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// Reset clears the results and everything else accumulated by Run so the
+// tester can run the same test again. Concurrency returns to the configured
+// value. Captured cache validators, a pause from the keyboard and a --control
+// rate cap are kept.
+// It must not be called while Run is in progress.
+func (lt *LoadTester) Reset() {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.results = make([]Result, 0)
+	lt.cooldownResults = nil
+	lt.concurrencyChanges = nil
+	lt.startTime = time.Time{}
+	lt.endTime = time.Time{}
+	lt.ctx = context.Background()
+	lt.limiter.setLimit(lt.config.Concurrent)
+	lt.inFlight.reset()
+	lt.retryAfter.until.Store(0)
+	lt.rateLimitSeen.Store(false)
+	lt.drainCanceled.Store(0)
+	lt.chaosAborted.Store(0)
+	lt.chaosDelayed.Store(0)
+	if lt.pacer != nil {
+		lt.pacer.reset()
+	}
+	if lt.workerPacer != nil {
+		lt.workerPacer.reset()
+	}
+	lt.rateCap.reset()
+	if lt.sse != nil {
+		lt.sse.reset()
+	}
+	if lt.headerCapture != nil {
+		lt.headerCapture = newHeaderCapture(lt.config.CaptureHeaders)
+	}
+	if lt.bodyDigests != nil {
+		lt.bodyDigests = newBodyDigests()
+	}
+}
+
+// Live returns the statistics of the run in progress
+func (lt *LoadTester) Live() *LiveStats {
+	return lt.live
+}
 
-	return lt.calculateStats(totalTime)
+// isSuccess reports whether a result counts as successful: no error and a 2xx
+// status code. Connect-only results have no status code, so an established
+// connection is a success.
+func (lt *LoadTester) isSuccess(result Result) bool {
+	if result.Error != nil {
+		return false
+	}
+	if lt.config.IfNoneMatch != "" && result.StatusCode == http.StatusNotModified {
+		return true
+	}
+	return lt.config.ConnectOnly || result.StatusCode >= 200 && result.StatusCode < 300
 }
 
 // calculateStats computes statistics from results
@@ -226,33 +1295,92 @@ func (lt *LoadTester) calculateStats(totalTime time.Duration) *Stats {
 	stats := &Stats{
 		TotalRequests: len(lt.results),
 		StatusCodes:   make(map[int]int),
+		Trailers:      make(map[string]int),
 		Percentiles:   make(map[int]time.Duration),
 		TotalTime:     totalTime,
+		ConnectOnly:   lt.config.ConnectOnly,
 	}
 
 	var responseTimes []time.Duration
+	var handshakeTimes []time.Duration
+	var ttfbTimes, ttlbTimes, transferTimes, dnsTimes, headerTimes []time.Duration
+	var throughputs []float64
 	var totalBytes int64
+	errorGroups := newErrorGroups()
+	if lt.config.ApdexT > 0 {
+		stats.Apdex = &Apdex{T: lt.config.ApdexT}
+	}
 
 	for _, result := range lt.results {
-		// Count as successful if no error and status code indicates success (2xx)
-		if result.Error == nil && result.StatusCode >= 200 && result.StatusCode < 300 {
+		if result.TLSHandshake > 0 {
+			handshakeTimes = append(handshakeTimes, result.TLSHandshake)
+		}
+		if result.DNSLookup > 0 {
+			dnsTimes = append(dnsTimes, result.DNSLookup)
+		}
+		if result.HeaderTime > 0 {
+			headerTimes = append(headerTimes, result.HeaderTime)
+		}
+		if result.TTLB > 0 {
+			ttfbTimes = append(ttfbTimes, result.TTFB)
+			ttlbTimes = append(ttlbTimes, result.TTLB)
+			transfer := result.TTLB - result.TTFB
+			transferTimes = append(transferTimes, transfer)
+			if transfer > 0 && result.ContentSize > 0 {
+				throughputs = append(throughputs, float64(result.ContentSize)/transfer.Seconds())
+			}
+		}
+
+		if lt.isSuccess(result) {
 			stats.SuccessfulReqs++
 		} else {
 			stats.FailedReqs++
 		}
+		if stats.Apdex != nil {
+			stats.Apdex.add(result.ResponseTime, lt.isSuccess(result))
+		}
 
 		// Count data transfer for all responses that have content size (even failed requests)
 		// This includes 4xx, 5xx responses that often have error message bodies
 		if result.ContentSize > 0 {
 			totalBytes += result.ContentSize
 		}
+		stats.HeaderBytes += result.HeaderBytes
+		stats.SentBytes += result.RequestBytes
 
 		responseTimes = append(responseTimes, result.ResponseTime)
-		stats.StatusCodes[result.StatusCode]++
+		if result.StatusCode > 0 {
+			stats.StatusCodes[result.StatusCode]++
+		} else if !lt.config.ConnectOnly {
+			stats.ErrorCount++
+		}
+		if result.Error != nil {
+			errorGroups.add(result.Error.Error(), result.Timestamp)
+		}
+		if len(result.Trailers) > 0 {
+			stats.ResponsesWithTrailers++
+			for _, name := range result.Trailers {
+				stats.Trailers[name]++
+			}
+		}
 	}
 
 	stats.TotalBytes = totalBytes
 	stats.ResponseTimes = responseTimes
+	stats.RateLimited = buildRateLimitStats(lt.results)
+	stats.Timeouts = buildTimeoutStats(lt.results, lt.isSuccess)
+	stats.Retries = buildRetryStats(lt.results, lt.isSuccess)
+	stats.Grace = buildGraceStats(lt.results, lt.isSuccess)
+	stats.ServerTiming = buildServerTimingStats(lt.results)
+	stats.TLS = buildTLSParams(lt.results)
+	if lt.config.IfNoneMatch != "" {
+		stats.Conditional = lt.buildConditionalStats()
+	}
+	if stats.Apdex != nil {
+		stats.Apdex.finish()
+	}
+	stats.ErrorGroups = errorGroups.sorted()
+	stats.ErrorsOmitted = errorGroups.omitted
 
 	if len(responseTimes) > 0 {
 		sort.Slice(responseTimes, func(i, j int) bool {
@@ -269,30 +1397,60 @@ func (lt *LoadTester) calculateStats(totalTime time.Duration) *Stats {
 		stats.AvgResponseTime = total / time.Duration(len(responseTimes))
 
 		// Calculate percentiles
-		percentiles := []int{50, 95, 99}
-		for _, p := range percentiles {
-			index := int(math.Ceil(float64(p)/100*float64(len(responseTimes)))) - 1
-			if index < 0 {
-				index = 0
-			}
-			if index >= len(responseTimes) {
-				index = len(responseTimes) - 1
-			}
-			stats.Percentiles[p] = responseTimes[index]
+		for _, p := range reportedPercentiles {
+			stats.Percentiles[p] = percentile(responseTimes, p)
 		}
 	}
 
+	stats.HandshakePercentiles = percentilesOf(handshakeTimes)
+	stats.TTFBPercentiles = percentilesOf(ttfbTimes)
+	stats.TTLBPercentiles = percentilesOf(ttlbTimes)
+	stats.TransferPercentiles = percentilesOf(transferTimes)
+	stats.HeaderPercentiles = percentilesOf(headerTimes)
+	if len(throughputs) > 0 {
+		sort.Float64s(throughputs)
+		stats.MedianThroughput = throughputs[len(throughputs)/2]
+	}
+	stats.DNSPercentiles = percentilesOf(dnsTimes)
+
 	if totalTime.Seconds() > 0 {
 		stats.RequestsPerSec = float64(stats.TotalRequests) / totalTime.Seconds()
 	}
 
+	stats.Timeline, stats.Degradation = buildTimeline(lt.results, lt.startTime, lt.isSuccess)
+	stats.Outliers = buildOutliers(lt.results, stats.Percentiles[99], stats.Timeline, lt.startTime)
+	if target := targetHost(lt.config.URL); target != "" && !lt.config.ConnectOnly {
+		stats.Hosts = buildHostStats(lt.results, target, lt.isSuccess)
+		if stats.Hosts != nil {
+			stats.TargetHost = target
+		}
+	}
+	if len(lt.config.MethodMix) > 0 {
+		stats.Methods = buildMethodStats(lt.results, lt.isSuccess)
+	}
+	if len(lt.config.TrackHeaders) > 0 {
+		stats.HeaderBreakdowns = buildHeaderBreakdowns(lt.results, lt.config.TrackHeaders)
+	}
+	if lt.headerCapture != nil {
+		stats.CapturedHeaders = lt.headerCapture.summary()
+	}
+	if lt.bodyDigests != nil {
+		stats.BodyConsistency = lt.bodyDigests.summary()
+	}
+	stats.ConcurrencyChanges = append([]ConcurrencyChange(nil), lt.concurrencyChanges...)
+
 	return stats
 }
 
 // SaveResultsToJSON saves results to a JSON file
 func (lt *LoadTester) SaveResultsToJSON(filename string, stats *Stats) error {
+	config, err := redactedConfigJSON(lt.config)
+	if err != nil {
+		return err
+	}
 	data := map[string]interface{}{
-		"config":             lt.config,
+		"metadata":           lt.metadata(),
+		"config":             config,
 		"stats":              stats,
 		"individual_results": lt.results,
 	}
@@ -305,18 +1463,127 @@ func (lt *LoadTester) SaveResultsToJSON(filename string, stats *Stats) error {
 	return os.WriteFile(filename, jsonData, 0644)
 }
 
+// WriteJSON writes the configuration and summary statistics to w. Unlike
+// SaveResultsToJSON it leaves out the individual results, which can be large.
+func (lt *LoadTester) WriteJSON(w io.Writer, stats *Stats) error {
+	config, err := redactedConfigJSON(lt.config)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"metadata": lt.metadata(),
+		"config":   config,
+		"stats":    stats,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// Final summary formats selectable with --format
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// completionLine reports how much of the planned run completed, optionally with its duration
+func completionLine(stats *Stats, withTime bool) string {
+	line := fmt.Sprintf("Completed: %d", stats.TotalRequests)
+	if stats.PlannedRequests > 0 {
+		line += fmt.Sprintf("/%d (%.1f%%)", stats.PlannedRequests,
+			float64(stats.TotalRequests)/float64(stats.PlannedRequests)*100)
+	}
+	if withTime {
+		line += fmt.Sprintf(" in %v", stats.TotalTime.Round(time.Millisecond))
+	}
+	if reason := stats.stopReason(); reason != "" {
+		line += " - stopped by " + reason
+	}
+	return line
+}
+
+// stopReason names what ended the run before all planned requests were sent,
+// if anything. A run without a request limit is meant to end at its time
+// limit, so only an interrupt stops it short.
+func (s *Stats) stopReason() string {
+	switch {
+	case s.Interrupted:
+		return "an interrupt"
+	case s.PlannedRequests == 0:
+		return ""
+	case s.MaxDurationReached:
+		return "--max-duration"
+	case s.ProfileFinished && len(s.Phases) > 0:
+		return "the end of --phases"
+	case s.ProfileFinished:
+		return "the end of --profile"
+	case s.Cooldown != nil && s.Cooldown.Stopped:
+		return "--cooldown"
+	}
+	return ""
+}
+
+// summaryLine renders the results as a single line of key=value pairs for scripts
+func summaryLine(stats *Stats) string {
+	line := fmt.Sprintf("requests=%d successful=%d failed=%d duration=%v rps=%.2f",
+		stats.TotalRequests, stats.SuccessfulReqs, stats.FailedReqs,
+		stats.TotalTime.Round(time.Millisecond), stats.RequestsPerSec)
+	for _, p := range reportedPercentiles {
+		line += fmt.Sprintf(" p%d=%v", p, stats.Percentiles[p])
+	}
+	if stats.Apdex != nil {
+		line += fmt.Sprintf(" apdex=%.2f", stats.Apdex.Score)
+	}
+	return line
+}
+
+// maxErrorMessages is how many distinct error messages the summary lists
+const maxErrorMessages = 10
+
 func printStats(stats *Stats) {
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println("LOAD TEST RESULTS")
 	fmt.Println(strings.Repeat("=", 60))
-	fmt.Printf("Total Requests: %d\n", stats.TotalRequests)
-	fmt.Printf("Successful: %d (%.2f%%)\n", stats.SuccessfulReqs, float64(stats.SuccessfulReqs)/float64(stats.TotalRequests)*100)
-	fmt.Printf("Failed: %d (%.2f%%)\n", stats.FailedReqs, float64(stats.FailedReqs)/float64(stats.TotalRequests)*100)
+	if reason := stats.stopReason(); reason != "" {
+		fmt.Printf("Total Requests: %d of %d planned (stopped by %s, %d never sent)\n",
+			stats.TotalRequests, stats.PlannedRequests, reason, stats.SkippedRequests)
+	} else {
+		fmt.Printf("Total Requests: %d\n", stats.TotalRequests)
+	}
+	if stats.CanceledAtDrain > 0 {
+		fmt.Printf("Canceled at drain: %d (still in flight after --drain-timeout, not counted)\n", stats.CanceledAtDrain)
+	}
+	if stats.ChaosAborted > 0 {
+		fmt.Printf("Aborted by chaos: %d (canceled by --chaos-abort, not counted)\n", stats.ChaosAborted)
+	}
+	if stats.ChaosDelayed > 0 {
+		fmt.Printf("Delayed by chaos: %d (held back by --chaos-delay before sending)\n", stats.ChaosDelayed)
+	}
+	// A run stopped before anything completed has no share to show
+	if stats.TotalRequests > 0 {
+		fmt.Printf("Successful: %d (%.2f%%)\n", stats.SuccessfulReqs, float64(stats.SuccessfulReqs)/float64(stats.TotalRequests)*100)
+		fmt.Printf("Failed: %d (%.2f%%)\n", stats.FailedReqs, float64(stats.FailedReqs)/float64(stats.TotalRequests)*100)
+	} else {
+		fmt.Println("Successful: 0")
+		fmt.Println("Failed: 0")
+	}
 	fmt.Printf("Total Time: %v\n", stats.TotalTime)
-	fmt.Printf("Requests/sec: %.2f\n", stats.RequestsPerSec)
+	if stats.ConnectOnly {
+		fmt.Printf("Connections/sec: %.2f\n", stats.RequestsPerSec)
+	} else {
+		fmt.Printf("Requests/sec: %.2f\n", stats.RequestsPerSec)
+	}
+	fmt.Printf("In Flight: %.1f avg, %d peak (%.0f%% / %.0f%% of the concurrency)\n", stats.AvgInFlight,
+		stats.PeakInFlight, stats.Utilization*100, stats.PeakUtilization*100)
+	if stats.Apdex != nil {
+		fmt.Printf("Apdex: %v\n", stats.Apdex)
+	}
 
 	// Enhanced data transfer display
-	if stats.TotalBytes > 0 {
+	if stats.ConnectOnly {
+		// No request is sent, so there is no data transfer to report
+	} else if stats.TotalBytes > 0 {
 		if stats.TotalBytes < 1024 {
 			fmt.Printf("Data Transfer: %d bytes", stats.TotalBytes)
 		} else if stats.TotalBytes < 1024*1024 {
@@ -337,9 +1604,27 @@ func printStats(stats *Stats) {
 	} else {
 		fmt.Printf("Data Transfer: 0 bytes\n")
 	}
+	if stats.SentBytes > 0 {
+		fmt.Printf("Response Headers: %s (%s/req, estimated)\n", formatBytes(stats.HeaderBytes),
+			formatBytes(stats.HeaderBytes/int64(stats.TotalRequests)))
+		fmt.Printf("Sent: %s (%s/req, estimated)\n", formatBytes(stats.SentBytes),
+			formatBytes(stats.SentBytes/int64(stats.TotalRequests)))
+		if seconds := stats.TotalTime.Seconds(); seconds > 0 {
+			in := stats.TotalBytes + stats.HeaderBytes
+			fmt.Printf("Wire Throughput: %s/s in, %s/s out\n", formatBytes(int64(float64(in)/seconds)),
+				formatBytes(int64(float64(stats.SentBytes)/seconds)))
+		}
+	}
 
 	fmt.Println(strings.Repeat("-", 40))
-	fmt.Println("RESPONSE TIMES")
+	switch {
+	case stats.ConnectOnly:
+		fmt.Println("CONNECT TIMES")
+	case stats.SSE != nil:
+		fmt.Println("STREAM DURATIONS")
+	default:
+		fmt.Println("RESPONSE TIMES")
+	}
 	fmt.Println(strings.Repeat("-", 40))
 	fmt.Printf("Min: %v\n", stats.MinResponseTime)
 	fmt.Printf("Max: %v\n", stats.MaxResponseTime)
@@ -348,53 +1633,656 @@ func printStats(stats *Stats) {
 	for p, time := range stats.Percentiles {
 		fmt.Printf("%dth percentile: %v\n", p, time)
 	}
+	if len(stats.HeaderPercentiles) > 0 {
+		fmt.Printf("Headers only p50/p95/p99: %v/%v/%v (before reading the body, which the times above include)\n",
+			roundLatency(stats.HeaderPercentiles[50]), roundLatency(stats.HeaderPercentiles[95]),
+			roundLatency(stats.HeaderPercentiles[99]))
+	}
 
-	fmt.Println(strings.Repeat("-", 40))
-	fmt.Println("STATUS CODES")
-	fmt.Println(strings.Repeat("-", 40))
-	for code, count := range stats.StatusCodes {
-		percentage := float64(count) / float64(stats.TotalRequests) * 100
-		if code == 0 {
-			fmt.Printf("Errors: %d (%.1f%%)\n", count, percentage)
-		} else {
-			fmt.Printf("%d: %d (%.1f%%)\n", code, count, percentage)
+	if len(stats.HandshakePercentiles) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("TLS HANDSHAKE TIMES")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, p := range reportedPercentiles {
+			fmt.Printf("%dth percentile: %v\n", p, stats.HandshakePercentiles[p])
 		}
 	}
-	fmt.Println(strings.Repeat("=", 60))
-}
 
-func printBanner() {
-	if !noBanner {
-		fmt.Print(banner)
+	if len(stats.TLS) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("TLS")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, p := range stats.TLS {
+			fmt.Printf("%s %s: %d (%.1f%%)\n", p.Version, p.CipherSuite, p.Requests,
+				float64(p.Requests)/float64(stats.TotalRequests)*100)
+		}
 	}
-}
 
-func runLoadTest(cmd *cobra.Command, args []string) error {
+	if len(stats.TTLBPercentiles) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("FIRST / LAST BYTE")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("%-6s %-12s %-12s %s\n", "", "TTFB", "Transfer", "TTLB")
+		for _, p := range reportedPercentiles {
+			fmt.Printf("%-6s %-12v %-12v %v\n", fmt.Sprintf("p%d", p), roundLatency(stats.TTFBPercentiles[p]),
+				roundLatency(stats.TransferPercentiles[p]), roundLatency(stats.TTLBPercentiles[p]))
+		}
+		if stats.MedianThroughput > 0 {
+			fmt.Printf("Throughput per response: %s/s median\n", formatBytes(int64(stats.MedianThroughput)))
+		}
+	}
 
-	if targetURL == "" && len(args) == 0 {
-		return fmt.Errorf("URL is required")
+	if len(stats.DNSPercentiles) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("DNS LOOKUP")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, p := range reportedPercentiles {
+			fmt.Printf("%dth percentile: %v\n", p, stats.DNSPercentiles[p])
+		}
 	}
 
-	// Use URL from args if not provided via flag
-	if targetURL == "" && len(args) > 0 {
-		targetURL = args[0]
+	if !stats.ConnectOnly {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("STATUS CODES")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, line := range formatStatusCodes(stats) {
+			fmt.Println(line)
+		}
 	}
 
-	config := Config{
-		URL:         targetURL,
-		Method:      strings.ToUpper(method),
-		Concurrent:  concurrent,
+	for _, breakdown := range stats.HeaderBreakdowns {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("HEADER %s\n", breakdown.Header)
+		fmt.Println(strings.Repeat("-", 40))
+		for _, v := range breakdown.Values {
+			fmt.Printf("%-12s %d (%.1f%%) | p50: %v | p95: %v | p99: %v\n", v.Value, v.Requests,
+				float64(v.Requests)/float64(stats.TotalRequests)*100,
+				roundLatency(v.Percentiles[50]), roundLatency(v.Percentiles[95]), roundLatency(v.Percentiles[99]))
+		}
+	}
+
+	if len(stats.CapturedHeaders) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("RESPONSE HEADERS")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, h := range stats.CapturedHeaders {
+			if h.Mixed {
+				fmt.Printf("%s: MIXED, responses disagreed\n", h.Header)
+			} else {
+				fmt.Printf("%s:\n", h.Header)
+			}
+			for _, v := range h.Values {
+				fmt.Printf("  %s: %d (%s to %s)\n", v.Value, v.Count,
+					v.FirstSeen.Format("15:04:05"), v.LastSeen.Format("15:04:05"))
+			}
+			if h.Omitted > 0 {
+				fmt.Printf("  %d more with other values\n", h.Omitted)
+			}
+		}
+	}
+
+	if bc := stats.BodyConsistency; bc != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("BODY CONSISTENCY")
+		fmt.Println(strings.Repeat("-", 40))
+		switch bc.Variants {
+		case 0:
+			fmt.Println("No successful responses to compare")
+		case 1:
+			fmt.Println("All successful responses had the same body")
+		default:
+			fmt.Printf("%d different bodies for identical requests\n", bc.Variants)
+		}
+		if bc.Variants > 1 {
+			for _, v := range bc.Bodies {
+				fmt.Printf("  %s: %d (%s)\n", v.Digest, v.Count, formatBytes(v.Size))
+			}
+			if bc.Omitted > 0 {
+				fmt.Printf("  %d more responses with other bodies\n", bc.Omitted)
+			}
+		}
+	}
+
+	if c := stats.Conditional; c != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("CONDITIONAL REQUESTS")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("304 Not Modified: %d (%.1f%%) | avg: %v\n", c.NotModified,
+			float64(c.NotModified)/float64(stats.TotalRequests)*100, c.AvgNotModified)
+		fmt.Printf("200 Full:         %d (%.1f%%) | avg: %v\n", c.Full,
+			float64(c.Full)/float64(stats.TotalRequests)*100, c.AvgFull)
+		fmt.Printf("Bytes saved: %s\n", formatBytes(c.BytesSaved))
+	}
+
+	if rl := stats.RateLimited; rl != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("RATE LIMITED")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Responses: %d (%.1f%%), first at %s\n", rl.Responses,
+			float64(rl.Responses)/float64(stats.TotalRequests)*100, rl.FirstSeen.Format("15:04:05.000"))
+		if rl.WithRetryAfter > 0 {
+			fmt.Printf("Retry-After: %v to %v (sent on %d)\n", rl.MinRetryAfter, rl.MaxRetryAfter, rl.WithRetryAfter)
+		} else {
+			fmt.Printf("Retry-After: not sent\n")
+		}
+	}
+
+	if to := stats.Timeouts; to != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("TIMEOUTS")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Timed out: %d (%.1f%% of failures)\n", to.Count, to.Share*100)
+		fmt.Printf("Waited before giving up p50/p95/p99: %v/%v/%v (%v to %v)\n",
+			roundLatency(to.Waited[50]), roundLatency(to.Waited[95]), roundLatency(to.Waited[99]),
+			roundLatency(to.MinWaited), roundLatency(to.MaxWaited))
+		if to.SlowestSuccess > 0 {
+			fmt.Printf("Slowest success: %v\n", roundLatency(to.SlowestSuccess))
+		}
+	}
+
+	if g := stats.Grace; g != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("GRACE PERIOD")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Retried: %d refused or reset connections over %d requests\n", g.Retries, g.Requests)
+		fmt.Printf("Recovered: %d succeeded after retrying, %d still failed\n", g.Recovered, g.Requests-g.Recovered)
+	}
+
+	if r := stats.Retries; r != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("RETRIES")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Retried: %d requests (%.1f%%), %d retries\n", r.RetriedRequests,
+			float64(r.RetriedRequests)/float64(stats.TotalRequests)*100, r.Retries)
+		fmt.Printf("Recovered: %d succeeded after retrying, %d still failed\n", r.Recovered, r.RetriedRequests-r.Recovered)
+		fmt.Printf("Time in backoff: %v\n", r.Backoff.Round(time.Millisecond))
+	}
+
+	if w := stats.Workers; w != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("WORKERS")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Requests per worker: %d to %d, mean %.1f (CV %.1f%%) over %d workers\n",
+			w.Min, w.Max, w.Spread.Mean, w.Spread.CV*100, len(w.Requests))
+	}
+
+	if s := stats.SSE; s != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("EVENTS")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Events: %d over %d streams (%.1f/s) | per stream: %.1f mean (CV %.1f%%)\n",
+			s.Events, s.Streams, s.EventsPerSec, s.EventsPerStream.Mean, s.EventsPerStream.CV*100)
+		if len(s.GapPercentiles) > 0 {
+			fmt.Printf("Time between events p50/p95/p99: %v/%v/%v\n", roundLatency(s.GapPercentiles[50]),
+				roundLatency(s.GapPercentiles[95]), roundLatency(s.GapPercentiles[99]))
+		}
+		if len(s.FirstEventPercentiles) > 0 {
+			fmt.Printf("First event p50/p95/p99: %v/%v/%v\n", roundLatency(s.FirstEventPercentiles[50]),
+				roundLatency(s.FirstEventPercentiles[95]), roundLatency(s.FirstEventPercentiles[99]))
+		}
+	}
+
+	if q := stats.Queue; q != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("QUEUEING")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Queue delay p50/p95/p99: %v/%v/%v (max %v) | %d started %v+ late\n", roundLatency(q.Percentiles[50]),
+			roundLatency(q.Percentiles[95]), roundLatency(q.Percentiles[99]), roundLatency(q.Max), q.Late, queueDelayWarning)
+		if q.Percentiles[95] >= queueDelayWarning {
+			fmt.Println("Requests started behind schedule: the generator is the bottleneck, raise --concurrent")
+		}
+	}
+
+	if len(stats.Timeline) > 1 {
+		rps := make([]float64, len(stats.Timeline))
+		p95 := make([]float64, len(stats.Timeline))
+		inFlight := make([]float64, len(stats.Timeline))
+		var maxRPS int
+		var maxP95 time.Duration
+		var maxInFlight float64
+		for i, bucket := range stats.Timeline {
+			rps[i] = float64(bucket.Requests)
+			p95[i] = float64(bucket.P95)
+			inFlight[i] = bucket.InFlight
+			maxInFlight = max(maxInFlight, bucket.InFlight)
+			if bucket.Requests > maxRPS {
+				maxRPS = bucket.Requests
+			}
+			if bucket.P95 > maxP95 {
+				maxP95 = bucket.P95
+			}
+		}
+
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("TIMELINE (%ds)\n", len(stats.Timeline))
+		fmt.Println(strings.Repeat("-", 40))
+		// Leave room for the label and the max value on narrow terminals
+		width := sparklineWidth
+		if available := terminalWidth() - 24; available < width {
+			width = available
+		}
+		fmt.Printf("RPS %s (max %d)\n", sparkline(rps, width), maxRPS)
+		fmt.Printf("p95 %s (max %v)\n", sparkline(p95, width), maxP95)
+		fmt.Printf("act %s (max %.1f in flight)\n", sparkline(inFlight, width), maxInFlight)
+		if stats.Queue != nil {
+			backlog := make([]float64, len(stats.Timeline))
+			var maxBacklog int
+			for i, bucket := range stats.Timeline {
+				backlog[i] = float64(bucket.Backlog)
+				maxBacklog = max(maxBacklog, bucket.Backlog)
+			}
+			fmt.Printf("bkl %s (max %d scheduled, not completed)\n", sparkline(backlog, width), maxBacklog)
+		}
+		if s := stats.Saturation; s != nil {
+			fmt.Printf("Saturated from +%ds: the backlog grew every second from %d to %d requests\n", s.Second, s.From, s.To)
+		}
+		if d := stats.Degradation; d != nil {
+			for _, p := range reportedPercentiles {
+				fmt.Printf("p%d start to end: %v -> %v", p, roundLatency(d.Start[p]), roundLatency(d.End[p]))
+				if growth := d.Growth(p); growth > 0 {
+					fmt.Printf(" (%.1fx)", growth)
+				}
+				fmt.Println()
+			}
+		}
+	}
+
+	if o := stats.Outliers; o != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("OUTLIERS")
+		fmt.Println(strings.Repeat("-", 40))
+		if len(o.Requests) > 0 {
+			fmt.Printf("Requests over %v (%dx p99): %d\n", roundLatency(o.Threshold), outlierFactor, len(o.Requests)+o.Omitted)
+			for _, r := range o.Requests {
+				fmt.Printf("  %s #%d %v", r.Sent.Format("15:04:05.000"), r.Seq, roundLatency(r.ResponseTime))
+				if r.Error != "" {
+					fmt.Printf(" error: %s", r.Error)
+				} else {
+					fmt.Printf(" status %d", r.StatusCode)
+				}
+				if r.RequestID != "" {
+					fmt.Printf(" [%s]", r.RequestID)
+				}
+				fmt.Println()
+			}
+			if o.Omitted > 0 {
+				fmt.Printf("  ...and %d more\n", o.Omitted)
+			}
+		}
+		if len(o.Seconds) > 0 {
+			fmt.Printf("Seconds with over %.0f%% errors: %d\n", anomalousErrorRate*100, len(o.Seconds))
+			for _, s := range o.Seconds {
+				fmt.Printf("  %s (+%ds) %d of %d failed\n", s.At.Format("15:04:05"), s.Second, s.Errors, s.Requests)
+			}
+		}
+	}
+
+	if len(stats.Phases) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("PHASES")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, phase := range stats.Phases {
+			fmt.Printf("%-10s %v-%v | %d requests (%.1f/s) | failed: %d", phase.Name, phase.Start,
+				phase.Start+phase.Duration, phase.Requests, phase.RPS, phase.Failed)
+			if phase.Requests > 0 {
+				fmt.Printf(" | p50/p95/p99: %v/%v/%v", roundLatency(phase.Percentiles[50]),
+					roundLatency(phase.Percentiles[95]), roundLatency(phase.Percentiles[99]))
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(stats.Methods) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("METHODS")
+		fmt.Println(strings.Repeat("-", 40))
+		names := make([]string, 0, len(stats.Methods))
+		for name := range stats.Methods {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return stats.Methods[names[i]].Requests > stats.Methods[names[j]].Requests
+		})
+		for _, name := range names {
+			m := stats.Methods[name]
+			fmt.Printf("%-7s %d (%.1f%%) | failed: %d (%.1f%%) | avg: %v | p95: %v\n", name, m.Requests,
+				float64(m.Requests)/float64(stats.TotalRequests)*100, m.Failed,
+				float64(m.Failed)/float64(m.Requests)*100, m.AvgResponseTime, m.P95)
+		}
+	}
+
+	if len(stats.Hosts) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("HOSTS")
+		fmt.Println(strings.Repeat("-", 40))
+		names := make([]string, 0, len(stats.Hosts))
+		for name := range stats.Hosts {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return stats.Hosts[names[i]].Requests > stats.Hosts[names[j]].Requests
+		})
+		for _, name := range names {
+			h := stats.Hosts[name]
+			fmt.Printf("%s %d (%.1f%%) | failed: %d (%.1f%%) | p95: %v | %s\n", name, h.Requests,
+				float64(h.Requests)/float64(stats.TotalRequests)*100, h.Failed,
+				float64(h.Failed)/float64(h.Requests)*100, roundLatency(h.P95), formatBytes(h.Bytes))
+		}
+		if _, ok := stats.Hosts[stats.TargetHost]; !ok {
+			fmt.Printf("No response came from %s itself: every request was redirected, so test the final URL to load the origin directly\n", stats.TargetHost)
+		}
+	}
+
+	if stats.ResponsesWithTrailers > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("TRAILERS")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Responses with trailers: %d (%.1f%%)\n", stats.ResponsesWithTrailers,
+			float64(stats.ResponsesWithTrailers)/float64(stats.TotalRequests)*100)
+		names := make([]string, 0, len(stats.Trailers))
+		for name := range stats.Trailers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s: %d\n", name, stats.Trailers[name])
+		}
+	}
+
+	if len(stats.ServerTiming) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("SERVER TIMING")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("%-12s %d responses | p50/p95/p99: %v/%v/%v\n", "(client)", stats.TotalRequests,
+			roundLatency(stats.Percentiles[50]), roundLatency(stats.Percentiles[95]), roundLatency(stats.Percentiles[99]))
+		for _, m := range stats.ServerTiming {
+			fmt.Printf("%-12s %d responses | p50/p95/p99: %v/%v/%v\n", m.Name, m.Responses,
+				roundLatency(m.Percentiles[50]), roundLatency(m.Percentiles[95]), roundLatency(m.Percentiles[99]))
+		}
+	}
+
+	if len(stats.ConcurrencyChanges) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("CONCURRENCY CHANGES")
+		fmt.Println(strings.Repeat("-", 40))
+		for _, change := range stats.ConcurrencyChanges {
+			fmt.Printf("%s (+%v): %d -> %d\n", change.At.Format("15:04:05"), change.Elapsed.Round(time.Second), change.From, change.To)
+		}
+	}
+
+	if len(stats.ErrorGroups) > 0 {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("TOP ERRORS")
+		fmt.Println(strings.Repeat("-", 40))
+		shown := stats.ErrorGroups
+		if len(shown) > maxErrorMessages {
+			shown = shown[:maxErrorMessages]
+		}
+		for _, group := range shown {
+			fmt.Printf("%s x%d (last seen %s)\n", group.Message, group.Count, group.LastSeen.Format("15:04:05"))
+		}
+		if more := len(stats.ErrorGroups) - len(shown); more > 0 || stats.ErrorsOmitted > 0 {
+			fmt.Printf("...and %d more distinct errors", more)
+			if stats.ErrorsOmitted > 0 {
+				fmt.Printf(" (plus %d errors beyond the %d tracked messages)", stats.ErrorsOmitted, maxDistinctErrors)
+			}
+			fmt.Println()
+		}
+	}
+
+	if stats.SelfMetrics != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("CLIENT RESOURCES (PEAK)")
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Goroutines: %d\n", stats.SelfMetrics.Goroutines)
+		if stats.SelfMetrics.OpenFDs >= 0 {
+			fmt.Printf("Open file descriptors: %d\n", stats.SelfMetrics.OpenFDs)
+		} else {
+			fmt.Printf("Open file descriptors: n/a\n")
+		}
+		fmt.Printf("Heap in use: %s\n", formatBytes(int64(stats.SelfMetrics.HeapAlloc)))
+		fmt.Printf("Memory from OS: %s\n", formatBytes(int64(stats.SelfMetrics.Sys)))
+		if stats.TotalRequests > 0 {
+			fmt.Printf("Allocated per request: %s (%.0f allocs)\n",
+				formatBytes(int64(stats.SelfMetrics.BytesPerRequest)), stats.SelfMetrics.AllocsPerRequest)
+		}
+	}
+
+	if stats.Cooldown != nil {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("COOLDOWN (last %v, not included above)\n", stats.Cooldown.Duration)
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Printf("Requests: %d (%d failed)\n", stats.Cooldown.Requests, stats.Cooldown.Failed)
+		if stats.Cooldown.Requests > 0 {
+			fmt.Printf("Max: %v\n", stats.Cooldown.MaxResponseTime)
+			for _, p := range reportedPercentiles {
+				fmt.Printf("%dth percentile: %v\n", p, stats.Cooldown.Percentiles[p])
+			}
+		}
+	}
+
+	if stats.Repeat != nil {
+		printRepeatStats(stats.Repeat)
+	}
+	if stats.Tune != nil {
+		printTuneStats(stats.Tune)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// plainBanner replaces banner when output is limited to ASCII
+const plainBanner = `
+BRUTAL - High-Performance HTTP Load Testing Tool
+
+`
+
+func printBanner() {
+	switch {
+	case noBanner:
+	case !useUnicode:
+		fmt.Print(plainBanner)
+	case !useColor:
+		fmt.Print(stripANSI(banner))
+	default:
+		fmt.Print(banner)
+	}
+}
+
+// printHeader prints the banner and the test configuration
+func printHeader(config Config) {
+	printBanner()
+	fmt.Printf("Starting load test...\n")
+	fmt.Printf("URL: %s\n", truncate(config.URL, terminalWidth()-len("URL: ")))
+	if config.Host != "" {
+		fmt.Printf("Host: %s\n", config.Host)
+	}
+	if config.ConnectOnly {
+		fmt.Printf("Mode: connect-only (TCP")
+		if strings.HasPrefix(config.URL, "https://") {
+			fmt.Printf(" + TLS handshake")
+		}
+		fmt.Printf(")\n")
+	} else {
+		fmt.Printf("Method: %s\n", methodDescription(config))
+	}
+	fmt.Printf("Concurrent users: %d\n", config.Concurrent)
+	switch {
+	case config.Requests == 0:
+		fmt.Printf("Total requests: no limit, until the run's time limit\n")
+	case config.ConnectOnly:
+		fmt.Printf("Total connections: %d\n", config.Requests)
+	default:
+		fmt.Printf("Total requests: %d\n", config.Requests)
+	}
+	if config.Timeout > 0 {
+		fmt.Printf("Timeout: %v\n", config.Timeout)
+	} else {
+		fmt.Printf("Timeout: none\n")
+	}
+	fmt.Printf("Seed: %d\n", config.Seed)
+	if config.MaxConnsPerHost > 0 {
+		fmt.Printf("Max connections per host: %d\n", config.MaxConnsPerHost)
+	}
+	if n := len(config.PinSHA256); n > 0 {
+		fmt.Printf("TLS key pins: %d\n", n)
+	}
+	if config.MaxDuration > 0 {
+		fmt.Printf("Max duration: %v\n", config.MaxDuration)
+	}
+	if config.Cooldown > 0 {
+		fmt.Printf("Cooldown: %v (excluded from the results)\n", config.Cooldown)
+	}
+	if n := len(config.Phases); n > 0 {
+		fmt.Printf("Phases: %d over %v\n", n, config.Profile[len(config.Profile)-1].At)
+	} else if n := len(config.Profile); n > 0 {
+		fmt.Printf("Rate profile: %d points over %v\n", n, config.Profile[n-1].At)
+	}
+	if config.DNSServer != "" {
+		fmt.Printf("DNS server: %s\n", config.DNSServer)
+	}
+	if config.ProxyURL != "" {
+		if config.ConnectOnly {
+			fmt.Printf("Proxy: %s (ignored in connect-only mode)\n", config.ProxyURL)
+		} else {
+			fmt.Printf("Proxy: %s\n", config.ProxyURL)
+		}
+	}
+	fmt.Println(strings.Repeat("-", 50))
+}
+
+// buildConfig resolves the command line flags and arguments into a Config
+func buildConfig(args []string) (Config, error) {
+	if targetURL == "" && len(args) == 0 && rawRequest == "" {
+		return Config{}, fmt.Errorf("URL is required")
+	}
+
+	// Use URL from args if not provided via flag
+	if targetURL == "" && len(args) > 0 {
+		targetURL = args[0]
+	}
+
+	config := Config{
+		URL:         targetURL,
+		Method:      strings.ToUpper(method),
+		Concurrent:  concurrent,
 		Requests:    requests,
 		Timeout:     timeout,
 		InsecureTLS: insecure,
 		ProxyURL:    proxy,
-		Headers:     make(map[string]string),
+		ConnectOnly: connectOnly,
+		Host:        hostHeader,
+		MaxDuration: maxDuration,
+		Seed:        seed,
+		ApdexT:      apdexT,
+		IfNoneMatch: ifNoneMatch,
+
+		TrackHeaders:   trackHeaders,
+		CaptureHeaders: captureHeaders,
+
+		CheckConsistency: checkConsistency,
+		CountHeaders:     countHeaders,
+		SSE:              sse,
+		RequestIDHeaders: requestIDHeaders,
+
+		MaxConnsPerHost: maxConnsPerHost,
+		MaxIdleConns:    maxIdleConns,
+
+		HonorRetryAfter: honorRetryAfter,
+		Headers:         make(map[string]string),
+
+		PrewarmConns: prewarmConns,
+	}
+
+	if maxConnsPerHost < 0 || maxIdleConns < 0 {
+		return Config{}, fmt.Errorf("--max-conns-per-host and --max-idle-conns can't be negative")
+	}
+
+	if dnsServer != "" {
+		server, err := parseDNSServer(dnsServer)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid --dns-server: %v", err)
+		}
+		config.DNSServer = server
+	}
+
+	if ifNoneMatch != "" && connectOnly {
+		return Config{}, fmt.Errorf("--if-none-match can't be used with --connect-only")
+	}
+	if checkConsistency && connectOnly {
+		return Config{}, fmt.Errorf("--check-consistency can't be used with --connect-only")
+	}
+	if countHeaders && connectOnly {
+		return Config{}, fmt.Errorf("--count-headers can't be used with --connect-only")
+	}
+	if sse {
+		switch {
+		case maxDuration <= 0:
+			return Config{}, fmt.Errorf("--sse needs --max-duration, as streams stay open until the run ends")
+		case connectOnly:
+			return Config{}, fmt.Errorf("--sse can't be used with --connect-only")
+		case retries > 0 || gracePeriod > 0 || drainTimeout > 0 || cooldown > 0:
+			return Config{}, fmt.Errorf("--sse can't be used with --retries, --grace-period, --drain-timeout or --cooldown")
+		}
+	}
+
+	if proxy != "" {
+		if _, err := url.Parse(proxy); err != nil {
+			return Config{}, fmt.Errorf("invalid --proxy: %v", err)
+		}
+	}
+
+	if len(pinSHA256) > 0 {
+		config.PinSHA256 = pinSHA256
+		if _, err := buildTLSConfig(config); err != nil {
+			return Config{}, err
+		}
 	}
 
 	// Parse headers if provided
 	if headers != "" {
 		if err := json.Unmarshal([]byte(headers), &config.Headers); err != nil {
-			return fmt.Errorf("error parsing headers: %v", err)
+			return Config{}, fmt.Errorf("error parsing headers: %v", err)
+		}
+	}
+
+	// A Host entry in the headers map would be silently dropped by net/http,
+	// so promote it to the Host override unless --host was given explicitly
+	for key, value := range config.Headers {
+		if strings.EqualFold(key, "Host") {
+			if config.Host == "" {
+				config.Host = value
+			}
+			delete(config.Headers, key)
+		}
+	}
+
+	// Content-Type is looked up by its canonical name below, so one given in
+	// another case is renamed rather than joined by a default
+	for key, value := range config.Headers {
+		if key != "Content-Type" && strings.EqualFold(key, "Content-Type") {
+			delete(config.Headers, key)
+			config.Headers["Content-Type"] = value
+		}
+	}
+	// --content-type replaces the defaults chosen for a body below, but one
+	// set in --headers still wins
+	if contentType != "" && config.Headers["Content-Type"] == "" {
+		config.Headers["Content-Type"] = contentType
+	}
+
+	// Both form flags hold key=value fields; --form-urlencoded takes several at once
+	formFields := append(append([]string(nil), formURLEncoded...), formPairs...)
+	if len(formFields) > 0 {
+		if body != "" || bodyTemplateFile != "" || randomBodySize != "" {
+			return Config{}, fmt.Errorf("--form can't be combined with --body, --body-template or --random-body-size")
+		}
+		encoded, err := encodeForm(formFields)
+		if err != nil {
+			return Config{}, err
+		}
+		config.Body = encoded
+		if config.Headers["Content-Type"] == "" {
+			config.Headers["Content-Type"] = formContentType
 		}
 	}
 
@@ -406,36 +2294,538 @@ func runLoadTest(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	tester := NewLoadTester(config)
+	// A template from --config gives way to any flag that sets the body
+	bodyTemplate := configSettings.bodyTemplate
+	if body != "" || randomBodySize != "" || len(formFields) > 0 {
+		bodyTemplate = ""
+	}
+	if bodyTemplateFile != "" {
+		if body != "" || randomBodySize != "" {
+			return Config{}, fmt.Errorf("--body-template can't be combined with --body or --random-body-size")
+		}
+		text, err := os.ReadFile(bodyTemplateFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("error reading body template: %v", err)
+		}
+		bodyTemplate = string(text)
+	}
+	if bodyTemplate != "" {
+		tmpl, err := newBodyTemplate(bodyTemplate)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing body template: %v", err)
+		}
+		// Render once so references to unknown fields fail here, not on every request
+		if _, err := tmpl.render(rand.New(rand.NewPCG(seed, 0))); err != nil {
+			return Config{}, fmt.Errorf("error rendering body template: %v", err)
+		}
+		config.BodyTemplate = bodyTemplate
+		if config.Headers["Content-Type"] == "" {
+			config.Headers["Content-Type"] = "application/json"
+		}
+	}
 
-	// Print banner and configuration
-	printBanner()
-	fmt.Printf("Starting load test...\n")
-	fmt.Printf("URL: %s\n", config.URL)
-	fmt.Printf("Method: %s\n", config.Method)
-	fmt.Printf("Concurrent users: %d\n", config.Concurrent)
-	fmt.Printf("Total requests: %d\n", config.Requests)
-	fmt.Printf("Timeout: %v\n", config.Timeout)
-	if config.ProxyURL != "" {
-		fmt.Printf("Proxy: %s\n", config.ProxyURL)
+	if bearerFile != "" {
+		if _, err := readToken(bearerFile); err != nil {
+			return Config{}, fmt.Errorf("error reading bearer token: %v", err)
+		}
+		config.BearerFile = bearerFile
+		config.BearerRefresh = bearerRefresh
+	}
+
+	if profileFile != "" && phasesFile != "" {
+		return Config{}, fmt.Errorf("--profile and --phases are mutually exclusive")
+	}
+	if profileFile == "" && phasesFile == "" {
+		config.Profile = configSettings.profile
+		if len(configSettings.phases) > 0 {
+			config.Phases = configSettings.phases
+			config.Profile = phasesProfile(config.Phases)
+		}
+	}
+	if profileFile != "" {
+		points, err := loadRateProfile(profileFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("error loading rate profile: %v", err)
+		}
+		config.Profile = points
+	}
+	if phasesFile != "" {
+		phases, err := loadPhases(phasesFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("error loading phases: %v", err)
+		}
+		config.Phases = phases
+		config.Profile = phasesProfile(phases)
+	}
+	if config.Requests < 0 {
+		return Config{}, fmt.Errorf("-n must not be negative")
+	}
+	if config.Requests == 0 && runLimitOf(config) == 0 {
+		return Config{}, fmt.Errorf("-n 0 runs without a request limit and needs --max-duration, --profile or --phases to end")
+	}
+
+	if methodMix != "" {
+		mix, err := parseMethodMix(methodMix)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing --methods: %v", err)
+		}
+		config.MethodMix = mix
+	}
+
+	if randomBodySize != "" {
+		if body != "" {
+			return Config{}, fmt.Errorf("--random-body-size and --body are mutually exclusive")
+		}
+		size, err := parseByteSize(randomBodySize)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing random body size: %v", err)
+		}
+		config.RandomBodySize = size
+		config.RandomBodyEach = randomBodyEach
+		if config.Headers["Content-Type"] == "" {
+			config.Headers["Content-Type"] = "application/octet-stream"
+		}
+	}
+
+	// A raw request file fully describes the request and overrides the flags above
+	if rawRequest != "" {
+		if err := loadRawRequest(rawRequest, &config); err != nil {
+			return Config{}, fmt.Errorf("error loading raw request: %v", err)
+		}
+	}
+
+	// Raw request files can be committed with ${VAR} references instead of literal tokens
+	if err := expandConfigEnv(&config); err != nil {
+		return Config{}, err
+	}
+
+	if len(config.PinSHA256) > 0 && !strings.HasPrefix(config.URL, "https://") {
+		return Config{}, fmt.Errorf("--pin-sha256 needs an https URL")
+	}
+
+	if retries < 0 {
+		return Config{}, fmt.Errorf("--retries can't be negative")
+	}
+	if retries > 0 {
+		if config.ConnectOnly {
+			return Config{}, fmt.Errorf("--retries doesn't apply to --connect-only")
+		}
+		if retryBackoff <= 0 || retryMaxBackoff < retryBackoff {
+			return Config{}, fmt.Errorf("--retry-backoff must be positive and no longer than --retry-max-backoff")
+		}
+		if retryJitter < 0 || retryJitter > 1 {
+			return Config{}, fmt.Errorf("--retry-jitter must be between 0 and 1")
+		}
+		config.Retries = retries
+		config.RetryBackoff = retryBackoff
+		config.RetryMaxBackoff = retryMaxBackoff
+		config.RetryJitter = retryJitter
+	}
+
+	if gracePeriod < 0 {
+		return Config{}, fmt.Errorf("--grace-period can't be negative")
+	}
+	if gracePeriod > 0 && config.ConnectOnly {
+		return Config{}, fmt.Errorf("--grace-period doesn't apply to --connect-only")
+	}
+	config.GracePeriod = gracePeriod
+
+	if maxRPSPerWorker < 0 {
+		return Config{}, fmt.Errorf("--max-rps-per-worker can't be negative")
+	}
+	config.MaxRPSPerWorker = maxRPSPerWorker
+
+	if chaosAbort != "" || chaosDelay != "" {
+		if config.ConnectOnly || config.SSE {
+			return Config{}, fmt.Errorf("--chaos-abort and --chaos-delay apply to HTTP requests, not --connect-only or --sse")
+		}
+	}
+	if chaosAbort != "" {
+		rate, err := parsePercent(chaosAbort)
+		if err != nil {
+			return Config{}, fmt.Errorf("--chaos-abort: %v", err)
+		}
+		config.ChaosAbort = rate
+	}
+	if chaosDelay != "" {
+		rate, delay, err := parseChaosDelay(chaosDelay)
+		if err != nil {
+			return Config{}, err
+		}
+		config.ChaosDelayRate, config.ChaosDelay = rate, delay
+	}
+
+	if timeout < 0 {
+		return Config{}, fmt.Errorf("--timeout can't be negative (0 means no limit)")
+	}
+	if drainTimeout < 0 {
+		return Config{}, fmt.Errorf("--drain-timeout can't be negative")
+	}
+	config.DrainTimeout = drainTimeout
+
+	if cooldown != 0 {
+		limit := runLimitOf(config)
+		if limit == 0 {
+			return Config{}, fmt.Errorf("--cooldown needs a time-limited run (--max-duration or --profile)")
+		}
+		if cooldown < 0 || cooldown >= limit {
+			return Config{}, fmt.Errorf("--cooldown must be positive and shorter than the run (%v)", limit)
+		}
+		config.Cooldown = cooldown
+	}
+
+	return config, nil
+}
+
+// runOnce issues a single request, prints its outcome and returns an error
+// when it didn't succeed so the process exits non-zero
+func runOnce(tester *LoadTester) error {
+	result := tester.execute(tester.requestRand(0))
+	fmt.Println(tester.describeResult(result))
+
+	if !tester.isSuccess(result) {
+		if result.Error != nil {
+			return fmt.Errorf("request failed: %v", result.Error)
+		}
+		return fmt.Errorf("request failed with status %d", result.StatusCode)
+	}
+	fmt.Println("OK")
+	return nil
+}
+
+func runLoadTest(cmd *cobra.Command, args []string) error {
+	// A config file comes before the preset so its settings win over the preset's
+	if configFile != "" {
+		if err := applyConfigFile(cmd.Flags(), configFile, len(args) > 0); err != nil {
+			return err
+		}
+	}
+	if presetName != "" {
+		if err := applyPreset(cmd.Flags(), presetName); err != nil {
+			return err
+		}
+	}
+	// An unseeded run still gets a seed, printed and saved so it can be replayed
+	if !cmd.Flags().Changed("seed") {
+		seed = newSeed()
+	}
+	config, err := buildConfig(args)
+	if err != nil {
+		return err
+	}
+	if printConfig {
+		data, err := redactedConfigJSON(config)
+		if err != nil {
+			return err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), indented.String())
+		return nil
+	}
+	if noProgress {
+		if cmd.Flags().Changed("progress") && progressMode != progressDisabled {
+			return fmt.Errorf("--no-progress conflicts with --progress %s", progressMode)
+		}
+		progressMode = progressDisabled
+	}
+	if progressMode, err = resolveProgressMode(progressMode); err != nil {
+		return err
+	}
+	if progressEvery <= 0 {
+		return fmt.Errorf("--progress-interval must be positive")
+	}
+	if outputFormat != formatText && outputFormat != formatJSON {
+		return fmt.Errorf("invalid --format %q: must be text or json", outputFormat)
+	}
+	if quiet {
+		progressMode = progressDisabled
+	}
+	if verbosity > 0 && requests > maxTracedRequests {
+		return fmt.Errorf("-v prints every request and is limited to -n %d or fewer", maxTracedRequests)
+	}
+	if repeat < 1 {
+		return fmt.Errorf("--repeat must be at least 1")
+	}
+	if targetP95 < 0 {
+		return fmt.Errorf("--target-p95 must not be negative")
+	}
+	if targetP95 > 0 {
+		switch {
+		case repeat > 1:
+			return fmt.Errorf("--target-p95 runs its own series of probes and can't be combined with --repeat")
+		case interactive, controlFile != "":
+			return fmt.Errorf("--target-p95 sets the concurrency itself and can't be combined with --interactive or --control")
+		case connectOnly:
+			return fmt.Errorf("--target-p95 tunes on response times and doesn't apply to --connect-only")
+		}
+	}
+	// An endpoint only makes sense with tracing, so it turns --otel on
+	if otelEndpoint != "" {
+		otelEnabled = true
+	}
+	if otelEnabled && connectOnly {
+		return fmt.Errorf("--otel traces HTTP requests and doesn't apply to --connect-only")
+	}
+	if webhookURL != "" {
+		if webhookFormat, err = resolveWebhookFormat(webhookURL, webhookFormat); err != nil {
+			return err
+		}
+	}
+	if warnLatency > 0 && critLatency > 0 && warnLatency > critLatency {
+		return fmt.Errorf("--warn-latency (%v) must not exceed --crit-latency (%v)", warnLatency, critLatency)
+	}
+	// From here on errors are about the run, not the command line
+	cmd.SilenceUsage = true
+
+	closeLog, err := setupLogging(logFile, logLevel, logFormat)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+	logger.Info("config resolved",
+		"url", config.URL,
+		"method", methodDescription(config),
+		"concurrent", config.Concurrent,
+		"requests", config.Requests,
+		"timeout", config.Timeout,
+		"max_duration", config.MaxDuration,
+		"proxy", config.ProxyURL,
+		"connect_only", config.ConnectOnly,
+		"host", config.Host,
+		"config_file", configFile,
+		"preset", presetName)
+
+	tester, err := NewLoadTester(config)
+	if err != nil {
+		return err
+	}
+	defer tester.Close()
+
+	if config.IfNoneMatch == conditionalAuto {
+		v, err := tester.captureValidators()
+		if err != nil {
+			return fmt.Errorf("error capturing cache validators: %v", err)
+		}
+		logger.Info("captured cache validators", "etag", v.etag, "last_modified", v.lastModified)
+		if !quiet {
+			fmt.Printf("Revalidating with ETag %q, Last-Modified %q\n", v.etag, v.lastModified)
+		}
+	}
+
+	if once {
+		return runOnce(tester)
+	}
+
+	if !quiet {
+		printHeader(config)
+	}
+
+	var sampler *selfMetricsSampler
+	if selfMetrics {
+		sampler = startSelfMetricsSampler(500 * time.Millisecond)
 	}
-	fmt.Println(strings.Repeat("-", 50))
 
-	// Run the load test with progress callback
-	stats := tester.Run(func(completed, total int) {
-		percent := float64(completed) / float64(total) * 100
-		fmt.Printf("\rProgress: %d/%d (%.1f%%)", completed, total, percent)
-	})
+	if config.PrewarmConns {
+		if config.ProxyURL != "" {
+			fmt.Fprintln(os.Stderr, "Warning: --prewarm-conns is ignored when a proxy is used")
+			logger.Warn("prewarming skipped because a proxy is used")
+		} else {
+			prewarmStart := time.Now()
+			n, err := tester.Prewarm()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: prewarming connections failed: %v\n", err)
+				logger.Warn("prewarming connections failed", "error", err)
+			} else {
+				elapsed := time.Since(prewarmStart).Round(time.Millisecond)
+				logger.Info("prewarmed connections", "established", n, "wanted", config.Concurrent, "duration", elapsed)
+				if !quiet {
+					fmt.Printf("Prewarmed %d/%d connections in %v\n", n, config.Concurrent, elapsed)
+				}
+			}
+		}
+	}
 
-	fmt.Printf("\rCompleted: %d/%d (100.0%%)\n", config.Requests, config.Requests)
-	printStats(stats)
+	interrupt, stopInterrupts := watchInterrupts(tester)
+	defer stopInterrupts()
+	restoreTerminal := func() {}
+	if interactive {
+		if !quiet {
+			fmt.Println("Interactive: press + or ] to raise concurrency by 10%, - or [ to lower it, space to pause/resume, d to show/hide request details")
+		}
+		tester.recent = &recentResults{}
+		restore, err := startKeyboardControl(tester, interrupt)
+		if err != nil {
+			return fmt.Errorf("error enabling interactive mode: %v", err)
+		}
+		restoreTerminal = restore
+	}
+	if controlFile != "" {
+		stopControl := startControlFile(tester, controlFile)
+		defer stopControl()
+	}
+
+	// Run the load test, refreshing the progress line on a fixed tick so the
+	// cost of rendering doesn't grow with the request rate
+	display := &progressDisplay{
+		total:      config.Requests,
+		limit:      runLimitOf(config),
+		sampler:    sampler,
+		thresholds: latencyThresholds{warn: warnLatency, crit: critLatency},
+	}
+	stopProgress := startProgress(tester, display, progressMode, progressEvery)
+	if otelEnabled {
+		tester.otel = startOtelExporter(otelEndpoint)
+	}
+	if verbosity > 0 {
+		tester.trace = newTracer(tester, verbosity, progressMode == progressBar)
+		tester.captureHeaders = verbosity >= 2
+	}
+	stopIntervalLog := startIntervalLog(tester, progressEvery)
+	var allocStart allocCounters
+	if sampler != nil {
+		allocStart = readAllocCounters()
+	}
+	var runs []*Stats
+	completedRequests := 0
+	if targetP95 > 0 {
+		stats, tune := tester.TuneConcurrency(targetP95, func(p TuneProbe) {
+			logger.Info("probe finished", "concurrency", p.Concurrency,
+				"p95", p.P95, "rps", p.RPS, "within_target", p.Within)
+			completedRequests += p.Requests
+			if !quiet {
+				outputMu.Lock()
+				fmt.Printf("\rProbe %s\033[K\n", p)
+				outputMu.Unlock()
+			}
+		})
+		stats.Tune = tune
+		runs = append(runs, stats)
+	} else {
+		for i := 1; i <= repeat && !tester.interrupted(); i++ {
+			if i > 1 {
+				tester.Reset()
+			}
+			runStats := tester.Run(nil)
+			runs = append(runs, runStats)
+			completedRequests += runStats.TotalRequests
+			if repeat > 1 {
+				logger.Info("repeat run finished", "run", i, "of", repeat,
+					"requests", runStats.TotalRequests, "rps", runStats.RequestsPerSec)
+				if !quiet {
+					outputMu.Lock()
+					fmt.Printf("\rRun %d/%d: %s\033[K\n", i, repeat, summaryLine(runStats))
+					outputMu.Unlock()
+				}
+			}
+		}
+	}
+	stats := runs[len(runs)-1]
+	if repeat > 1 {
+		stats.Repeat = buildRepeatStats(runs)
+	}
+	var allocEnd allocCounters
+	if sampler != nil {
+		allocEnd = readAllocCounters()
+	}
+	stopIntervalLog()
+	stopProgress()
+	var spansExported, spansFailed int
+	var spansErr error
+	if tester.otel != nil {
+		spansExported, spansFailed, spansErr = tester.otel.Stop()
+	}
+	logger.Info("run finished",
+		"requests", stats.TotalRequests,
+		"successful", stats.SuccessfulReqs,
+		"failed", stats.FailedReqs,
+		"duration", stats.TotalTime,
+		"rps", stats.RequestsPerSec,
+		"p50", stats.Percentiles[50],
+		"p95", stats.Percentiles[95],
+		"p99", stats.Percentiles[99])
+	restoreTerminal()
+
+	if sampler != nil {
+		peak := sampler.Stop()
+		peak.setAllocationsPerRequest(allocStart, allocEnd, completedRequests)
+		stats.SelfMetrics = &peak
+	}
+
+	switch {
+	case quiet:
+	case progressMode == progressBar:
+		fmt.Printf("\r%s\033[K\n", completionLine(stats, false))
+	default:
+		fmt.Println(completionLine(stats, true))
+	}
+
+	switch {
+	case noSummary:
+	case summaryOnly && outputFormat == formatJSON:
+		if err := tester.writeHeadlineJSON(os.Stdout, stats, quiet); err != nil {
+			return fmt.Errorf("error writing JSON results: %v", err)
+		}
+	case outputFormat == formatJSON:
+		if err := tester.WriteJSON(os.Stdout, stats); err != nil {
+			return fmt.Errorf("error writing JSON results: %v", err)
+		}
+	case summaryOnly && quiet:
+		fmt.Println(newHeadline(stats).keyValues())
+	case quiet:
+		fmt.Println(summaryLine(stats))
+	case summaryOnly:
+		fmt.Println(newHeadline(stats))
+	default:
+		printStats(stats)
+	}
 
 	// Save results to JSON if output file specified
 	if output != "" {
 		if err := tester.SaveResultsToJSON(output, stats); err != nil {
-			log.Printf("Error saving results to JSON: %v", err)
+			fmt.Fprintf(os.Stderr, "Warning: saving results to %s failed: %v\n", output, err)
+			logger.Error("saving results failed", "file", output, "error", err)
+		} else {
+			if !quiet {
+				fmt.Printf("Results saved to: %s\n", output)
+			}
+		}
+	}
+
+	if tester.otel != nil {
+		logger.Info("exported spans", "url", tester.otel.url, "exported", spansExported, "failed", spansFailed)
+		if spansFailed > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: exporting %d of %d spans to %s failed: %v\n",
+				spansFailed, spansExported+spansFailed, tester.otel.url, spansErr)
+		} else if !quiet {
+			fmt.Printf("Spans exported to: %s (%d)\n", tester.otel.url, spansExported)
+		}
+	}
+
+	// The URL is left out of messages: webhook URLs usually embed a token
+	if webhookURL != "" {
+		if err := tester.PostWebhook(webhookURL, webhookFormat, stats); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: posting results to the webhook failed: %v\n", err)
+			logger.Warn("posting results to the webhook failed", "error", err)
 		} else {
-			fmt.Printf("Results saved to: %s\n", output)
+			logger.Info("posted results to the webhook", "format", webhookFormat)
+			if !quiet {
+				fmt.Printf("Results posted to the %s webhook\n", webhookFormat)
+			}
+		}
+	}
+
+	// Checked last so a failing run is still reported and saved in full
+	if strict {
+		failed, total := 0, 0
+		for _, run := range runs {
+			failed += run.FailedReqs
+			total += run.TotalRequests
+		}
+		if failed > 0 {
+			return fmt.Errorf("--strict: %d of %d requests failed", failed, total)
 		}
 	}
 
@@ -443,107 +2833,7 @@ func runLoadTest(cmd *cobra.Command, args []string) error {
 }
 
 func main() {
-	var rootCmd = &cobra.Command{
-		Use:   "brutal [URL]",
-		Short: "Brutal - A powerful HTTP load testing tool",
-		Long: `Brutal is a blazingly fast HTTP load testing tool with comprehensive analytics.
-It provides detailed statistics, percentile analysis, and supports various HTTP methods.`,
-		Example: `  brutal https://api.example.com
-  brutal https://api.example.com -n 1000 -c 50
-  brutal https://api.example.com -method POST -body '{"test": "data"}'
-  brutal https://api.example.com -p http://proxy.example.com:8080`,
-		RunE: runLoadTest,
-		Args: cobra.MaximumNArgs(1),
-	}
-
-	// Add flags
-	rootCmd.Flags().StringVarP(&targetURL, "url", "u", "", "Target URL to test")
-	rootCmd.Flags().StringVarP(&method, "method", "X", "GET", "HTTP method")
-	rootCmd.Flags().StringVarP(&headers, "headers", "H", "", "Headers in JSON format")
-	rootCmd.Flags().StringVarP(&body, "body", "d", "", "Request body")
-	rootCmd.Flags().IntVarP(&concurrent, "concurrent", "c", 10, "Number of concurrent requests")
-	rootCmd.Flags().IntVarP(&requests, "requests", "n", 100, "Total number of requests")
-	rootCmd.Flags().DurationVarP(&timeout, "timeout", "t", 30*time.Second, "Request timeout")
-	rootCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Skip TLS certificate verification")
-	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output file for JSON results")
-	rootCmd.Flags().StringVarP(&proxy, "proxy", "p", "", "Proxy URL (e.g., http://proxy.example.com:8080)")
-
-	// Add persistent flags
-	rootCmd.PersistentFlags().BoolVarP(&noBanner, "no-banner", "", false, "Disable ASCII art banner")
-
-	// Add version command
-	var versionCmd = &cobra.Command{
-		Use:   "version",
-		Short: "Print the version number of Brutal",
-		Run: func(cmd *cobra.Command, args []string) {
-			if !noBanner {
-				printBanner()
-			}
-			fmt.Printf("Brutal Load Tester v%s\n", version)
-			fmt.Printf("Built with Go %s\n", "1.23+")
-			fmt.Printf("Powered by Cobra CLI Framework\n")
-			fmt.Println()
-		},
-	}
-	rootCmd.AddCommand(versionCmd)
-
-	// Add completion command
-	var completionCmd = &cobra.Command{
-		Use:   "completion [bash|zsh|fish|powershell]",
-		Short: "Generate completion script",
-		Long: `To load completions:
-
-Bash:
-  $ source <(brutal completion bash)
-
-  # To load completions for each session, execute once:
-  # Linux:
-  $ brutal completion bash > /etc/bash_completion.d/brutal
-  # macOS:
-  $ brutal completion bash > /usr/local/etc/bash_completion.d/brutal
-
-Zsh:
-  # If shell completion is not already enabled in your environment,
-  # you will need to enable it.  You can execute the following once:
-  $ echo "autoload -U compinit; compinit" >> ~/.zshrc
-
-  # To load completions for each session, execute once:
-  $ brutal completion zsh > "${fpath[1]}/_brutal"
-
-  # You will need to start a new shell for this setup to take effect.
-
-fish:
-  $ brutal completion fish | source
-
-  # To load completions for each session, execute once:
-  $ brutal completion fish > ~/.config/fish/completions/brutal.fish
-
-PowerShell:
-  PS> brutal completion powershell | Out-String | Invoke-Expression
-
-  # To load completions for every new session, run:
-  PS> brutal completion powershell > brutal.ps1
-  # and source this file from your PowerShell profile.
-`,
-		DisableFlagsInUseLine: true,
-		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
-		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-		Run: func(cmd *cobra.Command, args []string) {
-			switch args[0] {
-			case "bash":
-				cmd.Root().GenBashCompletion(os.Stdout)
-			case "zsh":
-				cmd.Root().GenZshCompletion(os.Stdout)
-			case "fish":
-				cmd.Root().GenFishCompletion(os.Stdout, true)
-			case "powershell":
-				cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
-			}
-		},
-	}
-	rootCmd.AddCommand(completionCmd)
-
-	if err := rootCmd.Execute(); err != nil {
+	if err := newRootCmd().Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,667 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer serves 200 with a fixed body, or 500 for every failEvery-th request when failEvery > 0
+func newTestServer(t *testing.T, failEvery int64) *httptest.Server {
+	t.Helper()
+	var count int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&count, 1)
+		if failEvery > 0 && n%failEvery == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		io.WriteString(w, "hello")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testConfig(url string) Config {
+	return Config{
+		URL:        url,
+		Method:     "GET",
+		Concurrent: 5,
+		Requests:   50,
+		Timeout:    5 * time.Second,
+		Headers:    map[string]string{},
+	}
+}
+
+// newTestLoadTester creates a load tester for config, failing t if it can't
+func newTestLoadTester(t testing.TB, config Config) *LoadTester {
+	t.Helper()
+	lt, err := NewLoadTester(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lt
+}
+
+// testRand returns a fixed random source for calls that take one
+func testRand() *rand.Rand {
+	return rand.New(rand.NewPCG(1, 1))
+}
+
+func TestMakeRequest(t *testing.T) {
+	var gotHost, gotAgent, gotHeader, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		gotAgent = r.Header.Get("User-Agent")
+		gotHeader = r.Header.Get("X-Test")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		io.WriteString(w, "hello world")
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Method = "POST"
+	config.Body = `{"a":1}`
+	config.Host = "vhost.example.com"
+	config.Headers["X-Test"] = "yes"
+
+	result := newTestLoadTester(t, config).makeRequest(testRand())
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", result.StatusCode)
+	}
+	if result.ContentSize != int64(len("hello world")) {
+		t.Errorf("content size = %d, want %d", result.ContentSize, len("hello world"))
+	}
+	if result.ResponseTime <= 0 {
+		t.Errorf("response time = %v, want > 0", result.ResponseTime)
+	}
+	if gotHost != "vhost.example.com" {
+		t.Errorf("Host = %q, want vhost.example.com", gotHost)
+	}
+	if gotAgent != "Go Brutal/1.0" {
+		t.Errorf("User-Agent = %q, want default", gotAgent)
+	}
+	if gotHeader != "yes" {
+		t.Errorf("X-Test = %q, want yes", gotHeader)
+	}
+	if gotBody != `{"a":1}` {
+		t.Errorf("body = %q", gotBody)
+	}
+}
+
+func TestRequestIDHeaders(t *testing.T) {
+	var gotID, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID, gotKey = r.Header.Get("X-Request-ID"), r.Header.Get("Idempotency-Key")
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.RequestIDHeaders = []string{"X-Request-ID", "Idempotency-Key"}
+	tester := newTestLoadTester(t, config)
+	first := tester.makeRequest(testRand())
+	if len(first.RequestID) != 36 || gotID != first.RequestID || gotKey != first.RequestID {
+		t.Fatalf("sent %q and %q, result has %q", gotID, gotKey, first.RequestID)
+	}
+	// The same random stream must still give a fresh id
+	if second := tester.makeRequest(testRand()); second.RequestID == first.RequestID {
+		t.Errorf("request id %q reused", second.RequestID)
+	}
+}
+
+func TestMakeRequestConnectionError(t *testing.T) {
+	srv := newTestServer(t, 0)
+	url := srv.URL
+	srv.Close()
+
+	result := newTestLoadTester(t, testConfig(url)).makeRequest(testRand())
+	if result.Error == nil {
+		t.Fatal("expected an error against a closed server")
+	}
+	if result.StatusCode != 0 {
+		t.Errorf("status = %d, want 0", result.StatusCode)
+	}
+}
+
+func TestResponseTimeIncludesBodyRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first half ")
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "second half")
+	}))
+	t.Cleanup(srv.Close)
+
+	config := testConfig(srv.URL)
+	config.Requests = 5
+	tester := newTestLoadTester(t, config)
+	result := tester.sendRequest("GET", testRand())
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.HeaderTime >= 50*time.Millisecond {
+		t.Errorf("header time = %v, want it before the slow half of the body", result.HeaderTime)
+	}
+	if result.ResponseTime < 100*time.Millisecond || result.ResponseTime != result.TTLB {
+		t.Errorf("response time = %v, TTLB = %v; want both to cover the whole body", result.ResponseTime, result.TTLB)
+	}
+
+	stats := tester.Run(nil)
+	if stats.Percentiles[50] < 100*time.Millisecond || stats.HeaderPercentiles[50] >= 50*time.Millisecond {
+		t.Errorf("p50 = %v, headers only p50 = %v; want the body in the first only", stats.Percentiles[50], stats.HeaderPercentiles[50])
+	}
+}
+
+func TestStatusCodesKeepErrorsApart(t *testing.T) {
+	var count atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every fourth request loses its connection before any response
+		if count.Add(1)%4 == 0 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, "hello")
+	}))
+	t.Cleanup(srv.Close)
+
+	config := testConfig(srv.URL)
+	config.Concurrent = 1
+	config.Requests = 40
+	config.Method = "POST" // a GET would be retried by the transport on a fresh connection
+	stats := newTestLoadTester(t, config).Run(nil)
+
+	if _, ok := stats.StatusCodes[0]; ok || stats.StatusCodes[200] != 30 || stats.ErrorCount != 10 {
+		t.Fatalf("status codes = %v, errors = %d; want 30 200s and 10 errors", stats.StatusCodes, stats.ErrorCount)
+	}
+	want := []string{"200: 30 (75.0%)", "No response: 10 (25.0%)"}
+	if got := formatStatusCodes(stats); !reflect.DeepEqual(got, want) {
+		t.Errorf("formatStatusCodes = %q, want %q", got, want)
+	}
+}
+
+func TestFormatStatusCodesSorted(t *testing.T) {
+	stats := &Stats{TotalRequests: 4, StatusCodes: map[int]int{503: 1, 200: 2, 404: 1}}
+	want := []string{"200: 2 (50.0%)", "404: 1 (25.0%)", "503: 1 (25.0%)"}
+	if got := formatStatusCodes(stats); !reflect.DeepEqual(got, want) {
+		t.Errorf("formatStatusCodes = %q, want %q", got, want)
+	}
+}
+
+func TestRunCountsResults(t *testing.T) {
+	srv := newTestServer(t, 5)
+
+	tester := newTestLoadTester(t, testConfig(srv.URL))
+	stats := tester.Run(nil)
+
+	if stats.TotalRequests != 50 {
+		t.Fatalf("total = %d, want 50", stats.TotalRequests)
+	}
+	if stats.SuccessfulReqs != 40 || stats.FailedReqs != 10 {
+		t.Errorf("successful/failed = %d/%d, want 40/10", stats.SuccessfulReqs, stats.FailedReqs)
+	}
+	if stats.StatusCodes[200] != 40 || stats.StatusCodes[500] != 10 || stats.ErrorCount != 0 {
+		t.Errorf("status codes = %v, errors = %d", stats.StatusCodes, stats.ErrorCount)
+	}
+	if stats.TotalBytes != 50*int64(len("hello")) {
+		t.Errorf("total bytes = %d, want %d", stats.TotalBytes, 50*len("hello"))
+	}
+	if stats.RequestsPerSec <= 0 {
+		t.Errorf("requests/sec = %v, want > 0", stats.RequestsPerSec)
+	}
+	if got := tester.Live().Snapshot().Completed; got != 50 {
+		t.Errorf("live completed = %d, want 50", got)
+	}
+
+	prev := stats.MinResponseTime
+	for _, p := range reportedPercentiles {
+		v := stats.Percentiles[p]
+		if v < prev || v > stats.MaxResponseTime {
+			t.Errorf("p%d = %v outside [%v, %v]", p, v, prev, stats.MaxResponseTime)
+		}
+		prev = v
+	}
+}
+
+func TestRunNumbersResults(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	tester := newTestLoadTester(t, config)
+	tester.Run(nil)
+
+	seen := make(map[int]bool)
+	for _, result := range tester.results {
+		if result.Seq < 1 || result.Seq > config.Requests || seen[result.Seq] {
+			t.Errorf("seq %d out of range or repeated", result.Seq)
+		}
+		seen[result.Seq] = true
+		if result.WorkerID < 1 || result.WorkerID > config.Concurrent {
+			t.Errorf("worker id %d outside 1..%d", result.WorkerID, config.Concurrent)
+		}
+		if result.StartTime.IsZero() || result.StartTime.After(result.Timestamp) {
+			t.Errorf("start %v not before completion %v", result.StartTime, result.Timestamp)
+		}
+	}
+}
+
+func TestTransportConnectionLimits(t *testing.T) {
+	config := testConfig("http://example.com")
+	transport := newTestLoadTester(t, config).httpClient.Transport.(*http.Transport)
+	if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 5 || transport.MaxConnsPerHost != 0 {
+		t.Errorf("defaults: idle %d, idle per host %d, per host %d", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+
+	config.MaxConnsPerHost = 3
+	config.MaxIdleConns = 50
+	transport = newTestLoadTester(t, config).httpClient.Transport.(*http.Transport)
+	if transport.MaxIdleConns != 50 || transport.MaxConnsPerHost != 3 {
+		t.Errorf("overrides: idle %d, per host %d", transport.MaxIdleConns, transport.MaxConnsPerHost)
+	}
+}
+
+func TestCalculateStats(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.invalid"))
+	start := time.Now()
+	tester.startTime = start
+	for i := 1; i <= 100; i++ {
+		result := Result{
+			StatusCode:   200,
+			ResponseTime: time.Duration(i) * time.Millisecond,
+			ContentSize:  10,
+			Timestamp:    start.Add(time.Duration(i) * 20 * time.Millisecond),
+		}
+		if i%25 == 0 {
+			result = Result{Error: errors.New("dial tcp: i/o timeout"), ResponseTime: result.ResponseTime, Timestamp: result.Timestamp}
+		}
+		tester.results = append(tester.results, result)
+	}
+
+	stats := tester.calculateStats(2 * time.Second)
+
+	if stats.SuccessfulReqs != 96 || stats.FailedReqs != 4 {
+		t.Errorf("successful/failed = %d/%d, want 96/4", stats.SuccessfulReqs, stats.FailedReqs)
+	}
+	if stats.MinResponseTime != time.Millisecond || stats.MaxResponseTime != 100*time.Millisecond {
+		t.Errorf("min/max = %v/%v", stats.MinResponseTime, stats.MaxResponseTime)
+	}
+	if stats.AvgResponseTime != 50500*time.Microsecond {
+		t.Errorf("avg = %v, want 50.5ms", stats.AvgResponseTime)
+	}
+	want := map[int]time.Duration{50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99: 99 * time.Millisecond}
+	for p, d := range want {
+		if stats.Percentiles[p] != d {
+			t.Errorf("p%d = %v, want %v", p, stats.Percentiles[p], d)
+		}
+	}
+	if stats.RequestsPerSec != 50 {
+		t.Errorf("requests/sec = %v, want 50", stats.RequestsPerSec)
+	}
+	if len(stats.ErrorGroups) != 1 || stats.ErrorGroups[0].Message != "dial tcp: i/o timeout" || stats.ErrorGroups[0].Count != 4 {
+		t.Errorf("error groups = %v", stats.ErrorGroups)
+	}
+	if len(stats.Timeline) != 3 {
+		t.Errorf("timeline has %d buckets, want 3", len(stats.Timeline))
+	}
+}
+
+func TestConnectOnly(t *testing.T) {
+	srv := newTestServer(t, 0)
+
+	config := testConfig(srv.URL)
+	config.ConnectOnly = true
+	config.Requests = 20
+	stats := newTestLoadTester(t, config).Run(nil)
+
+	if stats.SuccessfulReqs != 20 {
+		t.Errorf("successful = %d, want 20", stats.SuccessfulReqs)
+	}
+	if !stats.ConnectOnly {
+		t.Error("stats not marked as connect-only")
+	}
+	if stats.TotalBytes != 0 {
+		t.Errorf("total bytes = %d, want 0", stats.TotalBytes)
+	}
+}
+
+func TestBuildConfig(t *testing.T) {
+	savedURL, savedMethod, savedHeaders, savedBody, savedHost := targetURL, method, headers, body, hostHeader
+	t.Cleanup(func() {
+		targetURL, method, headers, body, hostHeader = savedURL, savedMethod, savedHeaders, savedBody, savedHost
+	})
+
+	targetURL, method, headers, body, hostHeader = "", "post", `{"Host": "api.example.com"}`, `{"x":1}`, ""
+
+	config, err := buildConfig([]string{"http://10.0.0.1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.URL != "http://10.0.0.1/" || config.Method != "POST" {
+		t.Errorf("url/method = %s %s", config.URL, config.Method)
+	}
+	if config.Host != "api.example.com" {
+		t.Errorf("Host = %q, want it promoted from headers", config.Host)
+	}
+	if _, ok := config.Headers["Host"]; ok {
+		t.Error("Host should be removed from the headers map")
+	}
+	if config.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q, want default for body", config.Headers["Content-Type"])
+	}
+
+	targetURL = ""
+	if _, err := buildConfig(nil); err == nil || !strings.Contains(err.Error(), "URL is required") {
+		t.Errorf("expected URL is required error, got %v", err)
+	}
+}
+
+func TestBuildConfigContentType(t *testing.T) {
+	savedHeaders, savedBody, savedContentType := headers, body, contentType
+	t.Cleanup(func() { headers, body, contentType = savedHeaders, savedBody, savedContentType })
+
+	tests := []struct {
+		headers, contentType, want string
+	}{
+		{"", "", "application/json"},
+		{"", "application/xml", "application/xml"},
+		{`{"Content-Type": "text/plain"}`, "application/xml", "text/plain"},
+		{`{"content-type": "text/plain"}`, "application/xml", "text/plain"},
+		{`{"content-type": "text/plain"}`, "", "text/plain"},
+	}
+	for _, tt := range tests {
+		headers, body, contentType = tt.headers, "<x/>", tt.contentType
+		config, err := buildConfig([]string{"http://10.0.0.1/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := config.Headers["Content-Type"]; got != tt.want || len(config.Headers) != 1 {
+			t.Errorf("-H %q --content-type %q: headers %v, want only Content-Type %q",
+				tt.headers, tt.contentType, config.Headers, tt.want)
+		}
+	}
+}
+
+func TestTrailersAreCounted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+		io.WriteString(w, "payload")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 10
+	stats := newTestLoadTester(t, config).Run(nil)
+
+	if stats.ResponsesWithTrailers != 10 {
+		t.Errorf("responses with trailers = %d, want 10", stats.ResponsesWithTrailers)
+	}
+	if stats.Trailers["Grpc-Status"] != 10 {
+		t.Errorf("trailers = %v", stats.Trailers)
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	stats := &Stats{
+		TotalRequests:  10,
+		SuccessfulReqs: 9,
+		FailedReqs:     1,
+		TotalTime:      1500 * time.Millisecond,
+		RequestsPerSec: 6.666,
+		Percentiles:    map[int]time.Duration{50: 10 * time.Millisecond, 95: 20 * time.Millisecond, 99: 30 * time.Millisecond},
+	}
+	want := "requests=10 successful=9 failed=1 duration=1.5s rps=6.67 p50=10ms p95=20ms p99=30ms"
+	if got := summaryLine(stats); got != want {
+		t.Errorf("summaryLine() = %q, want %q", got, want)
+	}
+}
+
+func TestMaxDurationStopsRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 1000
+	config.MaxDuration = 200 * time.Millisecond
+
+	start := time.Now()
+	stats := newTestLoadTester(t, config).Run(nil)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("run took %v despite MaxDuration", elapsed)
+	}
+	if !stats.MaxDurationReached {
+		t.Error("expected MaxDurationReached")
+	}
+	if stats.PlannedRequests != 1000 || stats.TotalRequests >= 1000 || stats.TotalRequests == 0 {
+		t.Errorf("got %d of %d planned requests", stats.TotalRequests, stats.PlannedRequests)
+	}
+	if stats.FailedReqs != 0 {
+		t.Errorf("requests cut off by MaxDuration counted as %d failures", stats.FailedReqs)
+	}
+	if stats.StoppedBy != "--max-duration" || stats.SkippedRequests < 1000-stats.TotalRequests-config.Concurrent {
+		t.Errorf("stopped by %q with %d skipped, want --max-duration and the unsent rest",
+			stats.StoppedBy, stats.SkippedRequests)
+	}
+}
+
+func TestMaxDurationWithNothingCompleted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.MaxDuration = 50 * time.Millisecond
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.TotalRequests != 0 || stats.SkippedRequests != config.Requests-config.Concurrent {
+		t.Fatalf("got %d requests and %d skipped, want 0 and %d",
+			stats.TotalRequests, stats.SkippedRequests, config.Requests-config.Concurrent)
+	}
+
+	// None of the summaries may divide by the empty results
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+	printStats(stats)
+	completionLine(stats, true)
+	summaryLine(stats)
+}
+
+func TestDrainTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay := 100 * time.Millisecond
+		if r.URL.Query().Get("slow") != "" {
+			delay = 5 * time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	// Requests in flight at the deadline finish within the drain timeout
+	config := testConfig(srv.URL)
+	config.Requests = 1000
+	config.MaxDuration = 150 * time.Millisecond
+	config.DrainTimeout = time.Second
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.CanceledAtDrain != 0 || stats.TotalRequests != 2*config.Concurrent {
+		t.Errorf("got %d requests and %d canceled at drain, want %d and 0",
+			stats.TotalRequests, stats.CanceledAtDrain, 2*config.Concurrent)
+	}
+
+	// Requests that outlast it are canceled and counted apart
+	config.URL = srv.URL + "?slow=1"
+	config.DrainTimeout = 100 * time.Millisecond
+	start := time.Now()
+	stats = newTestLoadTester(t, config).Run(nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("run took %v despite the drain timeout", elapsed)
+	}
+	if stats.CanceledAtDrain != config.Concurrent || stats.TotalRequests != 0 || stats.FailedReqs != 0 {
+		t.Errorf("canceled at drain = %d, total = %d, failed = %d; want %d, 0, 0",
+			stats.CanceledAtDrain, stats.TotalRequests, stats.FailedReqs, config.Concurrent)
+	}
+}
+
+func TestBodyReadStopsOnCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		// Stall the body until the test ends
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	tester := newTestLoadTester(t, testConfig(srv.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	tester.ctx = ctx
+
+	start := time.Now()
+	result := tester.makeRequest(testRand())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("body read took %v after cancellation", elapsed)
+	}
+	if category := errorCategory(result); category != "canceled_during_read" {
+		t.Errorf("category = %q (error %v), want canceled_during_read", category, result.Error)
+	}
+}
+
+func TestSaveResultsIncludesMetadata(t *testing.T) {
+	srv := newTestServer(t, 0)
+	tester := newTestLoadTester(t, testConfig(srv.URL))
+	stats := tester.Run(nil)
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := tester.SaveResultsToJSON(path, stats); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Metadata RunMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	meta := saved.Metadata
+	if meta.ToolVersion == "" || meta.GoVersion == "" || meta.OS == "" || meta.CPUs == 0 {
+		t.Errorf("incomplete metadata: %+v", meta)
+	}
+	if meta.StartedAt.IsZero() || meta.FinishedAt.Before(meta.StartedAt) {
+		t.Errorf("bad run timestamps: %v - %v", meta.StartedAt, meta.FinishedAt)
+	}
+}
+
+func TestFirstAndLastByteTimes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "start")
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, "end")
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 5
+	tester := newTestLoadTester(t, config)
+
+	result := tester.makeRequest(testRand())
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.TTFB <= 0 || result.TTFB > 40*time.Millisecond {
+		t.Errorf("TTFB = %v, want well before the delayed end of the body", result.TTFB)
+	}
+	if result.TTLB-result.TTFB < 40*time.Millisecond {
+		t.Errorf("TTLB %v isn't ~50ms after TTFB %v", result.TTLB, result.TTFB)
+	}
+
+	stats := tester.Run(nil)
+	if stats.TTLBPercentiles[50]-stats.TTFBPercentiles[50] < 40*time.Millisecond {
+		t.Errorf("median TTFB %v and TTLB %v should differ by the body delay",
+			stats.TTFBPercentiles[50], stats.TTLBPercentiles[50])
+	}
+	if transfer := stats.TransferPercentiles[50]; transfer < 40*time.Millisecond || transfer > 500*time.Millisecond {
+		t.Errorf("median transfer = %v, want about the 50ms body delay", transfer)
+	}
+	// 8 body bytes over ~50ms
+	if stats.MedianThroughput < 10 || stats.MedianThroughput > 200 {
+		t.Errorf("median throughput = %.1f bytes/s, want about 160", stats.MedianThroughput)
+	}
+}
+
+func TestResetBetweenRuns(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 20
+	config.CaptureHeaders = []string{"Content-Type"}
+	config.CheckConsistency = true
+	config.Profile = []RatePoint{{0, 200}, {time.Second, 200}}
+	tester := newTestLoadTester(t, config)
+
+	first := tester.Run(nil)
+	tester.SetConcurrency(1)
+	// A second Run used to close the rate profile's stop channel twice
+	tester.Reset()
+	if tester.Concurrency() != config.Concurrent {
+		t.Errorf("concurrency after Reset = %d, want %d", tester.Concurrency(), config.Concurrent)
+	}
+	second := tester.Run(nil)
+
+	if first.TotalRequests != 20 || second.TotalRequests != 20 {
+		t.Fatalf("requests = %d then %d, want 20 each", first.TotalRequests, second.TotalRequests)
+	}
+	if n := second.CapturedHeaders[0].Values[0].Count; n != 20 {
+		t.Errorf("captured header count = %d after Reset, want 20", n)
+	}
+	if n := second.BodyConsistency.Bodies[0].Count; n != 20 {
+		t.Errorf("body variant count = %d after Reset, want 20", n)
+	}
+	if len(tester.concurrencyChanges) != 0 {
+		t.Errorf("concurrency changes survived Reset: %v", tester.concurrencyChanges)
+	}
+}
+
+func TestNewLoadTesterRejectsBadFileSettings(t *testing.T) {
+	template := testConfig("http://example.com")
+	template.BodyTemplate = "{{.Missing"
+	missing := testConfig("http://example.com")
+	missing.BearerFile = filepath.Join(t.TempDir(), "token")
+	for _, config := range []Config{template, missing} {
+		if lt, err := NewLoadTester(config); err == nil {
+			lt.Close()
+			t.Errorf("NewLoadTester(template %q, bearer file %q) succeeded, want an error",
+				config.BodyTemplate, config.BearerFile)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WeightedMethod is one entry of a --methods mix
+type WeightedMethod struct {
+	Method string `json:"method"`
+	Weight int    `json:"weight"`
+}
+
+// mixMethods are the methods a --methods mix may use
+var mixMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// parseMethodMix parses a mix such as "GET:70,POST:20,PUT:10". A method without
+// a weight counts as weight 1. Weights may instead all be percentages, such as
+// "GET:90%,POST:10%", which must add up to 100.
+func parseMethodMix(s string) ([]WeightedMethod, error) {
+	var mix []WeightedMethod
+	seen := make(map[string]bool)
+	percentages, total := 0, 0
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weightText, hasWeight := strings.Cut(part, ":")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("missing method in %q", part)
+		}
+		if !mixMethods[name] {
+			return nil, fmt.Errorf("unsupported method %s", name)
+		}
+		weight := 1
+		if hasWeight {
+			weightText = strings.TrimSpace(weightText)
+			if text, ok := strings.CutSuffix(weightText, "%"); ok {
+				weightText = text
+				percentages++
+			}
+			w, err := strconv.Atoi(weightText)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight in %q", part)
+			}
+			weight = w
+		}
+		total += weight
+		if seen[name] {
+			return nil, fmt.Errorf("method %s listed twice", name)
+		}
+		seen[name] = true
+		if weight > 0 {
+			mix = append(mix, WeightedMethod{Method: name, Weight: weight})
+		}
+	}
+	if percentages > 0 {
+		if percentages != len(seen) {
+			return nil, fmt.Errorf("give every method a percentage or none in %q", s)
+		}
+		if total != 100 {
+			return nil, fmt.Errorf("percentages add up to %d, want 100", total)
+		}
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("no method with a positive weight in %q", s)
+	}
+	return mix, nil
+}
+
+// pickMethod chooses the method for the next request, by weight when a mix is configured
+func (lt *LoadTester) pickMethod(rng *rand.Rand) string {
+	mix := lt.config.MethodMix
+	if len(mix) == 0 {
+		return lt.config.Method
+	}
+	total := 0
+	for _, m := range mix {
+		total += m.Weight
+	}
+	n := rng.IntN(total)
+	for _, m := range mix {
+		if n < m.Weight {
+			return m.Method
+		}
+		n -= m.Weight
+	}
+	return mix[len(mix)-1].Method
+}
+
+// methodDescription renders the configured method or method mix for display
+func methodDescription(config Config) string {
+	if len(config.MethodMix) == 0 {
+		return config.Method
+	}
+	parts := make([]string, len(config.MethodMix))
+	for i, m := range config.MethodMix {
+		parts[i] = fmt.Sprintf("%s:%d", m.Method, m.Weight)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// methodSendsBody reports whether a request of a mixed workload carries the
+// configured body; reads and deletes are sent without one
+func methodSendsBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// MethodStats summarizes the results of one method of a --methods mix
+type MethodStats struct {
+	Requests        int           `json:"requests"`
+	Successful      int           `json:"successful"`
+	Failed          int           `json:"failed"`
+	AvgResponseTime time.Duration `json:"avg_response_time"`
+	P95             time.Duration `json:"p95"`
+}
+
+// buildMethodStats breaks the results down by request method
+func buildMethodStats(results []Result, success func(Result) bool) map[string]MethodStats {
+	times := make(map[string][]time.Duration)
+	methods := make(map[string]MethodStats)
+	for _, result := range results {
+		m := methods[result.Method]
+		m.Requests++
+		if success(result) {
+			m.Successful++
+		} else {
+			m.Failed++
+		}
+		methods[result.Method] = m
+		times[result.Method] = append(times[result.Method], result.ResponseTime)
+	}
+
+	for method, durations := range times {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		var sum time.Duration
+		for _, d := range durations {
+			sum += d
+		}
+		m := methods[method]
+		m.AvgResponseTime = sum / time.Duration(len(durations))
+		m.P95 = percentile(durations, 95)
+		methods[method] = m
+	}
+	return methods
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseMethodMix(t *testing.T) {
+	mix, err := parseMethodMix("GET:70, post:20,PUT:10,HEAD,PATCH:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WeightedMethod{{"GET", 70}, {"POST", 20}, {"PUT", 10}, {"HEAD", 1}}
+	if !reflect.DeepEqual(mix, want) {
+		t.Errorf("parseMethodMix = %v, want %v", mix, want)
+	}
+
+	for _, bad := range []string{"", "GET:-1", "GET:x", ":5", "GET:0", "GET,get", "FETCH:1"} {
+		if _, err := parseMethodMix(bad); err == nil {
+			t.Errorf("parseMethodMix(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseMethodMixPercentages(t *testing.T) {
+	mix, err := parseMethodMix("GET:90%, POST:10%,PUT:0%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []WeightedMethod{{"GET", 90}, {"POST", 10}}; !reflect.DeepEqual(mix, want) {
+		t.Errorf("parseMethodMix = %v, want %v", mix, want)
+	}
+	for _, bad := range []string{"GET:90%,POST:20%", "GET:50%", "GET:90%,POST", "GET:90%,POST:10", "GET:90%,TRACE:10%", "GET:%"} {
+		if _, err := parseMethodMix(bad); err == nil {
+			t.Errorf("parseMethodMix(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestMethodMixFlagAlias(t *testing.T) {
+	defer newRunCmd()
+	cmd := newRunCmd()
+	if err := cmd.ParseFlags([]string{"--method-mix", "GET:90%,POST:10%"}); err != nil {
+		t.Fatal(err)
+	}
+	if methodMix != "GET:90%,POST:10%" || !cmd.Flags().Changed("methods") {
+		t.Errorf("--method-mix set --methods to %q (changed %v)", methodMix, cmd.Flags().Changed("methods"))
+	}
+}
+
+func TestMethodMixRun(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.Method] += len(b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Requests = 400
+	config.Body = "payload"
+	config.MethodMix = []WeightedMethod{{"GET", 3}, {"POST", 1}}
+
+	stats := newTestLoadTester(t, config).Run(nil)
+	get, post := stats.Methods["GET"], stats.Methods["POST"]
+	if get.Requests+post.Requests != 400 || len(stats.Methods) != 2 {
+		t.Fatalf("unexpected per-method breakdown: %+v", stats.Methods)
+	}
+	// 3:1 weights; allow plenty of slack for randomness
+	if get.Requests < 240 || get.Requests > 360 {
+		t.Errorf("GET got %d of 400 requests, want about 300", get.Requests)
+	}
+	if bodies["GET"] != 0 || bodies["POST"] != post.Requests*len("payload") {
+		t.Errorf("bodies sent per method = %v", bodies)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// otelDefaultEndpoint is the standard OTLP/HTTP collector address, used
+	// when neither --otel-endpoint nor OTEL_EXPORTER_OTLP_ENDPOINT is set
+	otelDefaultEndpoint = "http://localhost:4318"
+	// otelBatchSize and otelFlushInterval bound how many spans wait for export
+	// and for how long
+	otelBatchSize     = 512
+	otelFlushInterval = 2 * time.Second
+	otelTimeout       = 10 * time.Second
+
+	// OTLP span kind and status codes
+	otelSpanKindClient  = 3
+	otelStatusCodeError = 2
+)
+
+// otelTracesURL resolves where spans are posted: the OTLP/HTTP traces path of
+// endpoint, of OTEL_EXPORTER_OTLP_ENDPOINT when endpoint is empty, or of the
+// local default collector
+func otelTracesURL(endpoint string) string {
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = otelDefaultEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return endpoint + "/v1/traces"
+}
+
+// newTraceContext returns fresh W3C trace and span ids, hex encoded. Like
+// request ids they come from the unseeded source, so replays get new traces.
+func newTraceContext() (traceID, spanID string) {
+	var b [24]byte
+	for i := 0; i < len(b); i += 8 {
+		v := globalRand.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+	return hex.EncodeToString(b[:16]), hex.EncodeToString(b[16:])
+}
+
+// setTraceContext sends a traceparent header with --otel, making the request
+// the parent of whatever spans the server records, and returns its ids
+func (lt *LoadTester) setTraceContext(req *http.Request) (traceID, spanID string) {
+	if lt.otel == nil {
+		return "", ""
+	}
+	traceID, spanID = newTraceContext()
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+spanID+"-01")
+	return traceID, spanID
+}
+
+// OTLP/HTTP JSON encoding of the few span fields brutal fills in
+type (
+	otelExport struct {
+		ResourceSpans []otelResourceSpans `json:"resourceSpans"`
+	}
+	otelResourceSpans struct {
+		Resource   otelResource     `json:"resource"`
+		ScopeSpans []otelScopeSpans `json:"scopeSpans"`
+	}
+	otelResource struct {
+		Attributes []otelAttribute `json:"attributes"`
+	}
+	otelScopeSpans struct {
+		Scope otelScope  `json:"scope"`
+		Spans []otelSpan `json:"spans"`
+	}
+	otelScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otelSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otelAttribute `json:"attributes"`
+		Status            *otelStatus     `json:"status,omitempty"`
+	}
+	otelStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otelAttribute struct {
+		Key   string    `json:"key"`
+		Value otelValue `json:"value"`
+	}
+	otelValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func stringAttribute(key, value string) otelAttribute {
+	return otelAttribute{Key: key, Value: otelValue{StringValue: &value}}
+}
+
+// intAttribute encodes value as a string, as OTLP JSON does for 64-bit integers
+func intAttribute(key string, value int64) otelAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otelAttribute{Key: key, Value: otelValue{IntValue: &s}}
+}
+
+func millisAttribute(key string, d time.Duration) otelAttribute {
+	ms := float64(d) / float64(time.Millisecond)
+	return otelAttribute{Key: key, Value: otelValue{DoubleValue: &ms}}
+}
+
+// newSpan describes a completed request as a client span. It runs from
+// dispatch to completion, so retries and their backoff are inside it, and
+// carries the per-request timings as attributes.
+func (lt *LoadTester) newSpan(result Result, success bool) otelSpan {
+	attrs := []otelAttribute{
+		stringAttribute("http.request.method", result.Method),
+		stringAttribute("url.full", lt.config.URL),
+		intAttribute("brutal.seq", int64(result.Seq)),
+		intAttribute("brutal.worker", int64(result.WorkerID)),
+	}
+	if result.StatusCode > 0 {
+		attrs = append(attrs,
+			intAttribute("http.response.status_code", int64(result.StatusCode)),
+			intAttribute("http.response.body.size", result.ContentSize),
+			millisAttribute("brutal.ttfb_ms", result.TTFB),
+			millisAttribute("brutal.ttlb_ms", result.TTLB))
+	}
+	if result.DNSLookup > 0 {
+		attrs = append(attrs, millisAttribute("brutal.dns_ms", result.DNSLookup))
+	}
+	if result.Retries > 0 {
+		attrs = append(attrs, intAttribute("brutal.retries", int64(result.Retries)))
+	}
+	if result.RequestID != "" {
+		attrs = append(attrs, stringAttribute("brutal.request_id", result.RequestID))
+	}
+	if !success {
+		attrs = append(attrs, stringAttribute("error.type", errorCategory(result)))
+	}
+
+	span := otelSpan{
+		TraceID:           result.TraceID,
+		SpanID:            result.SpanID,
+		Name:              result.Method,
+		Kind:              otelSpanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(result.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(result.Timestamp.UnixNano(), 10),
+		Attributes:        attrs,
+	}
+	if !success {
+		span.Status = &otelStatus{Code: otelStatusCodeError}
+		if result.Error != nil {
+			span.Status.Message = result.Error.Error()
+		} else {
+			span.Status.Message = http.StatusText(result.StatusCode)
+		}
+	}
+	return span
+}
+
+// otelExporter batches request spans and posts them to an OTLP/HTTP
+// collector in the background, so a slow collector never holds up requests
+type otelExporter struct {
+	url     string
+	client  *http.Client
+	version string
+
+	mu       sync.Mutex
+	pending  []otelSpan
+	exported int
+	failed   int
+	lastErr  error
+
+	full chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startOtelExporter(endpoint string) *otelExporter {
+	ver, _, _ := resolvedBuildInfo()
+	e := &otelExporter{
+		url:     otelTracesURL(endpoint),
+		client:  &http.Client{Timeout: otelTimeout},
+		version: ver,
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(otelFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.flush()
+			case <-e.full:
+				e.flush()
+			case <-e.stop:
+				e.flush()
+				return
+			}
+		}
+	}()
+
+	return e
+}
+
+// add queues a span, waking the exporter once a batch is ready
+func (e *otelExporter) add(span otelSpan) {
+	e.mu.Lock()
+	e.pending = append(e.pending, span)
+	ready := len(e.pending) >= otelBatchSize
+	e.mu.Unlock()
+	if ready {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush posts the queued spans in batches of otelBatchSize
+func (e *otelExporter) flush() {
+	e.mu.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	for len(spans) > 0 {
+		n := min(len(spans), otelBatchSize)
+		err := e.post(spans[:n])
+
+		e.mu.Lock()
+		if err != nil {
+			if e.lastErr == nil {
+				logger.Warn("exporting spans failed", "url", e.url, "error", err)
+			}
+			e.failed += n
+			e.lastErr = err
+		} else {
+			e.exported += n
+		}
+		e.mu.Unlock()
+		spans = spans[n:]
+	}
+}
+
+func (e *otelExporter) post(spans []otelSpan) error {
+	payload, err := json.Marshal(otelExport{ResourceSpans: []otelResourceSpans{{
+		Resource: otelResource{Attributes: []otelAttribute{stringAttribute("service.name", "brutal")}},
+		ScopeSpans: []otelScopeSpans{{
+			Scope: otelScope{Name: "brutal", Version: e.version},
+			Spans: spans,
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Stop exports the remaining spans and returns how many were exported and
+// how many failed, with the last error
+func (e *otelExporter) Stop() (exported, failed int, err error) {
+	close(e.stop)
+	<-e.done
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.exported, e.failed, e.lastErr
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOtelTracesURL(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	tests := map[string]string{
+		"":                             "http://localhost:4318/v1/traces",
+		"http://collector:4318":        "http://collector:4318/v1/traces",
+		"http://collector:4318/":       "http://collector:4318/v1/traces",
+		"http://collector/x/v1/traces": "http://collector/x/v1/traces",
+	}
+	for endpoint, want := range tests {
+		if got := otelTracesURL(endpoint); got != want {
+			t.Errorf("otelTracesURL(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://otel.example.com")
+	if got := otelTracesURL(""); got != "https://otel.example.com/v1/traces" {
+		t.Errorf("otelTracesURL from the environment = %q", got)
+	}
+}
+
+func TestOtelExportsSpanPerRequest(t *testing.T) {
+	var mu sync.Mutex
+	traceparents := map[string]bool{}
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents[r.Header.Get("Traceparent")] = true
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer target.Close()
+
+	var spans []otelSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("spans posted to %s", r.URL.Path)
+		}
+		var export otelExport
+		if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+			t.Errorf("decoding export: %v", err)
+		}
+		mu.Lock()
+		spans = append(spans, export.ResourceSpans[0].ScopeSpans[0].Spans...)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	config := testConfig(target.URL + "/fail")
+	tester := newTestLoadTester(t, config)
+	tester.otel = startOtelExporter(collector.URL)
+	tester.Run(nil)
+	exported, failed, err := tester.otel.Stop()
+	if exported != config.Requests || failed != 0 || err != nil {
+		t.Fatalf("exported %d, failed %d (%v); want %d and 0", exported, failed, err, config.Requests)
+	}
+
+	if len(spans) != config.Requests {
+		t.Fatalf("collector got %d spans, want %d", len(spans), config.Requests)
+	}
+	for _, span := range spans {
+		if !traceparents["00-"+span.TraceID+"-"+span.SpanID+"-01"] {
+			t.Errorf("span %s/%s doesn't match a traceparent the target saw", span.TraceID, span.SpanID)
+		}
+		if len(span.TraceID) != 32 || len(span.SpanID) != 16 || span.Kind != otelSpanKindClient {
+			t.Errorf("malformed span %+v", span)
+		}
+		if span.Status == nil || span.Status.Code != otelStatusCodeError {
+			t.Errorf("status = %+v, want an error for a 500", span.Status)
+		}
+		start, _ := strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+		end, _ := strconv.ParseInt(span.EndTimeUnixNano, 10, 64)
+		if start == 0 || end <= start {
+			t.Errorf("span ends at %s, not after its start %s", span.EndTimeUnixNano, span.StartTimeUnixNano)
+		}
+	}
+}
+
+func TestNoTraceparentWithoutOtel(t *testing.T) {
+	var sent bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = sent || r.Header.Get("Traceparent") != ""
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Concurrent = 1
+	newTestLoadTester(t, config).Run(nil)
+	if sent {
+		t.Error("traceparent sent without --otel")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// outlierFactor flags requests slower than this multiple of the p99
+	outlierFactor = 5
+	// maxOutliers is how many outlier requests are listed, slowest first
+	maxOutliers = 20
+	// anomalousErrorRate flags seconds in which more requests failed than this
+	anomalousErrorRate = 0.5
+)
+
+// OutlierRequest is a request much slower than the rest of the run
+type OutlierRequest struct {
+	Seq          int           `json:"seq"`
+	Sent         time.Time     `json:"sent"`
+	StatusCode   int           `json:"status_code"`
+	ResponseTime time.Duration `json:"response_time"`
+	RequestID    string        `json:"request_id,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// AnomalousSecond is a full second of the run in which most requests failed
+type AnomalousSecond struct {
+	Second   int       `json:"second"`
+	At       time.Time `json:"at"`
+	Requests int       `json:"requests"`
+	Errors   int       `json:"errors"`
+}
+
+// Outliers calls out the requests over Threshold and the failing seconds
+type Outliers struct {
+	Threshold time.Duration    `json:"threshold"`
+	Requests  []OutlierRequest `json:"requests,omitempty"`
+	// Omitted counts outlier requests beyond maxOutliers
+	Omitted int               `json:"omitted,omitempty"`
+	Seconds []AnomalousSecond `json:"seconds,omitempty"`
+}
+
+// buildOutliers finds requests slower than outlierFactor times p99 and the
+// seconds of the timeline whose error rate exceeds anomalousErrorRate. The
+// last second is skipped as it is usually cut short. It returns nil when
+// there is nothing to report.
+func buildOutliers(results []Result, p99 time.Duration, timeline []TimelineBucket, start time.Time) *Outliers {
+	o := &Outliers{Threshold: outlierFactor * p99}
+	if p99 > 0 {
+		for _, result := range results {
+			if result.ResponseTime <= o.Threshold {
+				continue
+			}
+			sent := result.StartTime
+			if sent.IsZero() {
+				sent = result.Timestamp
+			}
+			o.Requests = append(o.Requests, OutlierRequest{
+				Seq:          result.Seq,
+				Sent:         sent,
+				StatusCode:   result.StatusCode,
+				ResponseTime: result.ResponseTime,
+				RequestID:    result.RequestID,
+				Error:        errorMessage(result.Error),
+			})
+		}
+		sort.Slice(o.Requests, func(i, j int) bool { return o.Requests[i].ResponseTime > o.Requests[j].ResponseTime })
+		if len(o.Requests) > maxOutliers {
+			o.Omitted = len(o.Requests) - maxOutliers
+			o.Requests = o.Requests[:maxOutliers]
+		}
+	}
+
+	for _, bucket := range timeline[:max(len(timeline)-1, 0)] {
+		if bucket.Requests > 0 && float64(bucket.Errors)/float64(bucket.Requests) > anomalousErrorRate {
+			o.Seconds = append(o.Seconds, AnomalousSecond{
+				Second:   bucket.Second,
+				At:       start.Add(time.Duration(bucket.Second) * time.Second),
+				Requests: bucket.Requests,
+				Errors:   bucket.Errors,
+			})
+		}
+	}
+
+	if len(o.Requests) == 0 && len(o.Seconds) == 0 {
+		return nil
+	}
+	return o
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuildOutliers(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var results []Result
+	for i := 0; i < 1000; i++ {
+		results = append(results, Result{
+			Seq:          i + 1,
+			StartTime:    start.Add(time.Duration(i) * 3 * time.Millisecond),
+			StatusCode:   200,
+			ResponseTime: 10 * time.Millisecond,
+		})
+	}
+	results[10].ResponseTime = 2 * time.Second
+	results[20].ResponseTime = 3 * time.Second
+	results[20].RequestID = "slow-one"
+	results[30].ResponseTime = 40 * time.Millisecond // slow but within 5x p99
+	// Second 1 (requests 334-666) mostly fails
+	for i := 334; i < 600; i++ {
+		results[i].StatusCode = 0
+		results[i].Error = errors.New("connection reset")
+	}
+
+	success := func(r Result) bool { return r.Error == nil }
+	timeline, _ := buildTimeline(results, start, success)
+	o := buildOutliers(results, 10*time.Millisecond, timeline, start)
+	if o == nil {
+		t.Fatal("no outliers found")
+	}
+	if o.Threshold != 50*time.Millisecond {
+		t.Errorf("threshold = %v, want 50ms", o.Threshold)
+	}
+	if len(o.Requests) != 2 || o.Requests[0].Seq != 21 || o.Requests[0].RequestID != "slow-one" || o.Requests[1].Seq != 11 {
+		t.Errorf("outlier requests = %+v, want #21 then #11", o.Requests)
+	}
+	if len(o.Seconds) != 1 || o.Seconds[0].Second != 1 || !o.Seconds[0].At.Equal(start.Add(time.Second)) {
+		t.Errorf("anomalous seconds = %+v, want second 1", o.Seconds)
+	}
+
+	if o := buildOutliers(results[:300], 10*time.Millisecond, timeline[:1], start); o != nil {
+		// The only second is the last one, which is skipped
+		if len(o.Seconds) != 0 {
+			t.Errorf("last second flagged: %+v", o.Seconds)
+		}
+	}
+	calm := []Result{{ResponseTime: time.Millisecond, StatusCode: 200}}
+	if o := buildOutliers(calm, time.Millisecond, nil, start); o != nil {
+		t.Errorf("outliers for a calm run = %+v", o)
+	}
+}
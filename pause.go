@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+)
+
+// pauseGate holds back new requests while the run is paused from the keyboard.
+// Requests already in flight finish normally.
+type pauseGate struct {
+	mu     sync.Mutex
+	resume chan struct{} // closed on resume, nil while running
+}
+
+// toggle pauses a running gate or resumes a paused one and reports whether it
+// is now paused
+func (g *pauseGate) toggle() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume != nil {
+		close(g.resume)
+		g.resume = nil
+		return false
+	}
+	g.resume = make(chan struct{})
+	return true
+}
+
+// paused reports whether the gate is holding back requests
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resume != nil
+}
+
+// wait blocks while the gate is paused. It returns false if cancel closes first.
+func (g *pauseGate) wait(cancel <-chan struct{}) bool {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// TogglePause stops issuing new requests, or resumes a paused run, and reports
+// whether the run is now paused
+func (lt *LoadTester) TogglePause() bool {
+	paused := lt.pause.toggle()
+	if paused {
+		logger.Info("run paused", "in_flight", lt.inFlight.current.Load())
+	} else {
+		logger.Info("run resumed")
+	}
+	return paused
+}
+
+// Paused reports whether the run is paused
+func (lt *LoadTester) Paused() bool {
+	return lt.pause.paused()
+}
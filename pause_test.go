@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseHoldsNewRequests(t *testing.T) {
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+	}))
+	defer srv.Close()
+
+	tester := newTestLoadTester(t, testConfig(srv.URL))
+	if !tester.TogglePause() || !tester.Paused() {
+		t.Fatal("TogglePause did not pause")
+	}
+	done := make(chan *Stats)
+	go func() { done <- tester.Run(nil) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := served.Load(); n != 0 {
+		t.Fatalf("%d requests sent while paused", n)
+	}
+
+	if tester.TogglePause() {
+		t.Fatal("second TogglePause did not resume")
+	}
+	select {
+	case stats := <-done:
+		if stats.TotalRequests != 50 {
+			t.Errorf("TotalRequests = %d after resuming, want 50", stats.TotalRequests)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not finish after resuming")
+	}
+}
+
+func TestPauseGateCancel(t *testing.T) {
+	var g pauseGate
+	g.toggle()
+	cancel := make(chan struct{})
+	close(cancel)
+	if g.wait(cancel) {
+		t.Error("wait on a paused gate returned true after cancel")
+	}
+}
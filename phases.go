@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phase is one step of a --phases scenario: a named stretch of the run with
+// its own request rate and think time
+type Phase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	RPS      float64       `json:"rps"`
+	// ThinkMin and ThinkMax bound the pause each request keeps its
+	// concurrency slot for after the response, like a user reading a page
+	ThinkMin time.Duration `json:"think_min,omitempty"`
+	ThinkMax time.Duration `json:"think_max,omitempty"`
+}
+
+// loadPhases reads a scenario with one "name duration targetRPS [thinkTime]"
+// row per phase. The think time is a duration or a "min-max" range drawn from
+// uniformly per request. Fields are separated like a --profile.
+func loadPhases(filename string) ([]Phase, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var phases []Phase
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) != 3 && len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: want \"name duration targetRPS [thinkTime]\", got %q", lineNo, line)
+		}
+		phase := Phase{Name: fields[0]}
+		if seen[phase.Name] {
+			return nil, fmt.Errorf("line %d: phase %q listed twice", lineNo, phase.Name)
+		}
+		seen[phase.Name] = true
+		if phase.Duration, err = parseOffset(fields[1]); err != nil || phase.Duration <= 0 {
+			return nil, fmt.Errorf("line %d: invalid duration %q", lineNo, fields[1])
+		}
+		phase.RPS, err = strconv.ParseFloat(fields[2], 64)
+		if err != nil || phase.RPS < 0 || math.IsInf(phase.RPS, 0) || math.IsNaN(phase.RPS) {
+			return nil, fmt.Errorf("line %d: invalid target RPS %q", lineNo, fields[2])
+		}
+		if len(fields) == 4 {
+			if phase.ThinkMin, phase.ThinkMax, err = parseThinkTime(fields[3]); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+		}
+		phases = append(phases, phase)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("%s has no phases", filename)
+	}
+	return phases, nil
+}
+
+// parseThinkTime parses "2s" or a "1s-3s" range
+func parseThinkTime(s string) (lo, hi time.Duration, err error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if lo, err = parseOffset(from); err != nil {
+		return 0, 0, fmt.Errorf("invalid think time %q", s)
+	}
+	hi = lo
+	if isRange {
+		if hi, err = parseOffset(to); err != nil || hi < lo {
+			return 0, 0, fmt.Errorf("invalid think time range %q", s)
+		}
+	}
+	return lo, hi, nil
+}
+
+// phasesProfile turns phases into a rate profile that holds each phase's rate
+// for its duration and steps to the next one, ending with the last phase
+func phasesProfile(phases []Phase) []RatePoint {
+	var points []RatePoint
+	var at time.Duration
+	for _, phase := range phases {
+		points = append(points, RatePoint{At: at, RPS: phase.RPS})
+		at += phase.Duration
+		// One nanosecond before the next phase, so the rate steps instead of ramping
+		points = append(points, RatePoint{At: at - 1, RPS: phase.RPS})
+	}
+	points[len(points)-1].At = at
+	return points
+}
+
+// phaseAt returns the index of the phase running at elapsed, the last one
+// past the end
+func phaseAt(phases []Phase, elapsed time.Duration) int {
+	var end time.Duration
+	for i, phase := range phases {
+		end += phase.Duration
+		if elapsed < end {
+			return i
+		}
+	}
+	return len(phases) - 1
+}
+
+// thinkTime draws the pause after a request that started at elapsed, 0 without phases
+func (lt *LoadTester) thinkTime(elapsed time.Duration, rng *rand.Rand) time.Duration {
+	if len(lt.config.Phases) == 0 {
+		return 0
+	}
+	phase := lt.config.Phases[phaseAt(lt.config.Phases, elapsed)]
+	if phase.ThinkMax <= phase.ThinkMin {
+		return phase.ThinkMin
+	}
+	return phase.ThinkMin + time.Duration(rng.Int64N(int64(phase.ThinkMax-phase.ThinkMin)+1))
+}
+
+// PhaseStats summarises the requests sent during one phase
+type PhaseStats struct {
+	Name        string                `json:"name"`
+	Start       time.Duration         `json:"start"`
+	Duration    time.Duration         `json:"duration"`
+	Requests    int                   `json:"requests"`
+	Failed      int                   `json:"failed"`
+	RPS         float64               `json:"rps"`
+	Percentiles map[int]time.Duration `json:"percentiles,omitempty"`
+}
+
+// buildPhaseStats segments the results by the phase they were sent in
+func buildPhaseStats(results []Result, phases []Phase, start time.Time, success func(Result) bool) []PhaseStats {
+	stats := make([]PhaseStats, len(phases))
+	times := make([][]time.Duration, len(phases))
+	var at time.Duration
+	for i, phase := range phases {
+		stats[i] = PhaseStats{Name: phase.Name, Start: at, Duration: phase.Duration}
+		at += phase.Duration
+	}
+	for _, result := range results {
+		sent := result.StartTime
+		if sent.IsZero() {
+			sent = result.Timestamp
+		}
+		i := phaseAt(phases, sent.Sub(start))
+		stats[i].Requests++
+		if !success(result) {
+			stats[i].Failed++
+		}
+		times[i] = append(times[i], result.ResponseTime)
+	}
+	for i := range stats {
+		stats[i].RPS = float64(stats[i].Requests) / stats[i].Duration.Seconds()
+		stats[i].Percentiles = percentilesOf(times[i])
+	}
+	return stats
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadPhases(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "phases.txt")
+	os.WriteFile(file, []byte("# name duration rps think\nbrowse 2m 50 1s-3s\ncheckout,60,10,500ms\nrest 30s 0\n"), 0644)
+	phases, err := loadPhases(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Phase{
+		{Name: "browse", Duration: 2 * time.Minute, RPS: 50, ThinkMin: time.Second, ThinkMax: 3 * time.Second},
+		{Name: "checkout", Duration: time.Minute, RPS: 10, ThinkMin: 500 * time.Millisecond, ThinkMax: 500 * time.Millisecond},
+		{Name: "rest", Duration: 30 * time.Second},
+	}
+	if !reflect.DeepEqual(phases, want) {
+		t.Errorf("loadPhases = %+v, want %+v", phases, want)
+	}
+
+	for _, bad := range []string{"a 1m", "a 0 10", "a 1m -1", "a 1m 10 3s-1s", "a 1m 10\na 1m 5", ""} {
+		os.WriteFile(file, []byte(bad), 0644)
+		if _, err := loadPhases(file); err == nil {
+			t.Errorf("loadPhases(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPhasesProfileSteps(t *testing.T) {
+	phases := []Phase{{Name: "a", Duration: time.Second, RPS: 10}, {Name: "b", Duration: time.Second, RPS: 40}}
+	profile := phasesProfile(phases)
+	for _, tt := range []struct {
+		at   time.Duration
+		rps  float64
+		done bool
+	}{
+		{500 * time.Millisecond, 10, false},
+		{999 * time.Millisecond, 10, false},
+		{time.Second, 40, false},
+		{1900 * time.Millisecond, 40, false},
+		{2 * time.Second, 0, true},
+	} {
+		if rps, done := rateAt(profile, tt.at); rps != tt.rps || done != tt.done {
+			t.Errorf("rate at %v = %v, %v; want %v, %v", tt.at, rps, done, tt.rps, tt.done)
+		}
+	}
+}
+
+func TestRunPhases(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 10000
+	config.Concurrent = 2
+	config.Phases = []Phase{
+		{Name: "slow", Duration: 400 * time.Millisecond, RPS: 50},
+		// 20ms of think time caps each of the 2 slots below 50 rps, short of the target
+		{Name: "fast", Duration: 400 * time.Millisecond, RPS: 200, ThinkMin: 20 * time.Millisecond, ThinkMax: 20 * time.Millisecond},
+	}
+	config.Profile = phasesProfile(config.Phases)
+	stats := newTestLoadTester(t, config).Run(nil)
+
+	if len(stats.Phases) != 2 {
+		t.Fatalf("phases = %+v", stats.Phases)
+	}
+	slow, fast := stats.Phases[0], stats.Phases[1]
+	if slow.Requests < 15 || slow.Requests > 25 {
+		t.Errorf("slow phase sent %d requests, want about 20", slow.Requests)
+	}
+	if fast.Requests < 20 || fast.Requests > 45 {
+		t.Errorf("fast phase sent %d requests, want under 40 held back by think time", fast.Requests)
+	}
+	if slow.Requests+fast.Requests != stats.TotalRequests {
+		t.Errorf("phases cover %d of %d requests", slow.Requests+fast.Requests, stats.TotalRequests)
+	}
+}
+
+func TestThinkTimeRange(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.invalid"))
+	tester.config.Phases = []Phase{{Name: "a", Duration: time.Minute, ThinkMin: time.Second, ThinkMax: 2 * time.Second}}
+	rng := testRand()
+	for i := 0; i < 100; i++ {
+		if d := tester.thinkTime(time.Second, rng); d < time.Second || d > 2*time.Second {
+			t.Fatalf("think time %v outside 1s-2s", d)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// errPinMismatch marks handshakes whose certificate chain matched none of the
+// --pin-sha256 pins
+var errPinMismatch = errors.New("certificate doesn't match any pinned key")
+
+// parsePins decodes base64 SHA-256 public key pins, as printed by
+// openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func parsePins(pins []string) ([][]byte, error) {
+	decoded := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid pin %q: want the base64 SHA-256 of a public key", pin)
+		}
+		decoded = append(decoded, hash)
+	}
+	return decoded, nil
+}
+
+// publicKeyPin returns the base64 SHA-256 of cert's SubjectPublicKeyInfo
+func publicKeyPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// verifyPins returns a VerifyPeerCertificate callback accepting the handshake
+// when any certificate the server presented matches one of pins. It runs
+// after normal verification, so pinning doesn't replace it unless --insecure.
+func verifyPins(pins [][]byte) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		var leaf string
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range pins {
+				if bytes.Equal(hash[:], pin) {
+					return nil
+				}
+			}
+			if i == 0 {
+				leaf = publicKeyPin(cert)
+			}
+		}
+		return fmt.Errorf("%w (server key %s)", errPinMismatch, leaf)
+	}
+}
+
+// buildTLSConfig returns the client TLS settings for config, or nil when the
+// defaults apply. It fails when a pin is invalid.
+func buildTLSConfig(config Config) (*tls.Config, error) {
+	if !config.InsecureTLS && len(config.PinSHA256) == 0 {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: config.InsecureTLS}
+	if len(config.PinSHA256) > 0 {
+		pins, err := parsePins(config.PinSHA256)
+		if err != nil {
+			return nil, err
+		}
+		cfg.VerifyPeerCertificate = verifyPins(pins)
+	}
+	return cfg, nil
+}
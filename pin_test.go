@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPinSHA256(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	pin := publicKeyPin(srv.Certificate())
+
+	config := testConfig(srv.URL)
+	// The test certificate is self-signed, so only the pin is checked
+	config.InsecureTLS = true
+	config.PinSHA256 = []string{"uUcPDtOLOcHAYw5oWNBh7ToEXgYMDrZu5LY8XqQKeL0=", pin}
+	if result := newTestLoadTester(t, config).makeRequest(testRand()); result.Error != nil || result.StatusCode != http.StatusOK {
+		t.Fatalf("matching pin: status %d, error %v", result.StatusCode, result.Error)
+	}
+	config.ConnectOnly = true
+	if result := newTestLoadTester(t, config).execute(testRand()); result.Error != nil {
+		t.Fatalf("matching pin in connect-only mode: %v", result.Error)
+	}
+
+	config.ConnectOnly = false
+	config.PinSHA256 = []string{"uUcPDtOLOcHAYw5oWNBh7ToEXgYMDrZu5LY8XqQKeL0="}
+	result := newTestLoadTester(t, config).makeRequest(testRand())
+	if category := errorCategory(result); category != "tls_pin" {
+		t.Errorf("category = %q (error %v), want tls_pin", category, result.Error)
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	if _, err := parsePins([]string{"uUcPDtOLOcHAYw5oWNBh7ToEXgYMDrZu5LY8XqQKeL0="}); err != nil {
+		t.Errorf("valid pin rejected: %v", err)
+	}
+	for _, pin := range []string{"not base64!", "c2hvcnQ="} {
+		if _, err := parsePins([]string{pin}); err == nil {
+			t.Errorf("parsePins(%q) succeeded", pin)
+		}
+	}
+}
+
+func TestInvalidPinIsAnError(t *testing.T) {
+	defer newRunCmd()
+	cmd := newRunCmd()
+	if err := cmd.ParseFlags([]string{"https://example.com", "--pin-sha256", "c2hvcnQ="}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildConfig(cmd.Flags().Args()); err == nil {
+		t.Error("buildConfig accepted an invalid pin")
+	}
+
+	config := testConfig("https://example.com")
+	config.PinSHA256 = []string{"c2hvcnQ="}
+	config.ConnectOnly = true
+	if _, err := NewLoadTester(config); err == nil {
+		t.Error("NewLoadTester accepted an invalid pin")
+	}
+}
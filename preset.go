@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// preset is the defaults of a common kind of test: flag values as typed on
+// the command line, and the rate profile or phases it runs at. Paced presets
+// end with their profile; the request count only needs to be large enough.
+type preset struct {
+	flags   map[string]string
+	profile []RatePoint
+	phases  []Phase
+}
+
+var presets = map[string]preset{
+	// smoke checks the target works: one connection, ten requests, all of
+	// which must succeed
+	"smoke": {flags: map[string]string{"concurrent": "1", "requests": "10", "strict": "true"}},
+	// load sustains 50 requests per second for five minutes
+	"load": {
+		flags:   map[string]string{"concurrent": "50", "requests": "1000000"},
+		profile: steadyRate(50, 5*time.Minute),
+	},
+	// stress doubles the rate every two minutes from 50 to 800 requests per
+	// second, with up to 200 in flight. It runs every step; the PHASES section
+	// shows the step where failures start or the rate stops keeping up.
+	"stress": {
+		flags:  map[string]string{"concurrent": "200", "requests": "10000000"},
+		phases: steppedRate(50, 5, 2*time.Minute),
+	},
+	// soak holds 10 requests per second for an hour, logging progress every minute
+	"soak": {
+		flags: map[string]string{"concurrent": "10", "requests": "10000000",
+			"progress": progressLines, "progress-interval": "1m"},
+		profile: steadyRate(10, time.Hour),
+	},
+}
+
+// steadyRate is a rate profile holding rps for d
+func steadyRate(rps float64, d time.Duration) []RatePoint {
+	return []RatePoint{{At: 0, RPS: rps}, {At: d, RPS: rps}}
+}
+
+// steppedRate is n phases of length d, the first at rps and each one after
+// at double the rate of the one before
+func steppedRate(rps float64, n int, d time.Duration) []Phase {
+	phases := make([]Phase, n)
+	for i := range phases {
+		phases[i] = Phase{Name: fmt.Sprintf("step-%d", i+1), Duration: d, RPS: rps}
+		rps *= 2
+	}
+	return phases
+}
+
+// presetNames lists the presets for help and error messages
+func presetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// applyPreset sets the flags of the named preset that weren't given
+// explicitly, and its rate unless --profile, --phases or the --config
+// already sets one
+func applyPreset(flags *pflag.FlagSet, name string) error {
+	p, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown --preset %q: must be one of %s", name, presetNames())
+	}
+	if err := setUnchangedFlags(flags, p.flags); err != nil {
+		return fmt.Errorf("preset %s: %v", name, err)
+	}
+	if !flags.Changed("profile") && !flags.Changed("phases") &&
+		len(configSettings.profile) == 0 && len(configSettings.phases) == 0 {
+		configSettings.profile, configSettings.phases = p.profile, p.phases
+	}
+	return nil
+}
+
+// setUnchangedFlags sets each flag in values unless it was given explicitly;
+// a list flag takes a comma-separated value as its whole list. Setting marks
+// a flag as changed, so values applied first take precedence.
+func setUnchangedFlags(flags *pflag.FlagSet, values map[string]string) error {
+	for flag, value := range values {
+		if flags.Changed(flag) {
+			continue
+		}
+		if f := flags.Lookup(flag); f != nil {
+			if list, ok := f.Value.(pflag.SliceValue); ok {
+				if err := list.Replace(strings.Split(value, ",")); err != nil {
+					return err
+				}
+				f.Changed = true
+				continue
+			}
+		}
+		if err := flags.Set(flag, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
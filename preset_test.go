@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyPresetKeepsExplicitFlags(t *testing.T) {
+	cmd := newRunCmd()
+	if err := cmd.ParseFlags([]string{"-c", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyPreset(cmd.Flags(), "soak"); err != nil {
+		t.Fatal(err)
+	}
+	if concurrent != 3 {
+		t.Errorf("concurrent = %d, want the explicit 3", concurrent)
+	}
+	if progressMode != progressLines || progressEvery != time.Minute {
+		t.Errorf("preset not applied: progress %s every %v", progressMode, progressEvery)
+	}
+}
+
+func TestApplyPresetRejectsUnknown(t *testing.T) {
+	if err := applyPreset(newRunCmd().Flags(), "extreme"); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+}
+
+func TestPresetRates(t *testing.T) {
+	defer newRunCmd()
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "profile.csv")
+	if err := os.WriteFile(profile, []byte("0 5\n10s 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "saved.json")
+	if err := os.WriteFile(saved, []byte(`{"url":"http://saved","profile":[{"at":0,"rps":7},{"at":60000000000,"rps":7}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, preset string
+		args         []string
+		wantProfile  []RatePoint
+		wantPhases   []string
+	}{
+		{"load is a steady rate", "load", nil, steadyRate(50, 5*time.Minute), nil},
+		{"stress steps its rate", "stress", nil, phasesProfile(steppedRate(50, 5, 2*time.Minute)),
+			[]string{"step-1", "step-2", "step-3", "step-4", "step-5"}},
+		{"smoke is unpaced", "smoke", nil, nil, nil},
+		{"--profile wins", "stress", []string{"--profile", profile}, []RatePoint{{0, 5}, {10 * time.Second, 5}}, nil},
+		{"--config wins", "load", []string{"--config", saved}, steadyRate(7, time.Minute), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := newRunCmd()
+			if err := cmd.ParseFlags(append([]string{"http://example.com"}, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if configFile != "" {
+				if err := applyConfigFile(cmd.Flags(), configFile, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := applyPreset(cmd.Flags(), tc.preset); err != nil {
+				t.Fatal(err)
+			}
+			config, err := buildConfig(cmd.Flags().Args())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(config.Profile, tc.wantProfile) {
+				t.Errorf("profile = %v, want %v", config.Profile, tc.wantProfile)
+			}
+			var phases []string
+			for _, phase := range config.Phases {
+				phases = append(phases, phase.Name)
+			}
+			if !reflect.DeepEqual(phases, tc.wantPhases) {
+				t.Errorf("phases = %v, want %v", phases, tc.wantPhases)
+			}
+		})
+	}
+	if p := steppedRate(50, 5, 2*time.Minute); p[4].RPS != 800 {
+		t.Errorf("last stress step at %v rps, want 800", p[4].RPS)
+	}
+}
+
+func TestSmokePresetIsStrict(t *testing.T) {
+	defer newRunCmd()
+	srv := newTestServer(t, 4)
+	root := newRootCmd()
+	root.SetArgs([]string{"run", srv.URL, "--preset", "smoke", "--quiet", "--no-final-summary"})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "2 of 10 requests failed") {
+		t.Errorf("error = %v, want the smoke run to fail on its failed requests", err)
+	}
+
+	ok := newTestServer(t, 0)
+	root = newRootCmd()
+	root.SetArgs([]string{"run", ok.URL, "--preset", "smoke", "--quiet", "--no-final-summary"})
+	if err := root.Execute(); err != nil {
+		t.Errorf("passing smoke run failed: %v", err)
+	}
+}
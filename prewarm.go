@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// connPool hands out connections dialed before the test started. The
+// transport's dial functions take from it first and fall back to dialing.
+type connPool struct {
+	mu        sync.Mutex
+	conns     map[string][]net.Conn
+	dialer    *net.Dialer
+	tlsConfig *tls.Config
+}
+
+func newConnPool(dialer *net.Dialer, tlsConfig *tls.Config) *connPool {
+	return &connPool{
+		conns:     make(map[string][]net.Conn),
+		dialer:    dialer,
+		tlsConfig: tlsConfig,
+	}
+}
+
+func (p *connPool) put(addr string, conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[addr] = append(p.conns[addr], conn)
+}
+
+func (p *connPool) take(addr string) net.Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.conns[addr]
+	if len(conns) == 0 {
+		return nil
+	}
+	conn := conns[len(conns)-1]
+	p.conns[addr] = conns[:len(conns)-1]
+	return conn
+}
+
+// closeAll closes connections that were never handed out
+func (p *connPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conns := range p.conns {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		delete(p.conns, addr)
+	}
+}
+
+// dial opens a connection to addr, completing the TLS handshake when useTLS is set
+func (p *connPool) dial(ctx context.Context, addr string, useTLS bool) (net.Conn, error) {
+	if useTLS {
+		return (&tls.Dialer{NetDialer: p.dialer, Config: tlsConfigFor(p.tlsConfig, addr)}).DialContext(ctx, "tcp", addr)
+	}
+	return p.dialer.DialContext(ctx, "tcp", addr)
+}
+
+// install routes the transport's dials through the pool. For https targets the
+// pooled connections have already completed the TLS handshake. Because the
+// TLS dial is custom, requests use HTTP/1.1.
+func (p *connPool) install(transport *http.Transport) {
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if conn := p.take(addr); conn != nil {
+			return conn, nil
+		}
+		return p.dial(ctx, addr, false)
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if conn := p.take(addr); conn != nil {
+			return conn, nil
+		}
+		return p.dial(ctx, addr, true)
+	}
+}
+
+// tlsConfigFor returns a copy of base with ServerName set from addr when unset
+func tlsConfigFor(base *tls.Config, addr string) *tls.Config {
+	var cfg *tls.Config
+	if base != nil {
+		cfg = base.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			cfg.ServerName = host
+		}
+	}
+	return cfg
+}
+
+// Prewarm resolves the target and opens config.Concurrent connections to it
+// (completing the TLS handshake for https) before the measured run, so the
+// first requests don't pay for cold DNS and connection setup. It returns the
+// number of connections established.
+func (lt *LoadTester) Prewarm() (int, error) {
+	if lt.prewarmed == nil {
+		return 0, fmt.Errorf("connection prewarming is not enabled")
+	}
+
+	u, err := url.Parse(lt.config.URL)
+	if err != nil {
+		return 0, err
+	}
+	if u.Hostname() == "" {
+		return 0, fmt.Errorf("URL has no host: %s", lt.config.URL)
+	}
+	addr := targetAddr(u)
+
+	// Resolve once up front so a DNS failure is reported clearly and the
+	// system resolver cache is warm
+	if _, err := lt.resolver().LookupHost(context.Background(), u.Hostname()); err != nil {
+		return 0, fmt.Errorf("DNS lookup failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	established := 0
+
+	for i := 0; i < lt.config.Concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), lt.prewarmTimeout())
+			defer cancel()
+
+			conn, err := lt.prewarmed.dial(ctx, addr, u.Scheme == "https")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			established++
+			lt.prewarmed.put(addr, conn)
+		}()
+	}
+	wg.Wait()
+
+	if established == 0 && firstErr != nil {
+		return 0, firstErr
+	}
+	return established, nil
+}
+
+func (lt *LoadTester) prewarmTimeout() time.Duration {
+	if lt.config.Timeout > 0 {
+		return lt.config.Timeout
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrewarmReusesConnections(t *testing.T) {
+	var newConns int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&newConns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.PrewarmConns = true
+	tester := newTestLoadTester(t, config)
+
+	n, err := tester.Prewarm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != config.Concurrent {
+		t.Fatalf("prewarmed %d connections, want %d", n, config.Concurrent)
+	}
+
+	stats := tester.Run(nil)
+	if stats.SuccessfulReqs != config.Requests {
+		t.Errorf("successful = %d, want %d", stats.SuccessfulReqs, config.Requests)
+	}
+	if got := atomic.LoadInt64(&newConns); got != int64(config.Concurrent) {
+		t.Errorf("server saw %d connections, want only the %d prewarmed ones", got, config.Concurrent)
+	}
+}
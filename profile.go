@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RatePoint is one row of a --profile: the target request rate at an offset
+// from the start of the run
+type RatePoint struct {
+	At  time.Duration `json:"at"`
+	RPS float64       `json:"rps"`
+}
+
+// loadRateProfile reads a profile with one "timeOffset targetRPS" row per line.
+// Offsets are durations ("90s", "2m") or plain seconds; fields may be separated
+// by whitespace or a comma, and lines starting with # are ignored.
+func loadRateProfile(filename string) ([]RatePoint, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var points []RatePoint
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"timeOffset targetRPS\", got %q", lineNo, line)
+		}
+		at, err := parseOffset(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time offset %q", lineNo, fields[0])
+		}
+		rps, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || rps < 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+			return nil, fmt.Errorf("line %d: invalid target RPS %q", lineNo, fields[1])
+		}
+		if len(points) > 0 && at <= points[len(points)-1].At {
+			return nil, fmt.Errorf("line %d: time offsets must increase", lineNo)
+		}
+		points = append(points, RatePoint{At: at, RPS: rps})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("%s needs at least two rows", filename)
+	}
+	return points, nil
+}
+
+// parseOffset accepts a Go duration or a plain number of seconds
+func parseOffset(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return d, nil
+}
+
+// rateAt interpolates the target rate at elapsed linearly between profile
+// points. Before the first point its rate applies; done reports that elapsed
+// is past the last point.
+func rateAt(points []RatePoint, elapsed time.Duration) (rps float64, done bool) {
+	if elapsed >= points[len(points)-1].At {
+		return 0, true
+	}
+	if elapsed <= points[0].At {
+		return points[0].RPS, false
+	}
+	for i := 1; i < len(points); i++ {
+		if elapsed < points[i].At {
+			from, to := points[i-1], points[i]
+			frac := float64(elapsed-from.At) / float64(to.At-from.At)
+			return from.RPS + (to.RPS-from.RPS)*frac, false
+		}
+	}
+	return 0, true
+}
+
+// profileTick is how often the controller re-evaluates the target rate
+const profileTick = 100 * time.Millisecond
+
+// ratePacer spaces request starts to follow a rate profile. A controller
+// goroutine updates the target rate; workers call wait before each request.
+type ratePacer struct {
+	points []RatePoint
+	start  time.Time
+
+	rate atomic.Uint64 // math.Float64bits of the current target RPS
+	done atomic.Bool
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+	// slot is when the profile meant the next request to start. Unlike next
+	// it doesn't skip ahead when dispatching falls behind, so it keeps the
+	// time lost waiting for a free slot.
+	slot time.Time
+	stop chan struct{}
+}
+
+func newRatePacer(points []RatePoint) *ratePacer {
+	return &ratePacer{points: points, stop: make(chan struct{})}
+}
+
+// run starts the controller goroutine; call close to stop it
+func (p *ratePacer) run() {
+	p.start = time.Now()
+	p.next = p.start
+	p.slot = p.start
+	p.update()
+	stop := p.stop
+	go func() {
+		ticker := time.NewTicker(profileTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !p.update() {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// update sets the target rate for now and reports whether the profile is still running
+func (p *ratePacer) update() bool {
+	rps, done := rateAt(p.points, time.Since(p.start))
+	p.rate.Store(math.Float64bits(rps))
+	if done {
+		p.done.Store(true)
+	}
+	return !done
+}
+
+func (p *ratePacer) close() {
+	close(p.stop)
+}
+
+// reset prepares a closed pacer to follow the profile again from the start
+func (p *ratePacer) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop = make(chan struct{})
+	p.done.Store(false)
+	p.rate.Store(0)
+}
+
+// runLimit returns the longest the run can last: the --max-duration cap or
+// the end of the rate profile, whichever is sooner, or 0 when neither is set
+func (lt *LoadTester) runLimit() time.Duration {
+	return runLimitOf(lt.config)
+}
+
+// runLimitOf is runLimit for a config that has no tester yet
+func runLimitOf(config Config) time.Duration {
+	limit := config.MaxDuration
+	if n := len(config.Profile); n > 0 {
+		if end := config.Profile[n-1].At; limit == 0 || end < limit {
+			limit = end
+		}
+	}
+	return limit
+}
+
+// TargetRPS returns the rate the profile currently targets, lowered to a
+// --control cap, or 0 with neither
+func (lt *LoadTester) TargetRPS() float64 {
+	target := lt.rateCap.rate()
+	if lt.pacer != nil {
+		if rps := lt.pacer.currentRate(); target == 0 || rps < target {
+			target = rps
+		}
+	}
+	return target
+}
+
+// behind estimates how many requests the schedule has made due that haven't
+// been dispatched yet, held back for a free concurrency slot
+func (p *ratePacer) behind(now time.Time) int {
+	p.mu.Lock()
+	slot := p.slot
+	p.mu.Unlock()
+	if slot.IsZero() || !slot.Before(now) {
+		return 0
+	}
+	return int(now.Sub(slot).Seconds() * p.currentRate())
+}
+
+// Backlog returns the requests a --profile has scheduled that haven't
+// completed: those in flight and those due but waiting for a slot. It is 0
+// without a profile, whose backlog is only ever what is in flight.
+func (lt *LoadTester) Backlog() int {
+	if lt.pacer == nil {
+		return 0
+	}
+	return lt.InFlight() + lt.pacer.behind(time.Now())
+}
+
+// currentRate returns the target rate last set by the controller
+func (p *ratePacer) currentRate() float64 {
+	return math.Float64frombits(p.rate.Load())
+}
+
+// wait blocks until the next request may start and returns the start the
+// profile scheduled for it. It returns false once the profile has ended or
+// cancel is closed.
+func (p *ratePacer) wait(cancel <-chan struct{}) (time.Time, bool) {
+	paused := false
+	for {
+		if p.done.Load() {
+			return time.Time{}, false
+		}
+		rps := p.currentRate()
+		if rps <= 0 {
+			// Paused: check again once the controller may have raised the rate
+			paused = true
+			select {
+			case <-time.After(profileTick):
+				continue
+			case <-cancel:
+				return time.Time{}, false
+			}
+		}
+
+		p.mu.Lock()
+		now := time.Now()
+		// Don't bank the time spent paused or saturated as a burst
+		if p.next.Before(now) {
+			p.next = now
+		}
+		// A profile asking for no requests has no schedule to fall behind
+		if paused {
+			p.slot = p.next
+		}
+		at, slot := p.next, p.slot
+		interval := time.Duration(float64(time.Second) / rps)
+		p.next = p.next.Add(interval)
+		p.slot = p.slot.Add(interval)
+		p.mu.Unlock()
+
+		if delay := time.Until(at); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-cancel:
+				return time.Time{}, false
+			}
+		}
+		return slot, !p.done.Load()
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeProfile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profile.csv")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRateProfile(t *testing.T) {
+	points, err := loadRateProfile(writeProfile(t, "# comment\n0 10\n30s,100\n\n1.5m\t0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RatePoint{{0, 10}, {30 * time.Second, 100}, {90 * time.Second, 0}}
+	if len(points) != len(want) {
+		t.Fatalf("got %v, want %v", points, want)
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("point %d = %v, want %v", i, points[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"0 10\n", "0 10\n5s\n", "0 10\n5s -1\n", "5s 10\n1s 10\n", "0 10\nsoon 5\n"} {
+		if _, err := loadRateProfile(writeProfile(t, bad)); err == nil {
+			t.Errorf("loadRateProfile(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestRateAt(t *testing.T) {
+	points := []RatePoint{{time.Second, 10}, {3 * time.Second, 30}, {4 * time.Second, 0}}
+	tests := []struct {
+		at   time.Duration
+		rps  float64
+		done bool
+	}{
+		{0, 10, false},
+		{2 * time.Second, 20, false},
+		{3500 * time.Millisecond, 15, false},
+		{4 * time.Second, 0, true},
+	}
+	for _, tt := range tests {
+		rps, done := rateAt(points, tt.at)
+		if rps != tt.rps || done != tt.done {
+			t.Errorf("rateAt(%v) = %v, %v; want %v, %v", tt.at, rps, done, tt.rps, tt.done)
+		}
+	}
+}
+
+func TestRunFollowsRateProfile(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 10000
+	// 100 rps for half a second, then a linear drop to 0 over the next half
+	config.Profile = []RatePoint{{0, 100}, {500 * time.Millisecond, 100}, {time.Second, 0}}
+
+	stats := newTestLoadTester(t, config).Run(nil)
+	if !stats.ProfileFinished {
+		t.Error("expected the run to end with the profile")
+	}
+	// 50 requests in the flat part and 25 on the ramp down
+	if stats.TotalRequests < 60 || stats.TotalRequests > 90 {
+		t.Errorf("sent %d requests, want about 75", stats.TotalRequests)
+	}
+}
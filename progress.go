@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressInterval is how often the live progress line is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// Progress display modes selectable with --progress
+const (
+	progressAuto     = "auto"     // bar on a terminal, interval otherwise
+	progressBar      = "bar"      // single line redrawn in place with \r
+	progressLines    = "interval" // one complete line every --progress-interval, for CI logs
+	progressDisabled = "none"
+)
+
+// resolveProgressMode validates a --progress value and resolves auto by
+// whether stdout is a terminal
+func resolveProgressMode(mode string) (string, error) {
+	switch mode {
+	case progressAuto, "":
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			return progressBar, nil
+		}
+		return progressLines, nil
+	case progressBar, progressLines, progressDisabled:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid --progress %q: must be auto, bar, interval or none", mode)
+}
+
+// outputMu serializes writes of the progress display and request traces so
+// their lines don't interleave
+var outputMu sync.Mutex
+
+// progressDisplay renders the single-line live progress display
+type progressDisplay struct {
+	// total is the planned request count, 0 for a run without a limit
+	total int
+	// limit is the longest the run may last, shown next to the elapsed time
+	// when the run ends at whichever of the count and the time comes first
+	limit      time.Duration
+	sampler    *selfMetricsSampler // nil unless --self-metrics
+	thresholds latencyThresholds   // colors latencies when configured
+}
+
+// format renders the full progress line
+func (d *progressDisplay) format(snap LiveSnapshot) string {
+	line := fmt.Sprintf("Progress: %s | Elapsed: %s", d.count(snap, true), d.elapsed(snap))
+	if snap.Paused {
+		line += " | PAUSED"
+	} else if snap.ETAKnown {
+		line += fmt.Sprintf(" | ETA: %v", snap.ETA.Round(time.Second))
+	} else {
+		line += " | ETA: " + unknownValue()
+	}
+	if snap.Projected > 0 {
+		line += fmt.Sprintf(" | Projected: %d requests", snap.Projected)
+	}
+	line += fmt.Sprintf(" | RPS: %.1f current, %.1f overall", snap.CurrentRPS, snap.OverallRPS)
+	if snap.TargetRPS > 0 {
+		line += fmt.Sprintf(" (target %.1f)", snap.TargetRPS)
+	}
+	if snap.Concurrency != snap.ConfiguredConcurrency {
+		line += fmt.Sprintf(" | Concurrent: %d (live: %d)", snap.ConfiguredConcurrency, snap.Concurrency)
+	}
+	line += fmt.Sprintf(" | In flight: %d", snap.InFlight)
+	if snap.Backlog > snap.InFlight {
+		line += fmt.Sprintf(" | backlog: %d requests", snap.Backlog)
+	}
+	if snap.Completed > 0 {
+		line += fmt.Sprintf(" | p50/p95/p99: %s/%s/%s (10s: %s/%s/%s)",
+			d.latency(snap.Percentiles[50]), d.latency(snap.Percentiles[95]), d.latency(snap.Percentiles[99]),
+			d.latency(snap.RecentPercentiles[50]), d.latency(snap.RecentPercentiles[95]), d.latency(snap.RecentPercentiles[99]))
+	}
+	if snap.Failed > 0 {
+		line += fmt.Sprintf(" | failed: %d", snap.Failed)
+	}
+	if snap.BytesOut > 0 {
+		line += fmt.Sprintf(" | in/out: %s/%s", formatBytes(snap.BytesIn), formatBytes(snap.BytesOut))
+	}
+	if d.sampler != nil {
+		line += fmt.Sprintf(" [%s]", d.sampler.Latest())
+	}
+	return line
+}
+
+// count renders the completed requests out of the total, with the
+// percentage done when asked; without a total only the count is shown
+func (d *progressDisplay) count(snap LiveSnapshot, percent bool) string {
+	switch {
+	case d.total <= 0:
+		return fmt.Sprintf("%d", snap.Completed)
+	case percent:
+		return fmt.Sprintf("%d/%d (%.1f%%)", snap.Completed, d.total, float64(snap.Completed)/float64(d.total)*100)
+	}
+	return fmt.Sprintf("%d/%d", snap.Completed, d.total)
+}
+
+// elapsed renders the elapsed time, out of the time limit when there is one
+func (d *progressDisplay) elapsed(snap LiveSnapshot) string {
+	elapsed := snap.Elapsed.Round(time.Second)
+	if d.limit <= 0 {
+		return elapsed.String()
+	}
+	limit := d.limit
+	if limit >= time.Second {
+		limit = limit.Round(time.Second)
+	}
+	return fmt.Sprintf("%v/%v", elapsed, limit)
+}
+
+// latency formats a live latency, colored by the warn/crit thresholds
+func (d *progressDisplay) latency(v time.Duration) string {
+	return d.thresholds.colorize(v, roundLatency(v).String())
+}
+
+// fit renders the progress line for a terminal of the given width. The line is
+// cut with an ellipsis rather than wrapped, since a wrapped line can't be
+// redrawn in place with \r; below minTerminalWidth only the count is shown.
+func (d *progressDisplay) fit(snap LiveSnapshot, width int) string {
+	if width < minTerminalWidth {
+		return truncate(d.count(snap, false), width-1)
+	}
+	return truncate(d.format(snap), width-1)
+}
+
+// line renders a self-contained progress line for logs that don't honor \r
+func (d *progressDisplay) line(snap LiveSnapshot) string {
+	line := fmt.Sprintf("[%s] %s | RPS: %.1f | errors: %d",
+		d.elapsed(snap), d.count(snap, true), snap.CurrentRPS, snap.Failed)
+	if snap.Paused {
+		line += " | paused"
+	} else if snap.ETAKnown {
+		line += fmt.Sprintf(" | ETA: %v", snap.ETA.Round(time.Second))
+	}
+	if snap.Projected > 0 {
+		line += fmt.Sprintf(" | projected: %d", snap.Projected)
+	}
+	if snap.RateLimited > 0 {
+		line += fmt.Sprintf(" | rate limited: %d", snap.RateLimited)
+	}
+	if snap.InFlight > 0 {
+		line += fmt.Sprintf(" | in flight: %d", snap.InFlight)
+	}
+	if snap.Backlog > snap.InFlight {
+		line += fmt.Sprintf(" | backlog: %d", snap.Backlog)
+	}
+	if snap.Completed > 0 {
+		line += fmt.Sprintf(" | p95: %v", roundLatency(snap.Percentiles[95]))
+	}
+	return line
+}
+
+// capETA bounds the ETA by limit, the most a run may last, since a run cut
+// short by --max-duration or a rate profile ends sooner than its request
+// count suggests. A zero limit leaves the ETA alone.
+func capETA(snap *LiveSnapshot, limit time.Duration) {
+	if limit <= 0 {
+		return
+	}
+	remaining := max(limit-snap.Elapsed, 0)
+	if !snap.ETAKnown || snap.ETA > remaining {
+		snap.ETA = remaining
+		snap.ETAKnown = true
+	}
+}
+
+// projectTotal estimates the requests a run ending at limit will have
+// completed by then, at the current rate. It's left at 0 without a limit, and
+// when the run will reach its request count first.
+func projectTotal(snap *LiveSnapshot, limit time.Duration, total int) {
+	if limit <= 0 || snap.CurrentRPS <= 0 {
+		return
+	}
+	remaining := max(limit-snap.Elapsed, 0)
+	projected := snap.Completed + int(snap.CurrentRPS*remaining.Seconds())
+	if total > 0 && projected >= total {
+		return
+	}
+	snap.Projected = projected
+}
+
+// roundLatency trims a latency to three significant digits for compact display
+func roundLatency(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	case d >= time.Microsecond:
+		return d.Round(10 * time.Nanosecond)
+	}
+	return d
+}
+
+// startProgress reports progress in the given mode until the returned stop
+// function is called: the bar is redrawn every progressInterval, interval
+// lines are printed every every
+func startProgress(tester *LoadTester, display *progressDisplay, mode string, every time.Duration) func() {
+	if mode == progressDisabled {
+		return func() {}
+	}
+
+	tick := progressInterval
+	if mode == progressLines {
+		tick = every
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				snap := tester.Live().Snapshot()
+				snap.ConfiguredConcurrency = tester.config.Concurrent
+				snap.TargetRPS = tester.TargetRPS()
+				snap.Concurrency = tester.Concurrency()
+				snap.Paused = tester.Paused()
+				snap.InFlight = tester.InFlight()
+				snap.Backlog = tester.Backlog()
+				capETA(&snap, tester.runLimit())
+				projectTotal(&snap, tester.runLimit(), tester.config.Requests)
+				outputMu.Lock()
+				if mode == progressLines {
+					fmt.Println(display.line(snap))
+				} else {
+					fmt.Printf("\r%s\033[K", display.fit(snap, terminalWidth()))
+				}
+				outputMu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
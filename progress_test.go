@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResolveProgressMode(t *testing.T) {
+	for _, mode := range []string{progressBar, progressLines, progressDisabled} {
+		got, err := resolveProgressMode(mode)
+		if err != nil || got != mode {
+			t.Errorf("resolveProgressMode(%q) = %q, %v; want %q", mode, got, err, mode)
+		}
+	}
+	// go test's stdout isn't a terminal, so auto picks interval lines
+	if got, err := resolveProgressMode(progressAuto); err != nil || got != progressLines {
+		t.Errorf("resolveProgressMode(auto) = %q, %v; want %q", got, err, progressLines)
+	}
+	if _, err := resolveProgressMode("spinner"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	display := &progressDisplay{total: 200}
+	snap := LiveSnapshot{
+		Completed:   50,
+		Failed:      2,
+		Elapsed:     12 * time.Second,
+		CurrentRPS:  4.25,
+		Percentiles: map[int]time.Duration{95: 23456 * time.Microsecond},
+	}
+	want := "[12s] 50/200 (25.0%) | RPS: 4.2 | errors: 2 | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+
+	snap.ETA, snap.ETAKnown = 36*time.Second, true
+	want = "[12s] 50/200 (25.0%) | RPS: 4.2 | errors: 2 | ETA: 36s | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() with ETA = %q, want %q", got, want)
+	}
+
+	snap.InFlight = 7
+	want = "[12s] 50/200 (25.0%) | RPS: 4.2 | errors: 2 | ETA: 36s | in flight: 7 | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() with requests in flight = %q, want %q", got, want)
+	}
+
+	// A run limited by both count and time shows how far along each is
+	display.limit = 30 * time.Second
+	want = "[12s/30s] 50/200 (25.0%) | RPS: 4.2 | errors: 2 | ETA: 36s | in flight: 7 | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() with a time limit = %q, want %q", got, want)
+	}
+
+	// Without a request limit only the count is shown
+	display.total = 0
+	want = "[12s/30s] 50 | RPS: 4.2 | errors: 2 | ETA: 36s | in flight: 7 | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() without a request limit = %q, want %q", got, want)
+	}
+
+	snap.Projected = 126
+	want = "[12s/30s] 50 | RPS: 4.2 | errors: 2 | ETA: 36s | projected: 126 | in flight: 7 | p95: 23.46ms"
+	if got := display.line(snap); got != want {
+		t.Errorf("line() with a projected total = %q, want %q", got, want)
+	}
+}
+
+func TestProjectTotal(t *testing.T) {
+	snap := LiveSnapshot{Completed: 50, Elapsed: 10 * time.Second, CurrentRPS: 4}
+	projectTotal(&snap, 0, 0)
+	if snap.Projected != 0 {
+		t.Errorf("projected without a limit = %d, want 0", snap.Projected)
+	}
+	// 20s left at 4 req/s
+	projectTotal(&snap, 30*time.Second, 0)
+	if snap.Projected != 130 {
+		t.Errorf("projected over a 30s limit = %d, want 130", snap.Projected)
+	}
+	// A run that reaches its request count first isn't projected
+	snap.Projected = 0
+	projectTotal(&snap, 30*time.Second, 100)
+	if snap.Projected != 0 {
+		t.Errorf("projected past the request count = %d, want 0", snap.Projected)
+	}
+	projectTotal(&snap, 30*time.Second, 200)
+	if snap.Projected != 130 {
+		t.Errorf("projected short of the request count = %d, want 130", snap.Projected)
+	}
+}
+
+func TestCapETA(t *testing.T) {
+	snap := LiveSnapshot{Elapsed: 10 * time.Second, ETA: time.Minute, ETAKnown: true}
+	capETA(&snap, 0)
+	if snap.ETA != time.Minute {
+		t.Errorf("ETA without a limit = %v, want 1m", snap.ETA)
+	}
+	capETA(&snap, 30*time.Second)
+	if snap.ETA != 20*time.Second {
+		t.Errorf("ETA capped by a 30s limit = %v, want 20s", snap.ETA)
+	}
+
+	// The limit gives an ETA before the rate is known
+	snap = LiveSnapshot{Elapsed: 2 * time.Second}
+	capETA(&snap, 5*time.Second)
+	if !snap.ETAKnown || snap.ETA != 3*time.Second {
+		t.Errorf("ETA from the limit alone = %v (known %v), want 3s", snap.ETA, snap.ETAKnown)
+	}
+}
+
+// TestProgressThroughput checks that drawing progress doesn't slow the run:
+// the same load against a local server runs with the bar and without a
+// display, and the best rates must match within noise
+func TestProgressThroughput(t *testing.T) {
+	srv := newTestServer(t, 0)
+
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	run := func(mode string) float64 {
+		config := testConfig(srv.URL)
+		config.Requests = 0
+		config.MaxDuration = 300 * time.Millisecond
+		tester := newTestLoadTester(t, config)
+		defer tester.Close()
+		stop := startProgress(tester, &progressDisplay{limit: config.MaxDuration}, mode, progressInterval)
+		defer stop()
+		return tester.Run(nil).RequestsPerSec
+	}
+
+	// Alternate the runs so drift in the machine's load hits both alike
+	var with, without float64
+	for i := 0; i < 3; i++ {
+		without = max(without, run(progressDisabled))
+		with = max(with, run(progressBar))
+	}
+	t.Logf("%.0f req/s with progress, %.0f without", with, without)
+	if with < 0.8*without {
+		t.Error("progress slowed the run by more than 20%")
+	}
+}
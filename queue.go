@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// queueDelayWarning is the p95 queue delay from which the generator, not the
+// server, is reported as the bottleneck; below it the delay is timer jitter
+const queueDelayWarning = 10 * time.Millisecond
+
+// QueueStats summarises the queue delay of a --profile run: how long each
+// request waited between the start the profile scheduled for it and its
+// actual start, for a free concurrency slot or its worker's turn. Latencies
+// only cover service time, so a growing queue delay is the load the server
+// never saw (coordinated omission).
+type QueueStats struct {
+	Percentiles map[int]time.Duration `json:"percentiles"`
+	Max         time.Duration         `json:"max"`
+	// Late counts the requests that started queueDelayWarning or more behind schedule
+	Late int `json:"late"`
+}
+
+// queueDelay is how late result started against its scheduled slot
+func queueDelay(result Result, runStart time.Time) time.Duration {
+	return max(result.StartTime.Sub(runStart.Add(result.ScheduledAt)), 0)
+}
+
+func buildQueueStats(results []Result, runStart time.Time) *QueueStats {
+	if len(results) == 0 {
+		return nil
+	}
+	delays := make([]time.Duration, len(results))
+	qs := &QueueStats{}
+	for i, result := range results {
+		delays[i] = queueDelay(result, runStart)
+		qs.Max = max(qs.Max, delays[i])
+		if delays[i] >= queueDelayWarning {
+			qs.Late++
+		}
+	}
+	qs.Percentiles = percentilesOf(delays)
+	return qs
+}
+
+const (
+	// saturationMinSeconds and saturationMinGrowth are how long and how much
+	// the backlog must grow without a pause to count as saturation rather
+	// than noise
+	saturationMinSeconds = 3
+	saturationMinGrowth  = 10
+)
+
+// Saturation marks where the backlog of a --profile run started growing every
+// second until dispatching ended: requests arrived faster than they completed,
+// usually well before latency shows it
+type Saturation struct {
+	Second int       `json:"second"`
+	At     time.Time `json:"at"`
+	// From and To are the backlog when the growth started and at its end
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// addBacklog fills in the dispatched, completed and backlog counts of a
+// --profile run's timeline. Requests count as dispatched when the profile
+// schedules them, up to -n and until dispatching stops, whether or not a
+// concurrency slot was free to send them; the rate is stepped every
+// profileTick as the pacer does. Scheduled requests that were never sent,
+// or were cut off, stay in the backlog. Completions after the last second
+// are counted in it.
+func (lt *LoadTester) addBacklog(timeline []TimelineBucket, start time.Time) {
+	if len(timeline) == 0 || len(lt.config.Profile) == 0 {
+		return
+	}
+	last := len(timeline) - 1
+	for _, result := range lt.results {
+		timeline[min(max(int(result.Timestamp.Sub(start)/time.Second), 0), last)].Completed++
+	}
+
+	limit := lt.runLimit()
+	if cooldownAt := lt.cooldownStart(start); !cooldownAt.IsZero() {
+		limit = cooldownAt.Sub(start)
+	}
+	var scheduled float64
+	var at time.Duration
+	dispatched, backlog := 0, 0
+	for i := range timeline {
+		for end := time.Duration(i+1) * time.Second; at < end && (limit == 0 || at < limit); at += profileTick {
+			rps, done := rateAt(lt.config.Profile, at)
+			if done {
+				break
+			}
+			scheduled += rps * profileTick.Seconds()
+		}
+		total := int(math.Round(scheduled))
+		if lt.config.Requests > 0 {
+			total = min(total, lt.config.Requests)
+		}
+		timeline[i].Dispatched = total - dispatched
+		dispatched = total
+		backlog += timeline[i].Dispatched - timeline[i].Completed
+		timeline[i].Backlog = backlog
+	}
+}
+
+// findSaturation returns where the backlog began growing for good, nil when
+// it didn't. The last second with dispatches is left out as it is partial,
+// and so are those after it, where the run only drains.
+func findSaturation(timeline []TimelineBucket, start time.Time) *Saturation {
+	end := -1
+	for i, bucket := range timeline {
+		if bucket.Dispatched > 0 {
+			end = i
+		}
+	}
+	// backlog[i] is the backlog at the start of second i
+	backlog := []int{0}
+	for _, bucket := range timeline[:max(end, 0)] {
+		backlog = append(backlog, bucket.Backlog)
+	}
+	from := len(backlog) - 1
+	for from > 0 && backlog[from-1] < backlog[from] {
+		from--
+	}
+	to := len(backlog) - 1
+	if to-from < saturationMinSeconds || backlog[to]-backlog[from] < saturationMinGrowth {
+		return nil
+	}
+	return &Saturation{
+		Second: from,
+		At:     start.Add(time.Duration(from) * time.Second),
+		From:   backlog[from],
+		To:     backlog[to],
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuildQueueStats(t *testing.T) {
+	start := time.Now()
+	results := []Result{
+		{ScheduledAt: 0, StartTime: start},
+		{ScheduledAt: 10 * time.Millisecond, StartTime: start.Add(12 * time.Millisecond)},
+		{ScheduledAt: 20 * time.Millisecond, StartTime: start.Add(50 * time.Millisecond)},
+		// A start just before its slot is on time, not early
+		{ScheduledAt: 30 * time.Millisecond, StartTime: start.Add(29 * time.Millisecond)},
+	}
+	q := buildQueueStats(results, start)
+	if q.Max != 30*time.Millisecond || q.Late != 1 {
+		t.Errorf("max %v, late %d; want 30ms and 1", q.Max, q.Late)
+	}
+	if q.Percentiles[50] != 0 || q.Percentiles[95] != 30*time.Millisecond {
+		t.Errorf("percentiles = %v", q.Percentiles)
+	}
+	if buildQueueStats(nil, start) != nil {
+		t.Error("want nil without results")
+	}
+}
+
+func TestQueueDelayShowsSaturatedGenerator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	// One slot serving 20ms requests can't keep up with 200 requests per second
+	config := testConfig(srv.URL)
+	config.Concurrent = 1
+	config.Requests = 15
+	config.Profile = []RatePoint{{0, 200}, {10 * time.Second, 200}}
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.Queue == nil || stats.Queue.Percentiles[95] < 100*time.Millisecond {
+		t.Errorf("queue stats = %+v, want a p95 delay of 100ms or more", stats.Queue)
+	}
+
+	// With enough slots the requests start on schedule
+	config.Concurrent = 20
+	stats = newTestLoadTester(t, config).Run(nil)
+	if stats.Queue == nil || stats.Queue.Percentiles[95] >= queueDelayWarning {
+		t.Errorf("queue stats = %+v, want requests on schedule", stats.Queue)
+	}
+	if newTestLoadTester(t, testConfig(srv.URL)).Run(nil).Queue != nil {
+		t.Error("want no queue stats without a profile")
+	}
+}
+
+func TestAddBacklog(t *testing.T) {
+	start := time.Now()
+	config := testConfig("http://example.com")
+	config.Profile = []RatePoint{{At: 0, RPS: 10}, {At: 3 * time.Second, RPS: 10}}
+	config.Requests = 1000
+	tester := newTestLoadTester(t, config)
+	// 5 completions in each of the 3 seconds, against 10 scheduled
+	for sec := 0; sec < 3; sec++ {
+		for i := 0; i < 5; i++ {
+			at := start.Add(time.Duration(sec)*time.Second + time.Duration(i)*100*time.Millisecond)
+			tester.results = append(tester.results, Result{StartTime: at, Timestamp: at})
+		}
+	}
+	timeline, _ := buildTimeline(tester.results, start, func(Result) bool { return true })
+	tester.addBacklog(timeline, start)
+	for i, bucket := range timeline {
+		if bucket.Dispatched != 10 || bucket.Completed != 5 || bucket.Backlog != 5*(i+1) {
+			t.Errorf("second %d: dispatched %d, completed %d, backlog %d; want 10, 5, %d",
+				i, bucket.Dispatched, bucket.Completed, bucket.Backlog, 5*(i+1))
+		}
+	}
+
+	// Dispatching stops at -n
+	tester.config.Requests = 12
+	timeline, _ = buildTimeline(tester.results, start, func(Result) bool { return true })
+	tester.addBacklog(timeline, start)
+	if got := timeline[1].Dispatched; got != 2 {
+		t.Errorf("dispatched in second 1 = %d, want the 2 left of -n 12", got)
+	}
+}
+
+func TestFindSaturation(t *testing.T) {
+	start := time.Now()
+	timelineOf := func(backlog ...int) []TimelineBucket {
+		timeline := make([]TimelineBucket, len(backlog))
+		for i, b := range backlog {
+			timeline[i] = TimelineBucket{Second: i, Dispatched: 1, Backlog: b}
+		}
+		return timeline
+	}
+
+	// Steady, then growing every second from +3s; the last second is partial
+	s := findSaturation(timelineOf(5, 6, 5, 5, 15, 30, 60, 2), start)
+	if s == nil || s.Second != 4 || s.From != 5 || s.To != 60 {
+		t.Errorf("saturation = %+v, want growth from 5 at +4s to 60", s)
+	}
+	if !s.At.Equal(start.Add(4 * time.Second)) {
+		t.Errorf("at = %v, want 4s after the start", s.At.Sub(start))
+	}
+
+	for _, backlog := range [][]int{
+		{5, 6, 7, 8, 9, 0},     // growing, but by too little
+		{5, 50, 100, 90, 0},    // growth that didn't last
+		{5, 5, 5, 5, 5, 5, 5},  // steady
+		{5, 5, 5, 5, 5, 5, 90}, // only the partial last second
+	} {
+		if s := findSaturation(timelineOf(backlog...), start); s != nil {
+			t.Errorf("backlog %v: saturation %+v, want none", backlog, s)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "math/rand/v2"
+
+// globalRand draws from the runtime's unseeded source, for values that must
+// differ between runs even with the same --seed
+var globalRand = rand.New(globalSource{})
+
+// globalSource adapts the top-level math/rand/v2 functions to a rand.Source
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 { return rand.Uint64() }
+
+// newSeed picks a seed for runs that weren't given one with --seed
+func newSeed() uint64 {
+	return rand.Uint64()
+}
+
+// setupRand returns the random source for work done once per run, such as
+// the shared random body. It is stream 0 of the run's seed.
+func (lt *LoadTester) setupRand() *rand.Rand {
+	return rand.New(rand.NewPCG(lt.config.Seed, 0))
+}
+
+// requestRand returns the random source for request n (0-based). Every request
+// draws from its own stream of the run's seed, so the values a request gets
+// don't depend on how concurrent requests interleave.
+func (lt *LoadTester) requestRand(n int) *rand.Rand {
+	return rand.New(rand.NewPCG(lt.config.Seed, uint64(n)+1))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recordSeededRun runs a load test against a server that records every request
+// as "METHOD body" and returns the recorded requests in arrival order
+func recordSeededRun(t *testing.T, seed uint64, concurrent int) []string {
+	t.Helper()
+	var mu sync.Mutex
+	var recorded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		recorded = append(recorded, r.Method+" "+string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	config := testConfig(server.URL)
+	config.Concurrent = concurrent
+	config.Requests = 40
+	config.Seed = seed
+	config.MethodMix = []WeightedMethod{{Method: "GET", Weight: 1}, {Method: "POST", Weight: 2}, {Method: "PUT", Weight: 1}}
+	config.BodyTemplate = `{"id": "{{uuid}}", "n": {{randInt 1 1000}}, "name": "{{randString 6}}"}`
+	newTestLoadTester(t, config).Run(nil)
+	return recorded
+}
+
+func TestSameSeedSendsSameRequests(t *testing.T) {
+	first := recordSeededRun(t, 42, 1)
+	second := recordSeededRun(t, 42, 1)
+	if !slices.Equal(first, second) {
+		t.Fatalf("sequential runs with the same seed differ:\n%v\n%v", first, second)
+	}
+	if other := recordSeededRun(t, 43, 1); slices.Equal(first, other) {
+		t.Error("runs with different seeds sent the same requests")
+	}
+
+	// Concurrency may reorder arrivals but not change what each request sends
+	concurrent := recordSeededRun(t, 42, 8)
+	slices.Sort(first)
+	slices.Sort(concurrent)
+	if !slices.Equal(first, concurrent) {
+		t.Errorf("concurrent run with the same seed sent different requests:\n%v\n%v", first, concurrent)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitResponse reports whether resp tells the client to slow down: any
+// 429, or a 503 carrying Retry-After. The advertised backoff is returned when
+// the header is present and valid.
+func rateLimitResponse(resp *http.Response, now time.Time) (limited bool, retryAfter time.Duration, advertised bool) {
+	header := resp.Header.Get("Retry-After")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && header != "":
+	default:
+		return false, 0, false
+	}
+	retryAfter, advertised = parseRetryAfter(header, now)
+	return true, retryAfter, advertised
+}
+
+// parseRetryAfter parses a Retry-After value, either delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// RateLimitStats summarises the responses that asked the client to back off
+type RateLimitStats struct {
+	Responses int
+	FirstSeen time.Time
+	// WithRetryAfter counts the responses with a valid Retry-After, whose
+	// advertised backoffs ranged from MinRetryAfter to MaxRetryAfter
+	WithRetryAfter int
+	MinRetryAfter  time.Duration
+	MaxRetryAfter  time.Duration
+}
+
+// buildRateLimitStats returns nil when no response was rate limited
+func buildRateLimitStats(results []Result) *RateLimitStats {
+	var s *RateLimitStats
+	for _, result := range results {
+		if !result.RateLimited {
+			continue
+		}
+		if s == nil {
+			s = &RateLimitStats{FirstSeen: result.Timestamp}
+		}
+		s.Responses++
+		if result.Timestamp.Before(s.FirstSeen) {
+			s.FirstSeen = result.Timestamp
+		}
+		if result.RetryAfter < 0 {
+			continue
+		}
+		if s.WithRetryAfter == 0 || result.RetryAfter < s.MinRetryAfter {
+			s.MinRetryAfter = result.RetryAfter
+		}
+		if result.RetryAfter > s.MaxRetryAfter {
+			s.MaxRetryAfter = result.RetryAfter
+		}
+		s.WithRetryAfter++
+	}
+	return s
+}
+
+// retryAfterGate holds back new requests until the latest time a server asked
+// the client to wait for, used by --honor-retry-after
+type retryAfterGate struct {
+	until atomic.Int64 // unix nanoseconds, 0 when not paused
+}
+
+// extend moves the end of the pause out to until if it is later
+func (g *retryAfterGate) extend(until time.Time) {
+	for {
+		current := g.until.Load()
+		if until.UnixNano() <= current || g.until.CompareAndSwap(current, until.UnixNano()) {
+			return
+		}
+	}
+}
+
+// wait blocks until the pause is over. It returns false if cancel closes first.
+func (g *retryAfterGate) wait(cancel <-chan struct{}) bool {
+	for {
+		remaining := time.Until(time.Unix(0, g.until.Load()))
+		if remaining <= 0 {
+			return true
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-cancel:
+			timer.Stop()
+			return false
+		}
+	}
+}
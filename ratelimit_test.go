@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRateLimitedRun(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch hits.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			// A 503 without Retry-After is an ordinary failure
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Concurrent = 1
+	config.Requests = 5
+	config.HonorRetryAfter = true
+
+	start := time.Now()
+	stats := newTestLoadTester(t, config).Run(nil)
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("run took %v, want at least the 1s Retry-After", elapsed)
+	}
+
+	rl := stats.RateLimited
+	if rl == nil {
+		t.Fatal("no rate limit stats")
+	}
+	if rl.Responses != 2 || rl.WithRetryAfter != 1 || rl.MinRetryAfter != time.Second || rl.MaxRetryAfter != time.Second {
+		t.Errorf("rate limit stats = %+v, want 2 responses, one with a 1s Retry-After", rl)
+	}
+}
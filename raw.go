@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// headers that describe the original connection rather than the request and
+// are recomputed by net/http when the request is replayed
+var rawRequestSkippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+// loadRawRequest parses a raw HTTP request (request line, headers, body) as
+// copied from browser devtools or Burp and applies it to config, replacing the
+// method, headers and body. The target is the request's Host and path; when
+// config.URL is already set, its scheme and host are used with the raw path.
+// Use --host to keep sending the original virtual host to a different target.
+func loadRawRequest(filename string, config *Config) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	// Devtools copies HTTP/2 requests with an "HTTP/2" request line, which
+	// http.ReadRequest doesn't accept; the version is irrelevant for replaying.
+	if end := bytes.IndexByte(data, '\n'); end > 0 {
+		line := strings.TrimRight(string(data[:end]), "\r")
+		if i := strings.LastIndex(line, " HTTP/"); i > 0 {
+			data = append([]byte(line[:i]+" HTTP/1.1"), data[end:]...)
+		}
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("invalid raw request: %v", err)
+	}
+	defer req.Body.Close()
+
+	reqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("error reading raw request body: %v", err)
+	}
+
+	target := &url.URL{Scheme: "http", Host: req.Host, Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: req.URL.RawQuery}
+	if config.URL != "" {
+		base, err := url.Parse(config.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %v", err)
+		}
+		target.Scheme = base.Scheme
+		target.Host = base.Host
+	}
+	if target.Host == "" {
+		return fmt.Errorf("raw request has no Host header; pass the target with --url")
+	}
+
+	config.URL = target.String()
+	config.Method = req.Method
+	config.Body = string(reqBody)
+	config.Headers = make(map[string]string)
+	for key, values := range req.Header {
+		if rawRequestSkippedHeaders[key] {
+			continue
+		}
+		config.Headers[key] = strings.Join(values, ", ")
+	}
+
+	return nil
+}
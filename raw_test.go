@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRawRequest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "request.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRawRequest(t *testing.T) {
+	path := writeRawRequest(t, "POST /api/users?page=2 HTTP/2\r\n"+
+		"Host: api.example.com\r\n"+
+		"Content-Type: application/json\r\n"+
+		"Content-Length: 13\r\n"+
+		"Accept: text/html\r\n"+
+		"Accept: application/json\r\n"+
+		"\r\n"+
+		`{"name":"jo"}`)
+
+	config := Config{Method: "GET", Headers: map[string]string{"X-Old": "1"}}
+	if err := loadRawRequest(path, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.URL != "http://api.example.com/api/users?page=2" {
+		t.Errorf("URL = %q", config.URL)
+	}
+	if config.Method != "POST" {
+		t.Errorf("Method = %q", config.Method)
+	}
+	if config.Body != `{"name":"jo"}` {
+		t.Errorf("Body = %q", config.Body)
+	}
+	if _, ok := config.Headers["Content-Length"]; ok {
+		t.Error("Content-Length should not be replayed")
+	}
+	if _, ok := config.Headers["X-Old"]; ok {
+		t.Error("headers from flags should be replaced")
+	}
+	if config.Headers["Accept"] != "text/html, application/json" {
+		t.Errorf("Accept = %q", config.Headers["Accept"])
+	}
+}
+
+func TestLoadRawRequestRewritesTarget(t *testing.T) {
+	path := writeRawRequest(t, "GET /health HTTP/1.1\nHost: api.example.com\n\n")
+
+	config := Config{URL: "https://staging.example.com:8443/ignored"}
+	if err := loadRawRequest(path, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.URL != "https://staging.example.com:8443/health" {
+		t.Errorf("URL = %q", config.URL)
+	}
+}
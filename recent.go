@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxRecentResults bounds the outcomes kept for the --interactive detail view
+const maxRecentResults = 20
+
+// recentResults is a ring of the last completed results
+type recentResults struct {
+	mu   sync.Mutex
+	ring [maxRecentResults]Result
+	next int
+	full bool
+}
+
+func (r *recentResults) add(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ring[r.next] = result
+	r.next = (r.next + 1) % maxRecentResults
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the kept results, oldest first
+func (r *recentResults) list() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Result(nil), r.ring[:r.next]...)
+	}
+	return append(append([]Result(nil), r.ring[r.next:]...), r.ring[:r.next]...)
+}
+
+// ToggleDetail switches the detail view on or off and reports whether it is
+// now on. Switching it on prints the most recent outcomes; while it is on
+// every completed request is printed as well. It does nothing unless
+// --interactive set up the recent results.
+func (lt *LoadTester) ToggleDetail() bool {
+	if lt.recent == nil {
+		return false
+	}
+	on := !lt.detail.Load()
+	lt.detail.Store(on)
+	if on {
+		var b strings.Builder
+		b.WriteString("\r\033[K-- request details on (d to hide) --\r\n")
+		for _, result := range lt.recent.list() {
+			b.WriteString(lt.detailLine(result))
+		}
+		outputMu.Lock()
+		fmt.Print(b.String())
+		outputMu.Unlock()
+	}
+	return on
+}
+
+// noteRecent keeps a completed result for the detail view and prints it
+// while the view is on; a no-op unless --interactive
+func (lt *LoadTester) noteRecent(result Result) {
+	if lt.recent == nil {
+		return
+	}
+	lt.recent.add(result)
+	if lt.detail.Load() {
+		line := lt.detailLine(result)
+		outputMu.Lock()
+		fmt.Print(line)
+		outputMu.Unlock()
+	}
+}
+
+// detailLine renders a result for the detail view. The terminal is in raw
+// mode, so lines end in \r\n and first clear the progress line.
+func (lt *LoadTester) detailLine(result Result) string {
+	return "\r\033[K" + result.Timestamp.Format("15:04:05.000") + " " + lt.describeResult(result) + "\r\n"
+}
+
+// ShowErrors prints the distinct errors seen so far for the --interactive
+// error view, most frequent first
+func (lt *LoadTester) ShowErrors() {
+	groups, omitted := lt.live.Errors()
+	view := errorsView(groups, omitted)
+	outputMu.Lock()
+	fmt.Print(view)
+	outputMu.Unlock()
+}
+
+// errorsView renders up to maxErrorMessages error groups, with a line counting
+// the rest. Like detailLine it clears the progress line and ends lines in \r\n.
+func errorsView(groups []ErrorGroup, omitted int) string {
+	var b strings.Builder
+	b.WriteString("\r\033[K-- errors so far (e to refresh) --\r\n")
+	if len(groups) == 0 {
+		b.WriteString("none\r\n")
+		return b.String()
+	}
+	shown := groups
+	if len(shown) > maxErrorMessages {
+		shown = shown[:maxErrorMessages]
+	}
+	for _, group := range shown {
+		fmt.Fprintf(&b, "%s x%d (last seen %s)\r\n", group.Message, group.Count, group.LastSeen.Format("15:04:05"))
+	}
+	if more := len(groups) - len(shown); more > 0 || omitted > 0 {
+		fmt.Fprintf(&b, "...and %d more distinct errors", more)
+		if omitted > 0 {
+			fmt.Fprintf(&b, " (plus %d errors beyond the %d tracked messages)", omitted, maxDistinctErrors)
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
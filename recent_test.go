@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecentResultsRing(t *testing.T) {
+	var r recentResults
+	for i := 1; i <= 3; i++ {
+		r.add(Result{Seq: i})
+	}
+	if got := r.list(); len(got) != 3 || got[0].Seq != 1 || got[2].Seq != 3 {
+		t.Errorf("list before wrapping = %v", got)
+	}
+
+	for i := 4; i <= maxRecentResults+5; i++ {
+		r.add(Result{Seq: i})
+	}
+	got := r.list()
+	if len(got) != maxRecentResults {
+		t.Fatalf("kept %d results, want %d", len(got), maxRecentResults)
+	}
+	for i, result := range got {
+		if want := i + 6; result.Seq != want {
+			t.Fatalf("result %d has seq %d, want %d (oldest first)", i, result.Seq, want)
+		}
+	}
+}
+
+func TestToggleDetailNeedsInteractive(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.invalid"))
+	if tester.ToggleDetail() {
+		t.Error("detail view turned on without --interactive")
+	}
+}
+
+func TestErrorsView(t *testing.T) {
+	if got := errorsView(nil, 0); !strings.HasSuffix(got, "none\r\n") {
+		t.Errorf("view without errors = %q", got)
+	}
+
+	var groups []ErrorGroup
+	for i := 0; i < maxErrorMessages+3; i++ {
+		groups = append(groups, ErrorGroup{Message: fmt.Sprintf("error %d", i), Count: 1, LastSeen: time.Now()})
+	}
+	got := errorsView(groups, 0)
+	if !strings.Contains(got, "error 0 x1") || strings.Contains(got, fmt.Sprintf("error %d ", maxErrorMessages)) {
+		t.Errorf("view should list the first %d errors:\n%s", maxErrorMessages, got)
+	}
+	if !strings.HasSuffix(got, "...and 3 more distinct errors\r\n") {
+		t.Errorf("view should end with the count of the rest:\n%s", got)
+	}
+
+	got = errorsView(groups[:1], 5)
+	if !strings.Contains(got, "...and 0 more distinct errors (plus 5 errors beyond") {
+		t.Errorf("view should count the untracked errors:\n%s", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// RunSummary is the headline figures of one run of a --repeat series
+type RunSummary struct {
+	Requests  int                   `json:"requests"`
+	Failed    int                   `json:"failed"`
+	RPS       float64               `json:"rps"`
+	Latencies map[int]time.Duration `json:"percentiles"`
+}
+
+// Spread describes how a figure varied across the runs of a --repeat series.
+// CV is the coefficient of variation, the standard deviation over the mean.
+type Spread struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	CV     float64 `json:"cv"`
+}
+
+// RepeatStats aggregates the runs of a --repeat series
+type RepeatStats struct {
+	Runs      []RunSummary `json:"runs"`
+	RPS       Spread       `json:"rps"`
+	ErrorRate Spread       `json:"error_rate"`
+	// Latencies spreads each reported percentile, in nanoseconds like time.Duration
+	Latencies map[int]Spread `json:"percentiles"`
+}
+
+// newSpread computes the mean and sample standard deviation of values
+func newSpread(values []float64) Spread {
+	var s Spread
+	if len(values) == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(len(values))
+	if len(values) > 1 {
+		var sq float64
+		for _, v := range values {
+			sq += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sq / float64(len(values)-1))
+	}
+	if s.Mean != 0 {
+		s.CV = s.StdDev / s.Mean
+	}
+	return s
+}
+
+// buildRepeatStats summarises each run and how much the runs disagree
+func buildRepeatStats(runs []*Stats) *RepeatStats {
+	rs := &RepeatStats{Latencies: make(map[int]Spread)}
+	rps := make([]float64, len(runs))
+	errorRates := make([]float64, len(runs))
+	for i, stats := range runs {
+		rs.Runs = append(rs.Runs, RunSummary{
+			Requests:  stats.TotalRequests,
+			Failed:    stats.FailedReqs,
+			RPS:       stats.RequestsPerSec,
+			Latencies: stats.Percentiles,
+		})
+		rps[i] = stats.RequestsPerSec
+		if stats.TotalRequests > 0 {
+			errorRates[i] = float64(stats.FailedReqs) / float64(stats.TotalRequests)
+		}
+	}
+	rs.RPS = newSpread(rps)
+	rs.ErrorRate = newSpread(errorRates)
+	for _, p := range reportedPercentiles {
+		values := make([]float64, len(runs))
+		for i, stats := range runs {
+			values[i] = float64(stats.Percentiles[p])
+		}
+		rs.Latencies[p] = newSpread(values)
+	}
+	return rs
+}
+
+// printRepeatStats prints the per-run figures and their spread
+func printRepeatStats(rs *RepeatStats) {
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("REPEAT (%d runs, the last one is detailed above)\n", len(rs.Runs))
+	fmt.Println(strings.Repeat("-", 40))
+	for i, run := range rs.Runs {
+		fmt.Printf("Run %d: %d requests, %d failed, %.2f req/s, p95 %v\n",
+			i+1, run.Requests, run.Failed, run.RPS, roundLatency(run.Latencies[95]))
+	}
+	fmt.Printf("RPS: %.2f ± %.2f (CV %.1f%%)\n", rs.RPS.Mean, rs.RPS.StdDev, rs.RPS.CV*100)
+	for _, p := range reportedPercentiles {
+		s := rs.Latencies[p]
+		fmt.Printf("p%d: %v ± %v (CV %.1f%%)\n", p, roundLatency(time.Duration(s.Mean)),
+			roundLatency(time.Duration(s.StdDev)), s.CV*100)
+	}
+	fmt.Printf("Error rate: %.2f%% ± %.2f%%\n", rs.ErrorRate.Mean*100, rs.ErrorRate.StdDev*100)
+}
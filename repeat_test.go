@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewSpread(t *testing.T) {
+	s := newSpread([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if s.Mean != 5 || math.Abs(s.StdDev-2.138) > 0.001 || math.Abs(s.CV-0.4276) > 0.001 {
+		t.Errorf("spread = %+v, want mean 5, sample stddev 2.138", s)
+	}
+	if s := newSpread([]float64{3}); s.StdDev != 0 || s.CV != 0 {
+		t.Errorf("single value spread = %+v", s)
+	}
+}
+
+func TestRepeatReusesTester(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 20
+	tester := newTestLoadTester(t, config)
+
+	var runs []*Stats
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			tester.Reset()
+		}
+		runs = append(runs, tester.Run(nil))
+	}
+	for i, stats := range runs {
+		if stats.TotalRequests != 20 {
+			t.Errorf("run %d counted %d requests, want 20", i+1, stats.TotalRequests)
+		}
+	}
+
+	rs := buildRepeatStats(runs)
+	if len(rs.Runs) != 3 || rs.RPS.Mean <= 0 || rs.ErrorRate.Mean != 0 {
+		t.Errorf("repeat stats = %+v", rs)
+	}
+	if p95 := rs.Latencies[95]; p95.Mean <= 0 || time.Duration(p95.Mean) > time.Second {
+		t.Errorf("p95 spread = %+v", p95)
+	}
+}
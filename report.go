@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// savedResults is the part of a results file written by SaveResultsToJSON that
+// report and compare read back; individual results are skipped
+type savedResults struct {
+	Metadata *RunMetadata `json:"metadata"` // missing in files from older versions
+	Config   Config       `json:"config"`
+	Stats    Stats        `json:"stats"`
+}
+
+func loadResultsFile(filename string) (*savedResults, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var saved savedResults
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s is not a results file: %v", filename, err)
+	}
+	if saved.Stats.TotalRequests == 0 {
+		return nil, fmt.Errorf("%s has no results", filename)
+	}
+	return &saved, nil
+}
+
+// describeRun renders the metadata line shown by report and compare
+func (r *savedResults) describeRun() string {
+	if r.Metadata == nil {
+		return "unknown version"
+	}
+	return fmt.Sprintf("brutal %s on %s, started %s", r.Metadata.ToolVersion, r.Metadata.Hostname,
+		r.Metadata.StartedAt.Format(time.RFC3339))
+}
+
+func newReportCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "report <results.json>",
+		Short: "Print the summary of a saved results file",
+		Example: `  brutal run https://api.example.com -o results.json
+  brutal report results.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != formatText && format != formatJSON {
+				return fmt.Errorf("invalid --format %q: must be text or json", format)
+			}
+			cmd.SilenceUsage = true
+
+			saved, err := loadResultsFile(args[0])
+			if err != nil {
+				return err
+			}
+			if format == formatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(saved)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s %s (%s)\n", args[0], methodDescription(saved.Config),
+				saved.Config.URL, saved.describeRun())
+			printStats(&saved.Stats)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "", formatText, "Output format: text or json")
+	return cmd
+}
+
+func newCompareCmd() *cobra.Command {
+	var threshold float64
+	cmd := &cobra.Command{
+		Use:   "compare <baseline.json> <candidate.json>",
+		Short: "Compare two saved results files",
+		Example: `  brutal compare before.json after.json
+  brutal compare before.json after.json --regression-threshold 10`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if threshold < 0 {
+				return fmt.Errorf("--regression-threshold must not be negative")
+			}
+			cmd.SilenceUsage = true
+
+			baseline, err := loadResultsFile(args[0])
+			if err != nil {
+				return err
+			}
+			candidate, err := loadResultsFile(args[1])
+			if err != nil {
+				return err
+			}
+			return compareResults(cmd.OutOrStdout(), baseline, candidate, threshold)
+		},
+	}
+	cmd.Flags().Float64VarP(&threshold, "regression-threshold", "", 0, "Exit non-zero if RPS drops or p95 latency rises by more than this percentage (0 disables)")
+	return cmd
+}
+
+// percentChange returns the relative change from a to b in percent
+func percentChange(a, b float64) float64 {
+	if a == 0 {
+		return 0
+	}
+	return (b - a) / a * 100
+}
+
+// compareResults prints the key metrics of two runs side by side and returns
+// an error when the candidate regressed by more than threshold percent
+func compareResults(w io.Writer, baseline, candidate *savedResults, threshold float64) error {
+	a, b := &baseline.Stats, &candidate.Stats
+
+	fmt.Fprintf(w, "Baseline:  %s\n", baseline.describeRun())
+	fmt.Fprintf(w, "Candidate: %s\n", candidate.describeRun())
+	if baseline.Metadata != nil && candidate.Metadata != nil &&
+		baseline.Metadata.ToolVersion != candidate.Metadata.ToolVersion {
+		fmt.Fprintf(w, "Warning: the results were produced by different versions (%s vs %s)\n",
+			baseline.Metadata.ToolVersion, candidate.Metadata.ToolVersion)
+	}
+	if baseline.Config.URL != candidate.Config.URL {
+		fmt.Fprintf(w, "Warning: the runs targeted different URLs (%s vs %s)\n", baseline.Config.URL, candidate.Config.URL)
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	fmt.Fprintf(w, "%-16s %14s %14s %10s\n", "", "Baseline", "Candidate", "Change")
+	row := func(name string, x, y float64, format func(float64) string) {
+		fmt.Fprintf(w, "%-16s %14s %14s %+9.1f%%\n", name, format(x), format(y), percentChange(x, y))
+	}
+	rate := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	latency := func(v float64) string { return roundLatency(time.Duration(v)).String() }
+
+	row("Requests/sec", a.RequestsPerSec, b.RequestsPerSec, rate)
+	row("Avg latency", float64(a.AvgResponseTime), float64(b.AvgResponseTime), latency)
+	for _, p := range reportedPercentiles {
+		row(fmt.Sprintf("p%d latency", p), float64(a.Percentiles[p]), float64(b.Percentiles[p]), latency)
+	}
+	failRate := func(s *Stats) float64 { return float64(s.FailedReqs) / float64(s.TotalRequests) * 100 }
+	fmt.Fprintf(w, "%-16s %13.2f%% %13.2f%% %+8.2fpp\n", "Failed", failRate(a), failRate(b), failRate(b)-failRate(a))
+
+	// Whether the latency distributions differ at all, so a shift within the
+	// run-to-run noise isn't mistaken for a change
+	if _, p, ok := mannWhitneyU(a.ResponseTimes, b.ResponseTimes); !ok {
+		fmt.Fprintln(w, "Latency difference: not tested (a file has no response time samples)")
+	} else if p < significanceLevel {
+		fmt.Fprintf(w, "Latency difference: significant (Mann-Whitney U p=%.4f)\n", p)
+	} else {
+		fmt.Fprintf(w, "Latency difference: not significant (Mann-Whitney U p=%.4f), likely noise\n", p)
+	}
+
+	if threshold == 0 {
+		return nil
+	}
+	var regressions []string
+	if drop := -percentChange(a.RequestsPerSec, b.RequestsPerSec); drop > threshold {
+		regressions = append(regressions, fmt.Sprintf("RPS dropped %.1f%%", drop))
+	}
+	if rise := percentChange(float64(a.Percentiles[95]), float64(b.Percentiles[95])); rise > threshold {
+		regressions = append(regressions, fmt.Sprintf("p95 latency rose %.1f%%", rise))
+	}
+	if len(regressions) > 0 {
+		return fmt.Errorf("regression beyond %.1f%%: %s", threshold, strings.Join(regressions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// retryable reports whether a failed attempt is worth repeating: connection
+// errors, timeouts and server-side failures, but nothing once the run stops
+func (lt *LoadTester) retryable(result Result) bool {
+	if lt.ctx.Err() != nil {
+		return false
+	}
+	if result.Error != nil {
+		return !errors.Is(result.Error, errChaosAborted)
+	}
+	return result.StatusCode >= 500 || result.StatusCode == http.StatusTooManyRequests
+}
+
+// retryDelay returns the wait before the given retry, counted from 1: base
+// doubled for every earlier retry and capped at maxBackoff. The jitter
+// fraction of it is drawn at random, so 1 is full jitter and 0 none; without
+// jitter the retries of requests that failed together all land together.
+func retryDelay(retry int, base, maxBackoff time.Duration, jitter float64, rng *rand.Rand) time.Duration {
+	d := base
+	for i := 1; i < retry && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	random := time.Duration(float64(d) * jitter)
+	if random <= 0 {
+		return d
+	}
+	return d - random + time.Duration(rng.Int64N(int64(random)+1))
+}
+
+// waitBackoff sleeps for d and reports false if the run stopped first
+func (lt *LoadTester) waitBackoff(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-lt.ctx.Done():
+		return false
+	}
+}
+
+// RetryStats summarises --retries, nil when no request was retried
+type RetryStats struct {
+	// RetriedRequests needed at least one retry, Retries is the total sent
+	RetriedRequests int
+	Retries         int
+	// Recovered counts retried requests that succeeded in the end
+	Recovered int
+	// Backoff is the time spent waiting between attempts, summed over requests
+	Backoff time.Duration
+}
+
+func buildRetryStats(results []Result, isSuccess func(Result) bool) *RetryStats {
+	var s *RetryStats
+	for _, result := range results {
+		if result.Retries == 0 {
+			continue
+		}
+		if s == nil {
+			s = &RetryStats{}
+		}
+		s.RetriedRequests++
+		s.Retries += result.Retries
+		s.Backoff += result.Backoff
+		if isSuccess(result) {
+			s.Recovered++
+		}
+	}
+	return s
+}
+
+const (
+	// graceBackoff and graceMaxBackoff bound the waits between --grace-period
+	// retries, kept short as the target is expected to come up soon
+	graceBackoff    = 50 * time.Millisecond
+	graceMaxBackoff = time.Second
+)
+
+// inGracePeriod reports whether the run is still within --grace-period of
+// its start and hasn't been stopped
+func (lt *LoadTester) inGracePeriod() bool {
+	return lt.config.GracePeriod > 0 && lt.ctx.Err() == nil &&
+		time.Since(lt.startTime) < lt.config.GracePeriod
+}
+
+// refusedOrReset reports whether an attempt failed because the connection was
+// refused or reset, as by a server that isn't accepting yet
+func refusedOrReset(result Result) bool {
+	if result.Error == nil {
+		return false
+	}
+	category := errorCategory(result)
+	return category == "connection_refused" || category == "connection_reset"
+}
+
+// GraceStats summarises --grace-period, nil when nothing had to be retried
+type GraceStats struct {
+	// Requests needed at least one retry, Retries is the total sent
+	Requests int
+	Retries  int
+	// Recovered counts the requests that succeeded in the end
+	Recovered int
+}
+
+func buildGraceStats(results []Result, isSuccess func(Result) bool) *GraceStats {
+	var s *GraceStats
+	for _, result := range results {
+		if result.GraceRetries == 0 {
+			continue
+		}
+		if s == nil {
+			s = &GraceStats{}
+		}
+		s.Requests++
+		s.Retries += result.GraceRetries
+		if isSuccess(result) {
+			s.Recovered++
+		}
+	}
+	return s
+}
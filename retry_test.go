@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	base, maxBackoff := 100*time.Millisecond, 2*time.Second
+	want := []time.Duration{100, 200, 400, 800, 1600, 2000, 2000}
+	for i, w := range want {
+		if got := retryDelay(i+1, base, maxBackoff, 0, testRand()); got != w*time.Millisecond {
+			t.Errorf("retry %d without jitter = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	rng := testRand()
+	for i := 0; i < 1000; i++ {
+		if got := retryDelay(3, base, maxBackoff, 1, rng); got < 0 || got > 400*time.Millisecond {
+			t.Fatalf("full jitter = %v, want within [0, 400ms]", got)
+		}
+		if got := retryDelay(3, base, maxBackoff, 0.5, rng); got < 200*time.Millisecond || got > 400*time.Millisecond {
+			t.Fatalf("half jitter = %v, want within [200ms, 400ms]", got)
+		}
+	}
+}
+
+func TestRetriesRecoverFailures(t *testing.T) {
+	// Every second response fails, so from the second request on each one
+	// fails once and succeeds on its retry
+	srv := newTestServer(t, 2)
+	config := testConfig(srv.URL)
+	config.Concurrent = 1
+	config.Retries = 2
+	config.RetryBackoff = time.Millisecond
+	config.RetryMaxBackoff = time.Millisecond
+
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.FailedReqs != 0 {
+		t.Errorf("failed = %d, want every failure recovered", stats.FailedReqs)
+	}
+	r := stats.Retries
+	if r == nil {
+		t.Fatal("no retry stats")
+	}
+	if r.RetriedRequests != 49 || r.Retries != 49 || r.Recovered != 49 {
+		t.Errorf("retries = %+v, want 49 requests retried once and recovered", *r)
+	}
+	if r.Backoff != 49*time.Millisecond {
+		t.Errorf("backoff = %v, want 49ms", r.Backoff)
+	}
+}
+
+func TestNoRetryStatsWithoutRetries(t *testing.T) {
+	srv := newTestServer(t, 2)
+	stats := newTestLoadTester(t, testConfig(srv.URL)).Run(nil)
+	if stats.Retries != nil {
+		t.Errorf("retries = %+v, want nil without --retries", *stats.Retries)
+	}
+}
+
+func TestGracePeriodWaitsForLateServer(t *testing.T) {
+	// Reserve a port, then leave it closed until the run is under way
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		time.Sleep(200 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		srv.Listener = l
+		srv.Start()
+	}()
+
+	config := testConfig("http://" + addr)
+	config.Requests = 10
+	config.GracePeriod = 5 * time.Second
+	stats := newTestLoadTester(t, config).Run(nil)
+	<-started
+	if stats.FailedReqs != 0 {
+		t.Errorf("failed = %d, want the refused connections retried", stats.FailedReqs)
+	}
+	g := stats.Grace
+	if g == nil || g.Retries == 0 || g.Recovered != g.Requests {
+		t.Fatalf("grace stats = %+v, want recovered retries", g)
+	}
+	if g.Requests > config.Concurrent {
+		t.Errorf("%d requests retried, want only the first wave of %d", g.Requests, config.Concurrent)
+	}
+}
+
+func TestNoGraceRetriesAfterPeriod(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	config := testConfig("http://" + addr)
+	config.Requests = 5
+	config.GracePeriod = 100 * time.Millisecond
+	start := time.Now()
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.FailedReqs != 5 {
+		t.Errorf("failed = %d, want all 5 once the period is over", stats.FailedReqs)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("run took %v, want retrying to stop with the period", elapsed)
+	}
+}
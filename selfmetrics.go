@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SelfMetrics is a snapshot of the load generator's own resource usage
+type SelfMetrics struct {
+	Goroutines int
+	OpenFDs    int // -1 when the platform doesn't expose open descriptors
+	HeapAlloc  uint64
+	Sys        uint64
+
+	// BytesPerRequest and AllocsPerRequest are the heap bytes and objects the
+	// tool allocated during the run divided by the completed requests
+	BytesPerRequest  float64
+	AllocsPerRequest float64
+}
+
+// allocCounters are the runtime's cumulative heap allocation counters
+type allocCounters struct {
+	bytes   uint64
+	objects uint64
+}
+
+// readAllocCounters reads the allocation counters. ReadMemStats stops the
+// world, so it is only called at the start and end of a run.
+func readAllocCounters() allocCounters {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return allocCounters{bytes: mem.TotalAlloc, objects: mem.Mallocs}
+}
+
+// setAllocationsPerRequest records the allocations between start and end
+// spread over requests
+func (m *SelfMetrics) setAllocationsPerRequest(start, end allocCounters, requests int) {
+	if requests == 0 {
+		return
+	}
+	m.BytesPerRequest = float64(end.bytes-start.bytes) / float64(requests)
+	m.AllocsPerRequest = float64(end.objects-start.objects) / float64(requests)
+}
+
+// readSelfMetrics samples goroutine count, open file descriptors and memory
+func readSelfMetrics() SelfMetrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return SelfMetrics{
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    countOpenFDs(),
+		HeapAlloc:  mem.HeapAlloc,
+		Sys:        mem.Sys,
+	}
+}
+
+// countOpenFDs counts the entries of the per-process descriptor directory.
+// Linux exposes /proc/self/fd, macOS and the BSDs expose /dev/fd.
+func countOpenFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			// ReadDir itself holds one descriptor open while listing
+			return len(entries) - 1
+		}
+	}
+	return -1
+}
+
+func (m SelfMetrics) String() string {
+	fds := "n/a"
+	if m.OpenFDs >= 0 {
+		fds = fmt.Sprintf("%d", m.OpenFDs)
+	}
+	return fmt.Sprintf("goroutines: %d, fds: %s, heap: %s", m.Goroutines, fds, formatBytes(int64(m.HeapAlloc)))
+}
+
+// selfMetricsSampler periodically samples SelfMetrics and keeps the latest and peak values.
+// ReadMemStats stops the world briefly, so it is sampled on a ticker rather than per request.
+type selfMetricsSampler struct {
+	mu     sync.Mutex
+	latest SelfMetrics
+	peak   SelfMetrics
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func startSelfMetricsSampler(interval time.Duration) *selfMetricsSampler {
+	s := &selfMetricsSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		peak: SelfMetrics{OpenFDs: -1},
+	}
+	s.sample()
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sample()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *selfMetricsSampler) sample() {
+	m := readSelfMetrics()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latest = m
+	if m.Goroutines > s.peak.Goroutines {
+		s.peak.Goroutines = m.Goroutines
+	}
+	if m.OpenFDs > s.peak.OpenFDs {
+		s.peak.OpenFDs = m.OpenFDs
+	}
+	if m.HeapAlloc > s.peak.HeapAlloc {
+		s.peak.HeapAlloc = m.HeapAlloc
+	}
+	if m.Sys > s.peak.Sys {
+		s.peak.Sys = m.Sys
+	}
+}
+
+// Latest returns the most recent sample
+func (s *selfMetricsSampler) Latest() SelfMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest
+}
+
+// Stop ends sampling and returns the peak values observed
+func (s *selfMetricsSampler) Stop() SelfMetrics {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak
+}
+
+// formatBytes renders a byte count using the same units as the data transfer summary
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d bytes", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.2f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.2f MB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfMetricsSampler(t *testing.T) {
+	before := runtime.NumGoroutine()
+	s := startSelfMetricsSampler(5 * time.Millisecond)
+
+	latest := s.Latest()
+	if latest.Goroutines == 0 || latest.HeapAlloc == 0 {
+		t.Errorf("first sample %+v, want it taken at start", latest)
+	}
+	if runtime.GOOS == "linux" && latest.OpenFDs < 0 {
+		t.Errorf("open fds = %d, want a count on linux", latest.OpenFDs)
+	}
+	text := latest.String()
+	for _, field := range []string{"goroutines: ", "fds: ", "heap: "} {
+		if !strings.Contains(text, field) {
+			t.Errorf("Latest() = %q, want a %q field", text, field)
+		}
+	}
+
+	// Wait for the ticker to take another sample
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Latest() == latest && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	peak := s.Stop()
+	final := s.Latest()
+	if peak.Goroutines < final.Goroutines || peak.HeapAlloc < final.HeapAlloc || peak.OpenFDs < final.OpenFDs {
+		t.Errorf("peak %+v below the latest sample %+v", peak, final)
+	}
+	checkNoLeaks(t, before)
+	time.Sleep(20 * time.Millisecond)
+	if s.Latest() != final {
+		t.Error("sampling went on after Stop")
+	}
+}
+
+func TestSetAllocationsPerRequest(t *testing.T) {
+	var m SelfMetrics
+	m.setAllocationsPerRequest(allocCounters{bytes: 1000, objects: 10}, allocCounters{bytes: 5000, objects: 50}, 4)
+	if m.BytesPerRequest != 1000 || m.AllocsPerRequest != 10 {
+		t.Errorf("per request: %v bytes, %v allocs, want 1000 and 10", m.BytesPerRequest, m.AllocsPerRequest)
+	}
+	m = SelfMetrics{}
+	m.setAllocationsPerRequest(allocCounters{}, allocCounters{bytes: 10, objects: 1}, 0)
+	if m.BytesPerRequest != 0 || m.AllocsPerRequest != 0 {
+		t.Errorf("no requests: %v bytes, %v allocs, want 0", m.BytesPerRequest, m.AllocsPerRequest)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTiming is one metric of a Server-Timing header or trailer that
+// carries a duration, like db;dur=12.5
+type ServerTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// serverTimings collects the metrics with a duration from the Server-Timing
+// headers and trailers of a response. Trailers are only there once the body
+// has been read.
+func serverTimings(header, trailer http.Header) []ServerTiming {
+	var timings []ServerTiming
+	for _, h := range []http.Header{header, trailer} {
+		for _, value := range h.Values("Server-Timing") {
+			timings = append(timings, parseServerTiming(value)...)
+		}
+	}
+	return timings
+}
+
+// parseServerTiming parses one Server-Timing value, a comma-separated list of
+// metrics with ;-separated parameters. Commas and semicolons inside quoted
+// descriptions don't split. Metrics without a valid dur are left out.
+func parseServerTiming(value string) []ServerTiming {
+	var timings []ServerTiming
+	for _, metric := range splitUnquoted(value, ',') {
+		params := splitUnquoted(metric, ';')
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			if !strings.EqualFold(strings.TrimSpace(key), "dur") {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(val), `"`), 64)
+			if err != nil || ms < 0 {
+				break
+			}
+			timings = append(timings, ServerTiming{Name: name, Duration: time.Duration(ms * float64(time.Millisecond))})
+			break
+		}
+	}
+	return timings
+}
+
+// splitUnquoted splits s at each sep that isn't inside double quotes
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// ServerTimingStats aggregates one Server-Timing metric over the responses
+// that reported it
+type ServerTimingStats struct {
+	Name        string                `json:"name"`
+	Responses   int                   `json:"responses"`
+	Percentiles map[int]time.Duration `json:"percentiles"`
+}
+
+// buildServerTimingStats aggregates the Server-Timing metrics of the results
+// by name, nil when no response reported any
+func buildServerTimingStats(results []Result) []ServerTimingStats {
+	durations := make(map[string][]time.Duration)
+	for _, result := range results {
+		for _, t := range result.ServerTiming {
+			durations[t.Name] = append(durations[t.Name], t.Duration)
+		}
+	}
+	if len(durations) == 0 {
+		return nil
+	}
+
+	stats := make([]ServerTimingStats, 0, len(durations))
+	for name, d := range durations {
+		stats = append(stats, ServerTimingStats{Name: name, Responses: len(d), Percentiles: percentilesOf(d)})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
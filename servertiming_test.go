@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	got := parseServerTiming(`db;dur=12.5, cache;desc="Cache; read, hot";dur=3, miss, total;DUR="40", bad;dur=x`)
+	want := []ServerTiming{
+		{Name: "db", Duration: 12500 * time.Microsecond},
+		{Name: "cache", Duration: 3 * time.Millisecond},
+		{Name: "total", Duration: 40 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseServerTiming = %+v, want %+v", got, want)
+	}
+	if got := parseServerTiming(""); got != nil {
+		t.Errorf("empty value = %+v, want nil", got)
+	}
+}
+
+func TestServerTimingFromHeadersAndTrailers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Server-Timing")
+		w.Header().Set("Server-Timing", "app;dur=10")
+		w.Write([]byte("streamed body"))
+		w.(http.Flusher).Flush()
+		w.Header().Set("Server-Timing", "db;dur=4")
+	}))
+	t.Cleanup(srv.Close)
+
+	stats := newTestLoadTester(t, testConfig(srv.URL)).Run(nil)
+	if len(stats.ServerTiming) != 2 {
+		t.Fatalf("server timing = %+v, want app and db", stats.ServerTiming)
+	}
+	app, db := stats.ServerTiming[0], stats.ServerTiming[1]
+	if app.Name != "app" || app.Responses != 50 || app.Percentiles[95] != 10*time.Millisecond {
+		t.Errorf("app = %+v", app)
+	}
+	if db.Name != "db" || db.Responses != 50 || db.Percentiles[50] != 4*time.Millisecond {
+		t.Errorf("db = %+v, want the trailer's 4ms on every response", db)
+	}
+
+	if stats := newTestLoadTester(t, testConfig(newTestServer(t, 0).URL)).Run(nil); stats.ServerTiming != nil {
+		t.Errorf("server timing = %+v without the header", stats.ServerTiming)
+	}
+}
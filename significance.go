@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// significanceLevel is the p-value below which compare calls a latency
+// difference significant
+const significanceLevel = 0.05
+
+// mannWhitneyU tests whether the latencies in a and b come from the same
+// distribution. It returns the U statistic of a and the two-sided p-value from
+// the normal approximation with tie and continuity corrections, which is
+// accurate for the sample sizes of a load test (more than ~20 per side).
+// ok is false when either sample is empty.
+func mannWhitneyU(a, b []time.Duration) (u, p float64, ok bool) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, false
+	}
+
+	type sample struct {
+		value time.Duration
+		fromA bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Rank the combined samples, giving tied values their average rank
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+
+	f1, f2 := float64(n1), float64(n2)
+	n := f1 + f2
+	u = rankSumA - f1*(f1+1)/2
+	mean := f1 * f2 / 2
+	variance := f1 * f2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// Every value is identical, so there is no difference to detect
+		return u, 1, true
+	}
+	z := math.Max(math.Abs(u-mean)-0.5, 0) / math.Sqrt(variance)
+	return u, math.Erfc(z / math.Sqrt2), true
+}
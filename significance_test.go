@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		d := make([]time.Duration, len(values))
+		for i, v := range values {
+			d[i] = time.Duration(v) * time.Millisecond
+		}
+		return d
+	}
+
+	// Matches scipy.stats.mannwhitneyu(a, b, method="asymptotic")
+	u, p, ok := mannWhitneyU(ms(1, 2, 3, 4, 5), ms(6, 7, 8, 9, 10))
+	if !ok || u != 0 || math.Abs(p-0.01219) > 1e-4 {
+		t.Errorf("separated samples: u=%v p=%v ok=%v, want u=0 p=0.01219", u, p, ok)
+	}
+	if _, p, _ := mannWhitneyU(ms(5, 5, 5), ms(5, 5)); p != 1 {
+		t.Errorf("identical values: p=%v, want 1", p)
+	}
+	if _, _, ok := mannWhitneyU(nil, ms(1)); ok {
+		t.Error("empty sample reported as tested")
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	sample := func(n int, mean time.Duration) []time.Duration {
+		d := make([]time.Duration, n)
+		for i := range d {
+			d[i] = mean + time.Duration(rng.NormFloat64()*float64(5*time.Millisecond))
+		}
+		return d
+	}
+	if _, p, _ := mannWhitneyU(sample(500, 50*time.Millisecond), sample(500, 50*time.Millisecond)); p < significanceLevel {
+		t.Errorf("same distribution: p=%v, want not significant", p)
+	}
+	if _, p, _ := mannWhitneyU(sample(500, 50*time.Millisecond), sample(500, 52*time.Millisecond)); p >= significanceLevel {
+		t.Errorf("shifted distribution: p=%v, want significant", p)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseMaxLine bounds the length of one line of an event stream
+const sseMaxLine = 1 << 20
+
+// sseEvents gathers the event figures of the streams of an --sse run
+type sseEvents struct {
+	mu     sync.Mutex
+	events int
+	gaps   []time.Duration // time between consecutive events of a stream
+	first  []time.Duration // time from opening a stream to its first event
+}
+
+func (c *sseEvents) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = 0
+	c.gaps = nil
+	c.first = nil
+}
+
+// openStream subscribes to the target as an event stream and reads events
+// until the server ends the stream or the run ends. The stream counts as a
+// single result: ResponseTime is how long it stayed open, HeaderTime how long
+// it took to open and Events how many events it delivered. A stream cut off by
+// the end of the run isn't a failure.
+func (lt *LoadTester) openStream() Result {
+	start := time.Now()
+	// The client has no overall timeout in this mode, as streams stay open;
+	// --timeout only bounds the wait for the response headers
+	ctx, cancel := context.WithCancel(lt.ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", lt.config.URL, nil)
+	if err != nil {
+		return Result{Error: err, Timestamp: time.Now()}
+	}
+	lt.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	requestID := lt.setRequestID(req)
+
+	var openTimer *time.Timer
+	if lt.config.Timeout > 0 {
+		openTimer = time.AfterFunc(lt.config.Timeout, cancel)
+	}
+	resp, err := lt.httpClient.Do(req)
+	headerTime := time.Since(start)
+	if openTimer != nil && !openTimer.Stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		err = fmt.Errorf("opening the event stream: %w", context.DeadlineExceeded)
+	}
+	if err != nil {
+		return Result{Error: err, ResponseTime: headerTime, HeaderTime: headerTime, Timestamp: time.Now(),
+			RequestID: requestID, Host: errorHost(err)}
+	}
+	defer resp.Body.Close()
+
+	result := Result{
+		StatusCode: resp.StatusCode,
+		HeaderTime: headerTime,
+		RequestID:  requestID,
+		Host:       resp.Request.URL.Host,
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		result.ContentSize, result.Events, result.Error = lt.readEvents(resp, start)
+		if lt.ctx.Err() != nil {
+			result.Error = nil
+		}
+	}
+	result.Timestamp = time.Now()
+	result.ResponseTime = result.Timestamp.Sub(start)
+	result.TTLB = result.ResponseTime
+	return result
+}
+
+// readEvents reads an event stream, recording when each event arrives. An
+// event is dispatched by a blank line once it has data; comments, often sent
+// as keep-alives, aren't events.
+func (lt *LoadTester) readEvents(resp *http.Response, opened time.Time) (size int64, events int, err error) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), sseMaxLine)
+	var last time.Time
+	hasData := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		size += int64(len(line)) + 1
+		switch {
+		case line == "":
+			if !hasData {
+				continue
+			}
+			hasData = false
+			now := time.Now()
+			events++
+			lt.sse.mu.Lock()
+			lt.sse.events++
+			if last.IsZero() {
+				lt.sse.first = append(lt.sse.first, now.Sub(opened))
+			} else {
+				lt.sse.gaps = append(lt.sse.gaps, now.Sub(last))
+			}
+			lt.sse.mu.Unlock()
+			last = now
+		case line == "data" || strings.HasPrefix(line, "data:"):
+			hasData = true
+		}
+	}
+	return size, events, scanner.Err()
+}
+
+// SSEStats summarises the events of an --sse run
+type SSEStats struct {
+	Streams      int     `json:"streams"`
+	Events       int     `json:"events"`
+	EventsPerSec float64 `json:"events_per_sec"`
+	// EventsPerStream spreads the events delivered by each stream
+	EventsPerStream Spread `json:"events_per_stream"`
+	// GapPercentiles are the times between consecutive events of a stream,
+	// FirstEventPercentiles the times from opening a stream to its first event
+	GapPercentiles        map[int]time.Duration `json:"gap_percentiles"`
+	FirstEventPercentiles map[int]time.Duration `json:"first_event_percentiles"`
+}
+
+func (lt *LoadTester) buildSSEStats(totalTime time.Duration) *SSEStats {
+	lt.sse.mu.Lock()
+	defer lt.sse.mu.Unlock()
+
+	s := &SSEStats{Streams: len(lt.results), Events: lt.sse.events}
+	perStream := make([]float64, len(lt.results))
+	for i, result := range lt.results {
+		perStream[i] = float64(result.Events)
+	}
+	s.EventsPerStream = newSpread(perStream)
+	if totalTime > 0 {
+		s.EventsPerSec = float64(s.Events) / totalTime.Seconds()
+	}
+	s.GapPercentiles = percentilesOf(append([]time.Duration(nil), lt.sse.gaps...))
+	s.FirstEventPercentiles = percentilesOf(append([]time.Duration(nil), lt.sse.first...))
+	return s
+}
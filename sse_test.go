@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newEventServer streams an event every interval, with a keep-alive comment
+// in between, until the client goes away or count events were sent
+func newEventServer(t *testing.T, interval time.Duration, count int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "not an event stream request", http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for i := 0; i < count; i++ {
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+			fmt.Fprintf(w, ": keep-alive\n\nid: %d\nevent: tick\ndata: {\"n\":%d}\ndata: more\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSSECountsEvents(t *testing.T) {
+	srv := newEventServer(t, 20*time.Millisecond, 1000)
+	config := testConfig(srv.URL)
+	config.SSE = true
+	config.Concurrent = 3
+	config.Requests = 3
+	config.MaxDuration = 300 * time.Millisecond
+
+	stats := newTestLoadTester(t, config).Run(nil)
+	s := stats.SSE
+	if s == nil {
+		t.Fatal("no SSE stats")
+	}
+	// Each stream gets an event every 20ms for 300ms
+	if s.Streams != 3 || s.Events < 30 || s.Events > 45 {
+		t.Errorf("%d events over %d streams, want about 42 over 3", s.Events, s.Streams)
+	}
+	if gap := s.GapPercentiles[50]; gap < 15*time.Millisecond || gap > 40*time.Millisecond {
+		t.Errorf("median gap = %v, want about 20ms", gap)
+	}
+	// Streams cut off by the end of the run aren't failures
+	if stats.FailedReqs != 0 || stats.SuccessfulReqs != 3 {
+		t.Errorf("successful/failed = %d/%d, want 3/0", stats.SuccessfulReqs, stats.FailedReqs)
+	}
+	if stats.Percentiles[50] < 250*time.Millisecond {
+		t.Errorf("streams stayed open %v, want the whole run", stats.Percentiles[50])
+	}
+}
+
+func TestSSEReconnectsEndedStreams(t *testing.T) {
+	srv := newEventServer(t, time.Millisecond, 5)
+	config := testConfig(srv.URL)
+	config.SSE = true
+	config.Concurrent = 2
+	config.Requests = 6
+	config.MaxDuration = 5 * time.Second
+
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.SSE.Streams != 6 || stats.SSE.Events != 30 {
+		t.Errorf("%d events over %d streams, want 30 over 6", stats.SSE.Events, stats.SSE.Streams)
+	}
+	if stats.SSE.EventsPerStream.Mean != 5 {
+		t.Errorf("events per stream = %+v, want 5 each", stats.SSE.EventsPerStream)
+	}
+}
+
+func TestSSEOpenTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	config := testConfig(srv.URL)
+	config.SSE = true
+	config.Timeout = 50 * time.Millisecond
+	config.MaxDuration = 5 * time.Second
+	result := newTestLoadTester(t, config).openStream()
+	if !errors.Is(result.Error, context.DeadlineExceeded) || errorCategory(result) != "timeout" {
+		t.Errorf("error = %v, want the open to time out", result.Error)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// bodyTemplate renders a fresh request body for every request from a
+// text/template that is parsed once
+type bodyTemplate struct {
+	tmpl *template.Template
+	seq  atomic.Int64
+	// ids backs the seq function, shared by every worker rendering the template
+	ids atomic.Int64
+	// instances are clones of tmpl whose random functions draw from the
+	// source of the request being rendered
+	instances sync.Pool
+}
+
+// templateInstance is a clone of the template bound to its own random source
+type templateInstance struct {
+	tmpl *template.Template
+	rng  *rand.Rand
+}
+
+// bodyTemplateData is the data a body template is executed with
+type bodyTemplateData struct {
+	Seq int64 // 1-based number of the request
+}
+
+const randStringAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// bodyTemplateFuncs are the functions available in body templates, drawing
+// random values from the source of inst
+func bodyTemplateFuncs(inst *templateInstance) template.FuncMap {
+	return template.FuncMap{
+		// randInt returns a random integer in [min, max]
+		"randInt": func(min, max int) int {
+			if max <= min {
+				return min
+			}
+			return min + inst.rng.IntN(max-min+1)
+		},
+		// randString returns n random alphanumeric characters
+		"randString": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = randStringAlphabet[inst.rng.IntN(len(randStringAlphabet))]
+			}
+			return string(b)
+		},
+		// uuid returns a random version 4 UUID
+		"uuid": func() string { return newUUID(inst.rng) },
+		"now":  time.Now,
+	}
+}
+
+// newUUID returns a version 4 UUID drawn from rng
+func newUUID(rng *rand.Rand) string {
+	var b [16]byte
+	for i := 0; i < len(b); i += 8 {
+		v := rng.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func newBodyTemplate(text string) (*bodyTemplate, error) {
+	t := &bodyTemplate{}
+	tmpl, err := template.New("body").Funcs(bodyTemplateFuncs(nil)).Funcs(template.FuncMap{
+		// seq returns the next value of a counter shared by all workers, so every
+		// call yields a new, strictly increasing id
+		"seq": func() int64 { return t.ids.Add(1) },
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	t.tmpl = tmpl
+	t.instances.New = func() any {
+		inst := &templateInstance{}
+		// Clone copies the function maps, so rebinding them doesn't affect other instances
+		inst.tmpl = template.Must(t.tmpl.Clone()).Funcs(bodyTemplateFuncs(inst))
+		return inst
+	}
+	return t, nil
+}
+
+// render executes the template for the next request into a new buffer, taking
+// random values from rng
+func (t *bodyTemplate) render(rng *rand.Rand) ([]byte, error) {
+	inst := t.instances.Get().(*templateInstance)
+	defer t.instances.Put(inst)
+	inst.rng = rng
+
+	var buf bytes.Buffer
+	if err := inst.tmpl.Execute(&buf, bodyTemplateData{Seq: t.seq.Add(1)}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBodyTemplateRendersPerRequest(t *testing.T) {
+	tmpl, err := newBodyTemplate(`{"seq": {{.Seq}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		body, err := tmpl.render(testRand())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"seq": ` + strconv.Itoa(i) + `}`; string(body) != want {
+			t.Errorf("render %d = %s, want %s", i, body, want)
+		}
+	}
+}
+
+func TestBodyTemplateFuncs(t *testing.T) {
+	tmpl, err := newBodyTemplate(`{{uuid}} {{randInt 3 5}} {{randString 8}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} [345] [a-zA-Z0-9]{8}$`)
+	for i := 0; i < 50; i++ {
+		body, err := tmpl.render(testRand())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pattern.Match(body) {
+			t.Fatalf("unexpected render %q", body)
+		}
+	}
+}
+
+func TestBodyTemplateErrors(t *testing.T) {
+	if _, err := newBodyTemplate(`{{`); err == nil {
+		t.Error("expected a parse error")
+	}
+	tmpl, err := newBodyTemplate(`{{.Missing}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.render(testRand()); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestBodyTemplateSeqIsUniqueAcrossWorkers(t *testing.T) {
+	tmpl, err := newBodyTemplate(`{{seq}},{{seq}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, renders = 8, 100
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < renders; i++ {
+				body, err := tmpl.render(testRand())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				first, second, _ := strings.Cut(string(body), ",")
+				a, _ := strconv.Atoi(first)
+				b, _ := strconv.Atoi(second)
+				if b <= a {
+					t.Errorf("ids within one body aren't increasing: %s", body)
+				}
+				mu.Lock()
+				seen[a], seen[b] = true, true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 2*workers*renders {
+		t.Errorf("got %d distinct ids, want %d", len(seen), 2*workers*renders)
+	}
+	for id := 1; id <= 2*workers*renders; id++ {
+		if !seen[id] {
+			t.Fatalf("id %d was skipped", id)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	// defaultTerminalWidth is assumed when stdout isn't a terminal and $COLUMNS is unset
+	defaultTerminalWidth = 80
+	// minTerminalWidth is the narrowest terminal the full progress line is rendered for
+	minTerminalWidth = 40
+)
+
+// terminalWidth returns the current width of the terminal attached to stdout.
+// It is queried on every call so a resized terminal is picked up on the next redraw.
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return defaultTerminalWidth
+}
+
+// visibleWidth counts the runes of s that occupy a column, skipping ANSI escape sequences
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// ansiSequenceLen returns the length of the CSI escape sequence at the start of s, or 0
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 || s[0] != '\033' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// stripANSI removes ANSI escape sequences from s
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// ellipsis marks text cut by truncate
+func ellipsis() string {
+	if useUnicode {
+		return "…"
+	}
+	return "..."
+}
+
+// unknownValue stands in for a value that isn't known yet
+func unknownValue() string {
+	if useUnicode {
+		return "—"
+	}
+	return "-"
+}
+
+// truncate shortens s to at most width visible runes, marking the cut with an
+// ellipsis. ANSI color sequences don't count towards the width and are never split.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if visibleWidth(s) <= width {
+		return s
+	}
+	marker := ellipsis()
+	keep := width - utf8.RuneCountInString(marker)
+	if keep < 1 {
+		// Too narrow for the marker, so cut without one
+		marker, keep = "", width
+	}
+
+	var b strings.Builder
+	visible := 0
+	colored := false
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			colored = true
+			i += n
+			continue
+		}
+		if visible >= keep {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		visible++
+	}
+	if colored {
+		b.WriteString(colorReset)
+	}
+	b.WriteString(marker)
+	return b.String()
+}
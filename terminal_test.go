@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is too long", 8, "this is…"},
+		{"héllo wörld", 6, "héllo…"},
+		{colorRed + "12ms" + colorReset + " rest of line", 6, colorRed + "12ms" + colorReset + " " + colorReset + "…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+		if got := visibleWidth(truncate(tt.in, tt.width)); got > tt.width {
+			t.Errorf("truncate(%q, %d) is %d columns wide", tt.in, tt.width, got)
+		}
+	}
+}
+
+func TestLatencyThresholds(t *testing.T) {
+	thresholds := latencyThresholds{warn: 100 * time.Millisecond, crit: 500 * time.Millisecond}
+	tests := []struct {
+		latency time.Duration
+		want    string
+	}{
+		{50 * time.Millisecond, colorGreen},
+		{100 * time.Millisecond, colorYellow},
+		{499 * time.Millisecond, colorYellow},
+		{500 * time.Millisecond, colorRed},
+		{2 * time.Second, colorRed},
+	}
+	for _, tt := range tests {
+		if got := thresholds.color(tt.latency); got != tt.want {
+			t.Errorf("color(%v) = %q, want %q", tt.latency, got, tt.want)
+		}
+	}
+
+	if got := (latencyThresholds{}).colorize(time.Second, "1s"); got != "1s" {
+		t.Errorf("colorize without thresholds = %q, want plain value", got)
+	}
+	if got := (latencyThresholds{crit: time.Second}).color(900 * time.Millisecond); got != colorGreen {
+		t.Errorf("color below crit with no warn = %q, want green", got)
+	}
+}
+
+func TestPlainOutput(t *testing.T) {
+	defer func(color, unicode bool) { useColor, useUnicode = color, unicode }(useColor, useUnicode)
+	configureOutputStyle(true)
+
+	if got, want := truncate("this is too long", 8), "this ..."; got != want {
+		t.Errorf("truncate = %q, want %q", got, want)
+	}
+	if got, want := truncate("abcdef", 2), "ab"; got != want {
+		t.Errorf("truncate narrower than the marker = %q, want %q", got, want)
+	}
+	if got, want := sparkline([]float64{0, 1, 2, 7}, 10), "_.-#"; got != want {
+		t.Errorf("sparkline = %q, want %q", got, want)
+	}
+	thresholds := latencyThresholds{warn: time.Millisecond}
+	if got := thresholds.colorize(time.Second, "1s"); got != "1s" {
+		t.Errorf("colorize = %q, want it uncolored", got)
+	}
+	if got := stripANSI(banner); strings.Contains(got, "\033") {
+		t.Error("stripANSI left escape sequences in the banner")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// sparklineWidth is the maximum number of characters a sparkline occupies;
+// longer series are downsampled so the summary fits an 80-column terminal
+const sparklineWidth = 60
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// asciiSparkBlocks replace sparkBlocks when output is limited to ASCII
+var asciiSparkBlocks = []rune("_.-:=+*#")
+
+// percentileWindow is the span of the rolling percentiles on each timeline bucket
+const percentileWindow = 30 * time.Second
+
+// TimelineBucket aggregates the requests that completed during one second of the run
+type TimelineBucket struct {
+	Second   int           `json:"second"`
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	P95      time.Duration `json:"p95"`
+	// InFlight is the average number of requests in flight during the second
+	InFlight float64 `json:"in_flight"`
+	// Dispatched counts the requests a --profile scheduled in the second and
+	// Completed those that finished in it; Backlog is how many scheduled
+	// requests were left uncompleted at its end. All three are 0 without a profile.
+	Dispatched int `json:"dispatched,omitempty"`
+	Completed  int `json:"completed,omitempty"`
+	Backlog    int `json:"backlog,omitempty"`
+	// WindowPercentiles cover the percentileWindow ending with this second,
+	// read from a log-bucketed histogram, so they are slightly rounded up
+	WindowPercentiles map[int]time.Duration `json:"window_percentiles"`
+}
+
+// Degradation compares latency in the first and the last tenth of a run,
+// which shows a server slowing down over time (a leak, a growing queue)
+type Degradation struct {
+	Start map[int]time.Duration `json:"start"`
+	End   map[int]time.Duration `json:"end"`
+}
+
+// degradationMinSeconds is the shortest run whose tenths are compared
+const degradationMinSeconds = 10
+
+// Growth returns how many times the p-th percentile grew from start to end,
+// below 1 when it fell; 0 when the start is 0
+func (d *Degradation) Growth(p int) float64 {
+	if d.Start[p] == 0 {
+		return 0
+	}
+	return float64(d.End[p]) / float64(d.Start[p])
+}
+
+// buildDegradation compares the first and last tenth of the timeline's
+// seconds, nil when the run is shorter than degradationMinSeconds
+func buildDegradation(seconds [][]time.Duration) *Degradation {
+	if len(seconds) < degradationMinSeconds {
+		return nil
+	}
+	tenth := (len(seconds) + 9) / 10
+	var start, end []time.Duration
+	for _, times := range seconds[:tenth] {
+		start = append(start, times...)
+	}
+	for _, times := range seconds[len(seconds)-tenth:] {
+		end = append(end, times...)
+	}
+	if len(start) == 0 || len(end) == 0 {
+		return nil
+	}
+	return &Degradation{Start: percentilesOf(start), End: percentilesOf(end)}
+}
+
+// buildTimeline groups results into per-second buckets relative to start and
+// compares the latency of the start and the end of the run
+func buildTimeline(results []Result, start time.Time, success func(Result) bool) ([]TimelineBucket, *Degradation) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	var seconds [][]time.Duration
+	var errors []int
+	var busy []time.Duration // time spent in flight during each second, summed over requests
+	for _, result := range results {
+		// Bucket by when the request was sent, so a spike lines up with what
+		// the server was doing at the time rather than when slow responses ended
+		at := result.StartTime
+		if at.IsZero() {
+			at = result.Timestamp
+		}
+		sec := int(at.Sub(start) / time.Second)
+		if sec < 0 {
+			sec = 0
+		}
+		for len(seconds) <= sec {
+			seconds = append(seconds, nil)
+			errors = append(errors, 0)
+		}
+		// Spread the request's time in flight over the seconds it spans
+		from, to := max(at.Sub(start), 0), at.Sub(start)+result.ResponseTime
+		for from < to {
+			s := int(from / time.Second)
+			end := min(time.Duration(s+1)*time.Second, to)
+			for len(busy) <= s {
+				busy = append(busy, 0)
+			}
+			busy[s] += end - from
+			from = end
+		}
+		seconds[sec] = append(seconds[sec], result.ResponseTime)
+		if !success(result) {
+			errors[sec]++
+		}
+	}
+
+	// The window histogram slides one second at a time: each second's
+	// histogram is added as it enters and subtracted as it leaves
+	windowSeconds := int(percentileWindow / time.Second)
+	hists := make([]latencyHistogram, len(seconds))
+	var window latencyHistogram
+	timeline := make([]TimelineBucket, len(seconds))
+	for sec, times := range seconds {
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		for _, d := range times {
+			hists[sec].record(d)
+		}
+		window.merge(&hists[sec])
+		if sec >= windowSeconds {
+			window.subtract(&hists[sec-windowSeconds])
+		}
+		windowPercentiles := make(map[int]time.Duration)
+		for _, p := range reportedPercentiles {
+			windowPercentiles[p] = window.quantile(p)
+		}
+		timeline[sec] = TimelineBucket{
+			Second:            sec,
+			Requests:          len(times),
+			Errors:            errors[sec],
+			P95:               percentile(times, 95),
+			WindowPercentiles: windowPercentiles,
+		}
+		if sec < len(busy) {
+			timeline[sec].InFlight = float64(busy[sec]) / float64(time.Second)
+		}
+	}
+	return timeline, buildDegradation(seconds)
+}
+
+// sparkline renders values as a row of block characters scaled to the largest value.
+// Series longer than width are downsampled by averaging adjacent points.
+func sparkline(values []float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	if len(values) > width {
+		sampled := make([]float64, width)
+		for i := range sampled {
+			from := i * len(values) / width
+			to := (i + 1) * len(values) / width
+			var sum float64
+			for _, v := range values[from:to] {
+				sum += v
+			}
+			sampled[i] = sum / float64(to-from)
+		}
+		values = sampled
+	}
+
+	var max float64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	blocks := sparkBlocks
+	if !useUnicode {
+		blocks = asciiSparkBlocks
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if max > 0 {
+			level = int(v / max * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimelineWindowAndDegradation(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var results []Result
+	// 60 seconds, 10 requests each; latency grows from 10ms to 69ms
+	for sec := 0; sec < 60; sec++ {
+		for i := 0; i < 10; i++ {
+			results = append(results, Result{
+				StartTime:    start.Add(time.Duration(sec)*time.Second + time.Duration(i)*time.Millisecond),
+				ResponseTime: time.Duration(10+sec) * time.Millisecond,
+				StatusCode:   200,
+			})
+		}
+	}
+	timeline, degradation := buildTimeline(results, start, func(r Result) bool { return true })
+	if len(timeline) != 60 {
+		t.Fatalf("timeline has %d buckets, want 60", len(timeline))
+	}
+
+	// The window ending at second 59 covers seconds 30-59, 40ms to 69ms; the
+	// histogram rounds up by at most a bucket
+	p50 := timeline[59].WindowPercentiles[50]
+	if p50 < 54*time.Millisecond || p50 > 58*time.Millisecond {
+		t.Errorf("window p50 at 59s = %v, want about 54ms", p50)
+	}
+	if p99 := timeline[59].WindowPercentiles[99]; p99 < 69*time.Millisecond || p99 > 73*time.Millisecond {
+		t.Errorf("window p99 at 59s = %v, want about 69ms", p99)
+	}
+	if p50 := timeline[0].WindowPercentiles[50]; p50 < 10*time.Millisecond || p50 > 11*time.Millisecond {
+		t.Errorf("window p50 at 0s = %v, want about 10ms", p50)
+	}
+
+	if degradation == nil {
+		t.Fatal("no degradation for a 60s run")
+	}
+	// First tenth: seconds 0-5 (10-15ms), last tenth: 54-59 (64-69ms)
+	if degradation.Start[95] != 15*time.Millisecond || degradation.End[95] != 69*time.Millisecond {
+		t.Errorf("p95 start/end = %v/%v, want 15ms/69ms", degradation.Start[95], degradation.End[95])
+	}
+	if g := degradation.Growth(95); g < 4.5 || g > 4.7 {
+		t.Errorf("p95 growth = %.2f, want 4.6", g)
+	}
+
+	// Each second has 10 requests of 10-69ms in flight
+	if got := timeline[0].InFlight; got < 0.099 || got > 0.101 {
+		t.Errorf("in flight during second 0 = %.3f, want 0.1", got)
+	}
+
+	if _, d := buildTimeline(results[:50], start, func(r Result) bool { return true }); d != nil {
+		t.Errorf("degradation for a 5s run = %+v, want nil", d)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "time"
+
+// TimeoutStats describes the requests abandoned at the timeout. A timeout is
+// only known to be slower than the wait, so it is kept apart from failures
+// the server answered and from the latencies of responses.
+type TimeoutStats struct {
+	Count int
+	// Share is the fraction of failed requests that timed out
+	Share float64
+	// Waited is how long the abandoned requests had been running, reported
+	// percentiles and extremes
+	Waited    map[int]time.Duration
+	MinWaited time.Duration
+	MaxWaited time.Duration
+	// SlowestSuccess is the longest a successful request took, 0 when none
+	// succeeded
+	SlowestSuccess time.Duration
+}
+
+// buildTimeoutStats returns nil when no request timed out
+func buildTimeoutStats(results []Result, success func(Result) bool) *TimeoutStats {
+	var waited []time.Duration
+	var failed int
+	var slowest time.Duration
+	for _, result := range results {
+		if success(result) {
+			slowest = max(slowest, result.ResponseTime)
+			continue
+		}
+		failed++
+		if result.Error != nil && errorCategory(result) == "timeout" {
+			waited = append(waited, result.ResponseTime)
+		}
+	}
+	if len(waited) == 0 {
+		return nil
+	}
+
+	s := &TimeoutStats{
+		Count:          len(waited),
+		Share:          float64(len(waited)) / float64(failed),
+		Waited:         percentilesOf(waited),
+		SlowestSuccess: slowest,
+	}
+	// percentilesOf sorted the waits
+	s.MinWaited, s.MaxWaited = waited[0], waited[len(waited)-1]
+	return s
+}
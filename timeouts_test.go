@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeoutStats(t *testing.T) {
+	results := []Result{
+		{StatusCode: 200, ResponseTime: 40 * time.Millisecond},
+		{StatusCode: 200, ResponseTime: 90 * time.Millisecond},
+		{StatusCode: 500, ResponseTime: 10 * time.Millisecond},
+		{Error: errors.New("connection refused"), ResponseTime: time.Millisecond},
+		{Error: context.DeadlineExceeded, ResponseTime: 100 * time.Millisecond},
+		{Error: context.DeadlineExceeded, ResponseTime: 102 * time.Millisecond},
+	}
+	tester := newTestLoadTester(t, testConfig("http://example.com"))
+	s := buildTimeoutStats(results, tester.isSuccess)
+	if s == nil {
+		t.Fatal("no timeout stats")
+	}
+	if s.Count != 2 || s.Share != 0.5 {
+		t.Errorf("count %d, share %v; want 2 of 4 failures", s.Count, s.Share)
+	}
+	if s.MinWaited != 100*time.Millisecond || s.MaxWaited != 102*time.Millisecond {
+		t.Errorf("waited %v to %v, want 100ms to 102ms", s.MinWaited, s.MaxWaited)
+	}
+	if s.SlowestSuccess != 90*time.Millisecond {
+		t.Errorf("slowest success = %v, want 90ms", s.SlowestSuccess)
+	}
+
+	if s := buildTimeoutStats(results[:4], tester.isSuccess); s != nil {
+		t.Errorf("stats without timeouts = %+v, want nil", s)
+	}
+}
+
+func TestZeroTimeoutWaitsForSlowResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Timeout = 0
+	config.Requests = 4
+	stats := newTestLoadTester(t, config).Run(nil)
+	if stats.FailedReqs != 0 || stats.Timeouts != nil {
+		t.Errorf("%d failed, timeouts %+v; want none without a timeout", stats.FailedReqs, stats.Timeouts)
+	}
+
+	config.Timeout = 10 * time.Millisecond
+	stats = newTestLoadTester(t, config).Run(nil)
+	if stats.Timeouts == nil || stats.Timeouts.Count != 4 {
+		t.Fatalf("timeouts = %+v, want all 4 requests", stats.Timeouts)
+	}
+	if stats.Timeouts.MinWaited < 10*time.Millisecond {
+		t.Errorf("gave up after %v, before the 10ms timeout", stats.Timeouts.MinWaited)
+	}
+}
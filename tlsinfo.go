@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/tls"
+	"sort"
+)
+
+// TLSParams counts the responses that used one TLS version and cipher suite
+type TLSParams struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	Requests    int    `json:"requests"`
+}
+
+// buildTLSParams tallies the negotiated TLS parameters of https results, most
+// common first; nil when no result used TLS
+func buildTLSParams(results []Result) []TLSParams {
+	type key struct{ version, cipher uint16 }
+	counts := make(map[key]int)
+	for _, result := range results {
+		if result.TLSVersion != 0 {
+			counts[key{result.TLSVersion, result.TLSCipherSuite}]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	params := make([]TLSParams, 0, len(counts))
+	for k, n := range counts {
+		params = append(params, TLSParams{
+			Version:     tls.VersionName(k.version),
+			CipherSuite: tls.CipherSuiteName(k.cipher),
+			Requests:    n,
+		})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Requests != params[j].Requests {
+			return params[i].Requests > params[j].Requests
+		}
+		return params[i].Version+params[i].CipherSuite < params[j].Version+params[j].CipherSuite
+	})
+	return params
+}
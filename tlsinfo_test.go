@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSParams(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.InsecureTLS = true
+	config.Requests = 10
+	stats := newTestLoadTester(t, config).Run(nil)
+	if len(stats.TLS) != 1 || stats.TLS[0].Requests != 10 {
+		t.Fatalf("TLS = %+v, want one entry covering 10 requests", stats.TLS)
+	}
+	if stats.TLS[0].Version != "TLS 1.3" {
+		t.Errorf("version = %q, want TLS 1.3", stats.TLS[0].Version)
+	}
+
+	params := buildTLSParams([]Result{
+		{TLSVersion: tls.VersionTLS12, TLSCipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		{TLSVersion: tls.VersionTLS13, TLSCipherSuite: tls.TLS_AES_128_GCM_SHA256},
+		{TLSVersion: tls.VersionTLS13, TLSCipherSuite: tls.TLS_AES_128_GCM_SHA256},
+		{StatusCode: http.StatusOK},
+	})
+	if len(params) != 2 || params[0].Requests != 2 || params[1].CipherSuite != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" {
+		t.Errorf("params = %+v", params)
+	}
+	if buildTLSParams([]Result{{StatusCode: http.StatusOK}}) != nil {
+		t.Error("plain HTTP results produced TLS params")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxTracedRequests bounds -v, which prints a line per request and would
+// flood the terminal on a large run
+const maxTracedRequests = 1000
+
+// describeResult renders one result as "METHOD URL -> outcome", prefixed with
+// "#seq" for requests of a run
+func (lt *LoadTester) describeResult(result Result) string {
+	var b strings.Builder
+	if result.Seq > 0 {
+		fmt.Fprintf(&b, "#%d ", result.Seq)
+	}
+	if lt.config.ConnectOnly {
+		fmt.Fprintf(&b, "CONNECT %s -> ", lt.config.URL)
+	} else {
+		fmt.Fprintf(&b, "%s %s -> ", result.Method, lt.config.URL)
+	}
+	switch {
+	case result.Error != nil:
+		fmt.Fprintf(&b, "error after %v: %v", result.ResponseTime, result.Error)
+	case lt.config.ConnectOnly:
+		fmt.Fprintf(&b, "connected in %v", result.ResponseTime)
+	default:
+		fmt.Fprintf(&b, "%d in %v (%s)", result.StatusCode, result.ResponseTime, formatBytes(result.ContentSize))
+	}
+	if result.RequestID != "" {
+		fmt.Fprintf(&b, " [%s]", result.RequestID)
+	}
+	return b.String()
+}
+
+// formatHeaders renders response headers one per line, sorted by name and indented
+func formatHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&b, "    %s: %s\n", name, value)
+		}
+	}
+	return b.String()
+}
+
+// newTracer returns the per-request callback for -v: it prints each completed
+// request, with its response headers at verbosity 2, and logs it
+func newTracer(tester *LoadTester, verbosity int, bar bool) func(Result) {
+	return func(result Result) {
+		logger.Info("request",
+			"seq", result.Seq,
+			"request_id", result.RequestID,
+			"worker", result.WorkerID,
+			"start", result.StartTime,
+			"method", result.Method,
+			"url", tester.config.URL,
+			"status", result.StatusCode,
+			"response_time", result.ResponseTime,
+			"bytes", result.ContentSize,
+			"error", errorMessage(result.Error))
+
+		line := tester.describeResult(result) + "\n"
+		if verbosity >= 2 && result.Headers != nil {
+			line += formatHeaders(result.Headers)
+		}
+
+		outputMu.Lock()
+		defer outputMu.Unlock()
+		if bar {
+			// Clear the progress line; it is redrawn below on the next tick
+			fmt.Print("\r\033[K")
+		}
+		fmt.Print(line)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDescribeResult(t *testing.T) {
+	tester := newTestLoadTester(t, testConfig("http://example.com/"))
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{Result{Method: "GET", StatusCode: 200, ResponseTime: 12 * time.Millisecond, ContentSize: 5},
+			"GET http://example.com/ -> 200 in 12ms (5 bytes)"},
+		{Result{Method: "POST", ResponseTime: time.Second, Error: errors.New("boom")},
+			"POST http://example.com/ -> error after 1s: boom"},
+		{Result{Method: "POST", StatusCode: 201, ResponseTime: time.Millisecond, Seq: 7, RequestID: "0f8c"},
+			"#7 POST http://example.com/ -> 201 in 1ms (0 bytes) [0f8c]"},
+	}
+	for _, tt := range tests {
+		if got := tester.describeResult(tt.result); got != tt.want {
+			t.Errorf("describeResult = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFormatHeaders(t *testing.T) {
+	header := http.Header{"X-B": {"2", "3"}, "X-A": {"1"}}
+	want := "    X-A: 1\n    X-B: 2\n    X-B: 3\n"
+	if got := formatHeaders(header); got != want {
+		t.Errorf("formatHeaders = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// maxTrackedValues caps the distinct values broken out per tracked header;
+	// rarer values beyond the cap are pooled under otherHeaderValue
+	maxTrackedValues = 20
+	otherHeaderValue = "(other)"
+	// missingHeaderValue stands for responses without the header
+	missingHeaderValue = "(none)"
+)
+
+// trackedHeaderValues returns the values of names in header, in the same order
+func trackedHeaderValues(header http.Header, names []string) []string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = header.Get(name)
+	}
+	return values
+}
+
+// HeaderValueStats describes the responses that carried one value of a tracked header
+type HeaderValueStats struct {
+	Value       string                `json:"value"`
+	Requests    int                   `json:"requests"`
+	Percentiles map[int]time.Duration `json:"percentiles"`
+}
+
+// HeaderBreakdown tallies the values of a --track-header header, most frequent first
+type HeaderBreakdown struct {
+	Header string             `json:"header"`
+	Values []HeaderValueStats `json:"values"`
+}
+
+// buildHeaderBreakdowns groups the responses by the value of each tracked header
+func buildHeaderBreakdowns(results []Result, names []string) []HeaderBreakdown {
+	breakdowns := make([]HeaderBreakdown, len(names))
+	for i, name := range names {
+		var order []string
+		times := make(map[string][]time.Duration)
+		for _, result := range results {
+			// Failed requests have no response, so no header to tally
+			if result.TrackedHeaders == nil {
+				continue
+			}
+			value := result.TrackedHeaders[i]
+			if value == "" {
+				value = missingHeaderValue
+			}
+			if _, seen := times[value]; !seen {
+				if len(order) >= maxTrackedValues {
+					value = otherHeaderValue
+				}
+				if _, seen := times[value]; !seen {
+					order = append(order, value)
+				}
+			}
+			times[value] = append(times[value], result.ResponseTime)
+		}
+
+		values := make([]HeaderValueStats, 0, len(order))
+		for _, value := range order {
+			values = append(values, HeaderValueStats{
+				Value:       value,
+				Requests:    len(times[value]),
+				Percentiles: percentilesOf(times[value]),
+			})
+		}
+		sort.SliceStable(values, func(a, b int) bool { return values[a].Requests > values[b].Requests })
+		breakdowns[i] = HeaderBreakdown{Header: name, Values: values}
+	}
+	return breakdowns
+}
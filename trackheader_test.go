@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBuildHeaderBreakdowns(t *testing.T) {
+	var results []Result
+	for i := 0; i < 6; i++ {
+		results = append(results, Result{ResponseTime: 2 * time.Millisecond, TrackedHeaders: []string{"HIT"}})
+	}
+	for i := 0; i < 3; i++ {
+		results = append(results, Result{ResponseTime: 40 * time.Millisecond, TrackedHeaders: []string{"MISS"}})
+	}
+	results = append(results,
+		Result{ResponseTime: time.Millisecond, TrackedHeaders: []string{""}},
+		Result{Error: fmt.Errorf("refused")})
+
+	breakdowns := buildHeaderBreakdowns(results, []string{"X-Cache"})
+	if len(breakdowns) != 1 || breakdowns[0].Header != "X-Cache" {
+		t.Fatalf("breakdowns = %+v", breakdowns)
+	}
+	values := breakdowns[0].Values
+	if len(values) != 3 {
+		t.Fatalf("values = %+v, want HIT, MISS and (none)", values)
+	}
+	if values[0].Value != "HIT" || values[0].Requests != 6 || values[0].Percentiles[95] != 2*time.Millisecond {
+		t.Errorf("first value = %+v", values[0])
+	}
+	if values[1].Value != "MISS" || values[1].Percentiles[50] != 40*time.Millisecond {
+		t.Errorf("second value = %+v", values[1])
+	}
+	if values[2].Value != missingHeaderValue || values[2].Requests != 1 {
+		t.Errorf("third value = %+v", values[2])
+	}
+}
+
+func TestHeaderBreakdownCapsValues(t *testing.T) {
+	var results []Result
+	for i := 0; i < maxTrackedValues+10; i++ {
+		results = append(results, Result{TrackedHeaders: []string{fmt.Sprintf("node-%d", i)}})
+	}
+	values := buildHeaderBreakdowns(results, []string{"Server"})[0].Values
+	if len(values) != maxTrackedValues+1 {
+		t.Fatalf("%d values, want %d plus %s", len(values), maxTrackedValues, otherHeaderValue)
+	}
+	if values[0].Value != otherHeaderValue || values[0].Requests != 10 {
+		t.Errorf("pooled value = %+v, want 10 requests under %s", values[0], otherHeaderValue)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tuneMaxErrorRate is the share of failed requests above which a probe misses
+// the target however fast it was, so errors can't pass for low latency
+const tuneMaxErrorRate = 0.01
+
+// TuneProbe is the outcome of one probing run of --target-p95
+type TuneProbe struct {
+	Concurrency int           `json:"concurrency"`
+	Requests    int           `json:"requests"`
+	Failed      int           `json:"failed"`
+	RPS         float64       `json:"rps"`
+	P95         time.Duration `json:"p95"`
+	Within      bool          `json:"within_target"`
+}
+
+// TuneStats reports the concurrency search of --target-p95. Concurrency is
+// the highest level that kept p95 within the target, 0 when none did.
+type TuneStats struct {
+	TargetP95   time.Duration `json:"target_p95"`
+	Concurrency int           `json:"concurrency"`
+	Probes      []TuneProbe   `json:"probes"`
+}
+
+// probe runs the test again with the concurrency limited to c
+func (lt *LoadTester) probe(c int) *Stats {
+	lt.Reset()
+	lt.limiter.setLimit(c)
+	return lt.Run(nil)
+}
+
+// TuneConcurrency binary-searches between 1 and the configured concurrency
+// for the highest level whose p95 stays within target, one probing run per
+// level. It starts with the configured level, which ends the search when it
+// already meets the target. The returned stats are those of the best probe,
+// or of the last one when none met the target, and the tester is left holding
+// that probe's results. onProbe, when set, is called after each probe.
+func (lt *LoadTester) TuneConcurrency(target time.Duration, onProbe func(TuneProbe)) (*Stats, *TuneStats) {
+	tune := &TuneStats{TargetP95: target}
+	var best, last *Stats
+	var bestResults []Result
+	var bestStart, bestEnd time.Time
+
+	try := func(c int) bool {
+		stats := lt.probe(c)
+		p := TuneProbe{
+			Concurrency: c,
+			Requests:    stats.TotalRequests,
+			Failed:      stats.FailedReqs,
+			RPS:         stats.RequestsPerSec,
+			P95:         stats.Percentiles[95],
+		}
+		p.Within = stats.TotalRequests > 0 && p.P95 <= target &&
+			float64(p.Failed) <= tuneMaxErrorRate*float64(p.Requests)
+		tune.Probes = append(tune.Probes, p)
+		if onProbe != nil {
+			onProbe(p)
+		}
+		last = stats
+		if p.Within {
+			tune.Concurrency = c
+			best = stats
+			lt.mu.Lock()
+			bestResults, bestStart, bestEnd = lt.results, lt.startTime, lt.endTime
+			lt.mu.Unlock()
+		}
+		return p.Within
+	}
+
+	// lo met the target (0 meaning nothing has yet), hi missed it
+	lo, hi := 0, lt.config.Concurrent
+	if !try(hi) {
+		for hi-lo > 1 && !lt.interrupted() {
+			mid := lo + (hi-lo)/2
+			if try(mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+	}
+
+	if best == nil {
+		return last, tune
+	}
+	if best != last {
+		// Reports and --output describe the best probe, not the last one
+		lt.mu.Lock()
+		lt.results, lt.startTime, lt.endTime = bestResults, bestStart, bestEnd
+		lt.mu.Unlock()
+	}
+	return best, tune
+}
+
+// String is the one-line account of a probe printed as the search goes
+func (p TuneProbe) String() string {
+	verdict := "over target"
+	if p.Within {
+		verdict = "within target"
+	}
+	return fmt.Sprintf("concurrency %d: p95 %v, %.2f req/s, %d/%d failed, %s",
+		p.Concurrency, roundLatency(p.P95), p.RPS, p.Failed, p.Requests, verdict)
+}
+
+func printTuneStats(tune *TuneStats) {
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("CONCURRENCY TUNING (p95 target %v)\n", tune.TargetP95)
+	fmt.Println(strings.Repeat("-", 40))
+	for _, p := range tune.Probes {
+		fmt.Println(p)
+	}
+	if tune.Concurrency == 0 {
+		fmt.Println("No concurrency kept p95 within the target, not even 1")
+		return
+	}
+	fmt.Printf("Highest concurrency within target: %d (detailed above)\n", tune.Concurrency)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTuneConcurrencyFindsHighestWithinTarget(t *testing.T) {
+	// Each request takes 10ms per request in flight, itself included
+	var inFlight atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		time.Sleep(time.Duration(n) * 10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Concurrent = 8
+	config.Requests = 40
+	tester := newTestLoadTester(t, config)
+
+	var probed []int
+	stats, tune := tester.TuneConcurrency(45*time.Millisecond, func(p TuneProbe) {
+		probed = append(probed, p.Concurrency)
+	})
+	if len(probed) == 0 || probed[0] != 8 {
+		t.Fatalf("probed %v, want the configured concurrency first", probed)
+	}
+	// Up to 4 in flight stay within 45ms, 5 take 50ms
+	if tune.Concurrency < 2 || tune.Concurrency > 4 {
+		t.Errorf("tuned concurrency = %d, want about 4 (probes %+v)", tune.Concurrency, tune.Probes)
+	}
+	if stats.Percentiles[95] > 45*time.Millisecond {
+		t.Errorf("reported p95 = %v, want the best probe's", stats.Percentiles[95])
+	}
+	if got := len(tester.results); got != stats.TotalRequests {
+		t.Errorf("tester holds %d results, want the best probe's %d", got, stats.TotalRequests)
+	}
+}
+
+func TestTuneConcurrencyNoneWithinTarget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Concurrent = 4
+	config.Requests = 8
+	_, tune := newTestLoadTester(t, config).TuneConcurrency(time.Millisecond, nil)
+	if tune.Concurrency != 0 {
+		t.Errorf("tuned concurrency = %d, want 0", tune.Concurrency)
+	}
+	// 4 misses, then 2 and 1
+	if len(tune.Probes) != 3 || tune.Probes[2].Concurrency != 1 {
+		t.Errorf("probes = %+v, want 4, 2 and 1", tune.Probes)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Payload formats selectable with --webhook-format
+const (
+	webhookAuto  = "auto"
+	webhookJSON  = "json"
+	webhookSlack = "slack"
+)
+
+// webhookTimeout bounds posting the summary, so an unreachable endpoint can't
+// hang the end of a run
+const webhookTimeout = 10 * time.Second
+
+// resolveWebhookFormat checks the --webhook URL and resolves an auto format:
+// Slack incoming webhooks get a Slack message, anything else the JSON summary
+func resolveWebhookFormat(rawURL, format string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid --webhook %q: must be an http or https URL", rawURL)
+	}
+	switch format {
+	case webhookJSON, webhookSlack:
+		return format, nil
+	case webhookAuto:
+		if u.Hostname() == "hooks.slack.com" {
+			return webhookSlack, nil
+		}
+		return webhookJSON, nil
+	}
+	return "", fmt.Errorf("invalid --webhook-format %q: must be auto, json or slack", format)
+}
+
+// webhookText is the human-readable line of a webhook payload
+func webhookText(config Config, stats *Stats) string {
+	text := fmt.Sprintf("Load test of %s %s finished", methodDescription(config), config.URL)
+	if reason := stats.stopReason(); reason != "" {
+		text += fmt.Sprintf(" (stopped by %s)", reason)
+	}
+	return text
+}
+
+// webhookPayload renders the final stats for --webhook. The JSON format is
+// the --format json summary plus a "text" line, which chat services that
+// accept a plain text field display on their own. Secrets are redacted as in
+// --print-config, since the payload leaves the machine.
+func (lt *LoadTester) webhookPayload(format string, stats *Stats) ([]byte, error) {
+	text := webhookText(lt.config, stats)
+	var data interface{}
+	if format == webhookSlack {
+		data = map[string]string{
+			"text": fmt.Sprintf("%s\n```%s```", text, summaryLine(stats)),
+		}
+	} else {
+		data = map[string]interface{}{
+			"text":     text + ": " + summaryLine(stats),
+			"metadata": lt.metadata(),
+			"config":   lt.config,
+			"stats":    stats,
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return []byte(redactSecrets(buf.String(), lt.config.secrets)), nil
+}
+
+// PostWebhook posts the final stats to the --webhook URL
+func (lt *LoadTester) PostWebhook(webhookURL, format string, stats *Stats) error {
+	payload, err := lt.webhookPayload(format, stats)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveWebhookFormat(t *testing.T) {
+	tests := []struct {
+		url, format, want string
+	}{
+		{"https://hooks.slack.com/services/T/B/x", webhookAuto, webhookSlack},
+		{"https://example.com/hook", webhookAuto, webhookJSON},
+		{"https://hooks.slack.com/services/T/B/x", webhookJSON, webhookJSON},
+		{"http://example.com/hook", webhookSlack, webhookSlack},
+	}
+	for _, tt := range tests {
+		got, err := resolveWebhookFormat(tt.url, tt.format)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveWebhookFormat(%q, %q) = %q, %v; want %q", tt.url, tt.format, got, err, tt.want)
+		}
+	}
+	for _, bad := range [][2]string{{"example.com/hook", webhookAuto}, {"ftp://example.com", webhookAuto}, {"https://example.com", "xml"}} {
+		if _, err := resolveWebhookFormat(bad[0], bad[1]); err == nil {
+			t.Errorf("resolveWebhookFormat(%q, %q) succeeded, want an error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var received []map[string]interface{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		received = append(received, payload)
+	}))
+	defer hook.Close()
+
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Headers["Authorization"] = "Bearer s3cr3t"
+	config.secrets = []string{"s3cr3t"}
+	tester := newTestLoadTester(t, config)
+	stats := tester.Run(nil)
+
+	for _, format := range []string{webhookJSON, webhookSlack} {
+		if err := tester.PostWebhook(hook.URL, format, stats); err != nil {
+			t.Fatalf("PostWebhook(%s): %v", format, err)
+		}
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %d payloads, want 2", len(received))
+	}
+
+	full, slack := received[0], received[1]
+	if _, ok := full["stats"]; !ok {
+		t.Errorf("json payload has no stats: %v", full)
+	}
+	for _, payload := range received {
+		text, _ := payload["text"].(string)
+		if !strings.Contains(text, "requests=50") {
+			t.Errorf("text = %q, want the summary line", text)
+		}
+	}
+	if len(slack) != 1 {
+		t.Errorf("slack payload = %v, want only text", slack)
+	}
+	if data, _ := json.Marshal(full); strings.Contains(string(data), "s3cr3t") {
+		t.Error("secret leaked into the webhook payload")
+	}
+}
+
+func TestPostWebhookReportsFailures(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer hook.Close()
+
+	tester := newTestLoadTester(t, testConfig(hook.URL))
+	if err := tester.PostWebhook(hook.URL, webhookJSON, &Stats{}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("PostWebhook = %v, want the 400 status", err)
+	}
+}
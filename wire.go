@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// byteCounter is an io.Writer that only counts what is written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// headerSize is the size of h serialized as HTTP/1.1 header lines
+func headerSize(h http.Header) int64 {
+	var c byteCounter
+	h.Write(&c)
+	return int64(c)
+}
+
+// requestSize estimates the bytes req puts on the wire for --count-headers:
+// the request line, Host, the headers set so far, Content-Length and the
+// body. Headers the transport adds itself, like Accept-Encoding, and HTTP/2
+// header compression aren't accounted for.
+func requestSize(req *http.Request) int64 {
+	n := int64(len(req.Method)+len(" ")+len(req.URL.RequestURI())+len(" HTTP/1.1\r\n")) +
+		int64(len("Host: ")+len(req.Host)+len("\r\n")) +
+		headerSize(req.Header) + int64(len("\r\n"))
+	if req.ContentLength > 0 {
+		n += int64(len("Content-Length: ")+len(strconv.FormatInt(req.ContentLength, 10))+len("\r\n")) + req.ContentLength
+	}
+	return n
+}
+
+// responseHeaderSize estimates the bytes of the status line and headers of
+// resp, re-serialized as HTTP/1.1. The transport takes Transfer-Encoding out
+// of the headers, so it is added back.
+func responseHeaderSize(resp *http.Response) int64 {
+	n := int64(len(resp.Proto)+len(" ")+len(resp.Status)+len("\r\n")) + headerSize(resp.Header) + int64(len("\r\n"))
+	for _, te := range resp.TransferEncoding {
+		n += int64(len("Transfer-Encoding: ") + len(te) + len("\r\n"))
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestSizeMatchesWireFormat(t *testing.T) {
+	get, _ := http.NewRequest("GET", "http://example.com/api/items?page=2", nil)
+	post, _ := http.NewRequest("POST", "http://example.com/api/items", strings.NewReader(`{"name":"brutal"}`))
+	post.Header.Set("Cookie", "session=abc123")
+	for _, req := range []*http.Request{get, post} {
+		// Set as brutal always does, so Write doesn't add its own
+		req.Header.Set("User-Agent", "brutal")
+		var wire bytes.Buffer
+		req.Write(&wire)
+		if got := requestSize(req); got != int64(wire.Len()) {
+			t.Errorf("%s: requestSize = %d, want %d for\n%s", req.Method, got, wire.Len(), wire.String())
+		}
+	}
+}
+
+func TestResponseHeaderSize(t *testing.T) {
+	head := "HTTP/1.1 200 OK\r\n" +
+		"Content-Security-Policy: default-src 'self'\r\n" +
+		"Content-Type: application/json\r\n" +
+		"Set-Cookie: a=1\r\n" +
+		"Set-Cookie: b=2\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n"
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(head+"2\r\n{}\r\n0\r\n\r\n")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := responseHeaderSize(resp); got != int64(len(head)) {
+		t.Errorf("responseHeaderSize = %d, want %d", got, len(head))
+	}
+}
+
+func TestCountHeaders(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.CountHeaders = true
+	tester := newTestLoadTester(t, config)
+	stats := tester.Run(nil)
+
+	// Every response has at least a status line, Content-Length, Content-Type and Date
+	if stats.HeaderBytes < 50*int64(len("HTTP/1.1 200 OK\r\n")) || stats.SentBytes < 50*int64(len("GET / HTTP/1.1\r\n")) {
+		t.Errorf("header bytes %d, sent bytes %d; want at least a status and request line each", stats.HeaderBytes, stats.SentBytes)
+	}
+	if stats.TotalBytes != 50*int64(len("hello")) {
+		t.Errorf("total bytes = %d, want only the bodies", stats.TotalBytes)
+	}
+	snap := tester.Live().Snapshot()
+	if snap.BytesIn != stats.TotalBytes+stats.HeaderBytes || snap.BytesOut != stats.SentBytes {
+		t.Errorf("live in/out = %d/%d, want %d/%d", snap.BytesIn, snap.BytesOut, stats.TotalBytes+stats.HeaderBytes, stats.SentBytes)
+	}
+
+	if stats := newTestLoadTester(t, testConfig(srv.URL)).Run(nil); stats.HeaderBytes != 0 || stats.SentBytes != 0 {
+		t.Error("headers counted without --count-headers")
+	}
+}
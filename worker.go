@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// workerPacer spaces the requests of each concurrency slot at least interval
+// apart for --max-rps-per-worker, so every virtual user is capped on its own
+// rather than the fastest ones taking most of the throughput
+type workerPacer struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[int]time.Time // earliest start of each slot's next request
+}
+
+func newWorkerPacer(rps float64) *workerPacer {
+	return &workerPacer{
+		interval: time.Duration(float64(time.Second) / rps),
+		next:     make(map[int]time.Time),
+	}
+}
+
+// wait blocks until slot may start its next request and reserves that start.
+// It returns false if cancel is closed first.
+func (p *workerPacer) wait(slot int, cancel <-chan struct{}) bool {
+	p.mu.Lock()
+	start := time.Now()
+	if next := p.next[slot]; next.After(start) {
+		start = next
+	}
+	p.next[slot] = start.Add(p.interval)
+	p.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// reset forgets the reserved starts before the tester runs again
+func (p *workerPacer) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next = make(map[int]time.Time)
+}
+
+// WorkerStats shows how evenly the requests were spread over the concurrency
+// slots. Requests is indexed by worker id minus one.
+type WorkerStats struct {
+	Requests []int  `json:"requests"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+	Spread   Spread `json:"spread"`
+}
+
+func buildWorkerStats(results []Result) *WorkerStats {
+	var counts []int
+	for _, result := range results {
+		if result.WorkerID < 1 {
+			continue
+		}
+		for len(counts) < result.WorkerID {
+			counts = append(counts, 0)
+		}
+		counts[result.WorkerID-1]++
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ws := &WorkerStats{Requests: counts, Min: counts[0], Max: counts[0]}
+	values := make([]float64, len(counts))
+	for i, n := range counts {
+		ws.Min = min(ws.Min, n)
+		ws.Max = max(ws.Max, n)
+		values[i] = float64(n)
+	}
+	ws.Spread = newSpread(values)
+	return ws
+}
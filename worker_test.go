@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkerPacerSpacesEachSlot(t *testing.T) {
+	p := newWorkerPacer(100) // 10ms apart
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		p.wait(1, nil)
+		p.wait(2, nil)
+	}
+	// Each slot waits four intervals; the slots don't hold each other up
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > 80*time.Millisecond {
+		t.Errorf("5 requests on each of 2 slots took %v, want about 40ms", elapsed)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	p.wait(3, cancel)
+	if p.wait(3, cancel) {
+		t.Error("wait returned true after cancel")
+	}
+}
+
+func TestMaxRPSPerWorkerEvensOutWorkers(t *testing.T) {
+	srv := newTestServer(t, 0)
+	config := testConfig(srv.URL)
+	config.Requests = 100
+	config.MaxRPSPerWorker = 200
+
+	start := time.Now()
+	stats := newTestLoadTester(t, config).Run(nil)
+	// 5 workers at 200 rps each send 100 requests in about 100ms
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("run took %v, faster than the per-worker cap allows", elapsed)
+	}
+	w := stats.Workers
+	if w == nil {
+		t.Fatal("no worker stats")
+	}
+	if len(w.Requests) != config.Concurrent || w.Min < 15 || w.Max > 25 {
+		t.Errorf("requests per worker = %v, want about 20 each", w.Requests)
+	}
+}
+
+func TestBuildWorkerStats(t *testing.T) {
+	results := []Result{{WorkerID: 1}, {WorkerID: 1}, {WorkerID: 3}, {WorkerID: 1}}
+	w := buildWorkerStats(results)
+	if len(w.Requests) != 3 || w.Requests[0] != 3 || w.Requests[1] != 0 || w.Requests[2] != 1 {
+		t.Fatalf("requests = %v, want [3 0 1]", w.Requests)
+	}
+	if w.Min != 0 || w.Max != 3 || w.Spread.Mean != 4.0/3 {
+		t.Errorf("min %d, max %d, mean %v; want 0, 3 and 4/3", w.Min, w.Max, w.Spread.Mean)
+	}
+	if buildWorkerStats(nil) != nil {
+		t.Error("want nil without results")
+	}
+}